@@ -0,0 +1,130 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+const (
+	// BasicFilterP is the Golomb-Rice coding parameter used by basic block
+	// filters (GCSFilterRegular) as defined by BIP0158.
+	BasicFilterP = 19
+
+	// BasicFilterM is the inverse of the false positive rate used by basic
+	// block filters (GCSFilterRegular) as defined by BIP0158.
+	BasicFilterM = 784931
+)
+
+// BasicFilterKey returns the SipHash key used for the basic block filter of
+// the block with the passed hash, which is the first 16 bytes of the hash.
+func BasicFilterKey(blockHash *ShaHash) [GCSKeySize]byte {
+	var key [GCSKeySize]byte
+	copy(key[:], blockHash[:GCSKeySize])
+	return key
+}
+
+// BuildBasicFilter builds the basic block filter (GCSFilterRegular) for the
+// passed block as defined by BIP0158.  The filter commits to the output
+// scripts of every transaction in the block, excluding empty scripts and
+// scripts which begin with OP_RETURN, along with prevOutScripts, which must
+// hold the output scripts spent by every input of the block other than the
+// coinbase.  Empty scripts in prevOutScripts are ignored.
+func BuildBasicFilter(block *MsgBlock, prevOutScripts [][]byte) (*GCSFilter, error) {
+	blockHash, err := block.BlockSha()
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the unique scripts committed to by the filter.
+	seen := make(map[string]struct{})
+	items := make([][]byte, 0, len(prevOutScripts))
+	addItem := func(script []byte) {
+		if len(script) == 0 {
+			return
+		}
+		if _, ok := seen[string(script)]; ok {
+			return
+		}
+		seen[string(script)] = struct{}{}
+		items = append(items, script)
+	}
+	for _, tx := range block.Transactions {
+		for _, txOut := range tx.TxOut {
+			if len(txOut.PkScript) > 0 && txOut.PkScript[0] == opReturn {
+				continue
+			}
+			addItem(txOut.PkScript)
+		}
+	}
+	for _, script := range prevOutScripts {
+		addItem(script)
+	}
+
+	return NewGCSFilter(BasicFilterP, BasicFilterM,
+		BasicFilterKey(&blockHash), items)
+}
+
+// MatchBasicFilter returns whether the passed script is likely committed to
+// by the passed basic block filter of the block with the passed hash.
+func MatchBasicFilter(filter *GCSFilter, blockHash *ShaHash, script []byte) bool {
+	return filter.Match(BasicFilterKey(blockHash), script)
+}
+
+// MatchAnyBasicFilter returns whether any of the passed scripts are likely
+// committed to by the passed basic block filter of the block with the passed
+// hash.
+func MatchAnyBasicFilter(filter *GCSFilter, blockHash *ShaHash,
+	scripts [][]byte) bool {
+
+	return filter.MatchAny(BasicFilterKey(blockHash), scripts)
+}
+
+// FilterHash returns the hash of the passed filter, which is the double
+// SHA-256 of its serialization.  This is the value carried by the cfheaders
+// message (MsgCFHeaders).
+func FilterHash(filter *GCSFilter) ShaHash {
+	// SetBytes can't fail here due to the fact DoubleSha256 always returns
+	// a []byte of the right size regardless of input.
+	var hash ShaHash
+	_ = hash.SetBytes(DoubleSha256(filter.Bytes()))
+	return hash
+}
+
+// MakeFilterHeader returns the filter header which chains the passed filter
+// to the filter header of the previous block as defined by BIP0157.  The
+// header is the double SHA-256 of the filter hash followed by the previous
+// filter header.  The previous filter header for the genesis block is all
+// zeros.
+func MakeFilterHeader(filter *GCSFilter, prevHeader *ShaHash) ShaHash {
+	filterHash := FilterHash(filter)
+	return MakeFilterHeaderFromHash(&filterHash, prevHeader)
+}
+
+// MakeFilterHeaderFromHash returns the filter header which chains the filter
+// with the passed hash to the filter header of the previous block.  It allows
+// the filter headers for the hashes in a cfheaders message (MsgCFHeaders) to
+// be derived without the filters themselves.
+func MakeFilterHeaderFromHash(filterHash, prevHeader *ShaHash) ShaHash {
+	buf := make([]byte, 0, HashSize*2)
+	buf = append(buf, filterHash[:]...)
+	buf = append(buf, prevHeader[:]...)
+
+	var header ShaHash
+	_ = header.SetBytes(DoubleSha256(buf))
+	return header
+}
+
+// DeriveFilterHeaders returns the filter header of every block covered by the
+// passed cfheaders message by chaining its filter hashes onto its previous
+// filter header.  The headers are returned in order so the final entry is the
+// filter header of the block identified by StopHash and may be compared with a
+// checkpoint from a cfcheckpt message (MsgCFCheckpt).
+func DeriveFilterHeaders(msg *MsgCFHeaders) []ShaHash {
+	headers := make([]ShaHash, 0, len(msg.FilterHashes))
+	prevHeader := msg.PrevFilterHeader
+	for _, filterHash := range msg.FilterHashes {
+		header := MakeFilterHeaderFromHash(filterHash, &prevHeader)
+		headers = append(headers, header)
+		prevHeader = header
+	}
+	return headers
+}
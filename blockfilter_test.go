@@ -0,0 +1,299 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// p2pkhScript returns a pay-to-pubkey-hash output script for a pubkey hash
+// consisting of 20 copies of b.
+func p2pkhScript(b byte) []byte {
+	script := []byte{0x76, 0xa9, 0x14} // OP_DUP OP_HASH160 OP_DATA_20
+	script = append(script, bytes.Repeat([]byte{b}, 20)...)
+	return append(script, 0x88, 0xac) // OP_EQUALVERIFY OP_CHECKSIG
+}
+
+// basicFilterGenesisBlock is the test network (version 3) genesis block from
+// the BIP0158 test vectors with its coinbase converted to a Reddcoin version 2
+// transaction, which carries a timestamp.  The filter only depends on the
+// block hash and the output scripts, so the BIP0158 results still apply.
+var basicFilterGenesisBlock = func() *rddwire.MsgBlock {
+	block := rddwire.NewMsgBlock(&rddwire.BlockHeader{
+		Version:    1,
+		MerkleRoot: mainNetGenesisMerkleRoot,
+		Timestamp:  time.Unix(1296688602, 0), // 2011-02-02 23:16:42 +0000 UTC
+		Bits:       0x1d00ffff,
+		Nonce:      414098458,
+	})
+	coinbase := genesisCoinbaseTx.Copy()
+	coinbase.Version = rddwire.TxVersion
+	coinbase.Timestamp = time.Unix(1296688602, 0)
+	block.AddTransaction(coinbase)
+	return block
+}()
+
+// basicFilterTestNetBlock returns a test network (version 3) block from the
+// BIP0158 test vectors which only consists of a coinbase paying 50 coins to the
+// passed output script.  The coinbase is converted to a Reddcoin version 2
+// transaction in the same way as basicFilterGenesisBlock, so the header keeps
+// the merkle root of the original block.
+func basicFilterTestNetBlock(prevBlock, merkleRoot string, timestamp int64,
+	nonce uint32, sigScript, pkScript string) *rddwire.MsgBlock {
+
+	prevHash, err := rddwire.NewShaHashFromStr(prevBlock)
+	if err != nil {
+		panic(err)
+	}
+	merkleHash, err := rddwire.NewShaHashFromStr(merkleRoot)
+	if err != nil {
+		panic(err)
+	}
+	sigScriptBytes, err := hex.DecodeString(sigScript)
+	if err != nil {
+		panic(err)
+	}
+	pkScriptBytes, err := hex.DecodeString(pkScript)
+	if err != nil {
+		panic(err)
+	}
+
+	block := rddwire.NewMsgBlock(&rddwire.BlockHeader{
+		Version:    1,
+		PrevBlock:  *prevHash,
+		MerkleRoot: *merkleHash,
+		Timestamp:  time.Unix(timestamp, 0),
+		Bits:       0x1d00ffff,
+		Nonce:      nonce,
+	})
+	coinbase := rddwire.NewMsgTx()
+	coinbase.Timestamp = time.Unix(timestamp, 0)
+	coinbase.AddTxIn(rddwire.NewTxIn(rddwire.NewOutPoint(&rddwire.ShaHash{},
+		0xffffffff), sigScriptBytes))
+	coinbase.AddTxOut(rddwire.NewTxOut(5000000000, pkScriptBytes))
+	block.AddTransaction(coinbase)
+	return block
+}
+
+// basicFilterTestNetBlock2 and basicFilterTestNetBlock3 are blocks 2
+// (000000006c02c8ea6e4ff69651f7fcde348fb9d557a06e6957b65552002a7820) and 3
+// (000000008b896e272758da5297bcd98fdc6d97c9b765ecec401e286dc1fdbe10) of the
+// test network (version 3) from the BIP0158 test vectors.
+var (
+	basicFilterTestNetBlock2 = basicFilterTestNetBlock(
+		"00000000b873e79784647a6c82962c70d228557d24a747ea4d1b8bbe878e1206",
+		"20222eb90f5895556926c112bb5aa0df4ab5abc3107e21a6950aec3b2e3541e2",
+		1296688946, 0x3435d200, "0432e7494d010e062f503253482f",
+		"21038a7f6ef1c8ca0c588aa53fa860128077c9e6c11e6830f4d7ee4e763a56b7718fac")
+	basicFilterTestNetBlock3 = basicFilterTestNetBlock(
+		"000000006c02c8ea6e4ff69651f7fcde348fb9d557a06e6957b65552002a7820",
+		"71241692d7adc0980c018e764a50974f59e1657ba88a1b1503ae2a53fc5aba41",
+		1296689030, 0xb6c28d05, "0486e7494d0151062f503253482f",
+		"2103f6d9ff4c12959445ca5549c811683bf9c88e637b222dd2e0311154c4c85cf423ac")
+)
+
+// basicFilterPoSVBlock is a Reddcoin proof-of-stake-velocity block which
+// exercises the rules for which scripts are committed to by a basic filter.
+var basicFilterPoSVBlock = func() *rddwire.MsgBlock {
+	block := rddwire.NewMsgBlock(&rddwire.BlockHeader{
+		Version:    rddwire.BlockVersion,
+		PrevBlock:  mainNetGenesisHash,
+		MerkleRoot: mainNetGenesisMerkleRoot,
+		Timestamp:  time.Unix(0x5a5a5a5a, 0), // 2018-01-14 00:23:54 +0000 UTC
+		Bits:       0x1e0fffff,
+	})
+	block.Signature = []byte{0x30, 0x01, 0x02}
+
+	// Coinbase with an OP_RETURN output and an empty output, neither of
+	// which are committed to.
+	coinbase := rddwire.NewMsgTx()
+	coinbase.Timestamp = time.Unix(0x5a5a5a5a, 0)
+	coinbase.AddTxIn(rddwire.NewTxIn(rddwire.NewOutPoint(&rddwire.ShaHash{},
+		0xffffffff), []byte{0x51}))
+	coinbase.AddTxOut(rddwire.NewTxOut(0, nil))
+	coinbase.AddTxOut(rddwire.NewTxOut(5000, p2pkhScript(0x11)))
+	coinbase.AddTxOut(rddwire.NewTxOut(0, []byte{0x6a, 0x04, 0xde, 0xad,
+		0xbe, 0xef}))
+	block.AddTransaction(coinbase)
+
+	// Spend with a duplicate output script.
+	spend := rddwire.NewMsgTx()
+	spend.Timestamp = time.Unix(0x5a5a5a5a, 0)
+	spend.AddTxIn(rddwire.NewTxIn(rddwire.NewOutPoint(&mainNetGenesisMerkleRoot,
+		0), nil))
+	spend.AddTxOut(rddwire.NewTxOut(1000, p2pkhScript(0x11)))
+	spend.AddTxOut(rddwire.NewTxOut(2000, p2pkhScript(0x22)))
+	block.AddTransaction(spend)
+	return block
+}()
+
+// TestBuildBasicFilter tests building and matching basic block filters.
+func TestBuildBasicFilter(t *testing.T) {
+	tests := []struct {
+		name           string            // Short description of test
+		block          *rddwire.MsgBlock // Block to build filter for
+		prevOutScripts [][]byte          // Scripts spent by the block
+		prevHeader     string            // Previous filter header
+		n              uint32            // Expected number of items
+		filter         []byte            // Expected serialized filter
+		header         string            // Expected filter header
+	}{
+		{
+			name:       "BIP0158 test network genesis block",
+			block:      basicFilterGenesisBlock,
+			prevHeader: "0000000000000000000000000000000000000000000000000000000000000000",
+			n:          1,
+			filter:     []byte{0x01, 0x9d, 0xfc, 0xa8},
+			header:     "21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750",
+		},
+		{
+			name:       "BIP0158 test network block 2",
+			block:      basicFilterTestNetBlock2,
+			prevHeader: "d7bdac13a59d745b1add0d2ce852f1a0442e8945fc1bf3848d3cbffd88c24fe1",
+			n:          1,
+			filter:     []byte{0x01, 0x74, 0xa1, 0x70},
+			header:     "186afd11ef2b5e7e3504f2e8cbf8df28a1fd251fe53d60dff8b1467d1b386cf0",
+		},
+		{
+			name:       "BIP0158 test network block 3",
+			block:      basicFilterTestNetBlock3,
+			prevHeader: "186afd11ef2b5e7e3504f2e8cbf8df28a1fd251fe53d60dff8b1467d1b386cf0",
+			n:          1,
+			filter:     []byte{0x01, 0x6c, 0xf7, 0xa0},
+			header:     "8d63aadf5ab7257cb6d2316a57b16f517bff1c6388f124ec4c04af1212729d2a",
+		},
+		{
+			name:  "PoSV block with spends",
+			block: basicFilterPoSVBlock,
+			prevOutScripts: [][]byte{
+				p2pkhScript(0x33),
+				nil,
+				p2pkhScript(0x22),
+			},
+			prevHeader: "21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750",
+			n:          3,
+			filter: []byte{
+				0x03, 0xde, 0xfd, 0xa6, 0x16, 0x02, 0xc4, 0x00,
+				0x42,
+			},
+			header: "3ddc2ffb932b3b629a0bbe0dbc17f324147ab9f2da04dca345be03313c751e21",
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		filter, err := rddwire.BuildBasicFilter(test.block,
+			test.prevOutScripts)
+		if err != nil {
+			t.Errorf("BuildBasicFilter #%d (%s) unexpected error %v",
+				i, test.name, err)
+			continue
+		}
+		if filter.N() != test.n {
+			t.Errorf("BuildBasicFilter #%d (%s) wrong number of "+
+				"items - got %v, want %v", i, test.name,
+				filter.N(), test.n)
+			continue
+		}
+		if !bytes.Equal(filter.Bytes(), test.filter) {
+			t.Errorf("BuildBasicFilter #%d (%s)\n got: %s want: %s",
+				i, test.name, spew.Sdump(filter.Bytes()),
+				spew.Sdump(test.filter))
+			continue
+		}
+
+		// Ensure the filter header chains as expected.
+		prevHeader, err := rddwire.NewShaHashFromStr(test.prevHeader)
+		if err != nil {
+			t.Errorf("NewShaHashFromStr #%d (%s) unexpected error %v",
+				i, test.name, err)
+			continue
+		}
+		header := rddwire.MakeFilterHeader(filter, prevHeader)
+		if header.String() != test.header {
+			t.Errorf("MakeFilterHeader #%d (%s) wrong header - "+
+				"got %v, want %v", i, test.name, header,
+				test.header)
+			continue
+		}
+
+		// Ensure all output and previous output scripts match other
+		// than those which aren't committed to.
+		blockHash, _ := test.block.BlockSha()
+		scripts := test.prevOutScripts
+		for _, tx := range test.block.Transactions {
+			for _, txOut := range tx.TxOut {
+				scripts = append(scripts, txOut.PkScript)
+			}
+		}
+		for _, script := range scripts {
+			want := len(script) > 0 && script[0] != 0x6a
+			match := rddwire.MatchBasicFilter(filter, &blockHash,
+				script)
+			if match != want {
+				t.Errorf("MatchBasicFilter #%d (%s) wrong "+
+					"result for script %x - got %v, want %v",
+					i, test.name, script, match, want)
+			}
+		}
+		if !rddwire.MatchAnyBasicFilter(filter, &blockHash, scripts) {
+			t.Errorf("MatchAnyBasicFilter #%d (%s) did not match",
+				i, test.name)
+			continue
+		}
+		if rddwire.MatchAnyBasicFilter(filter, &blockHash,
+			[][]byte{p2pkhScript(0x44), p2pkhScript(0x55)}) {
+
+			t.Errorf("MatchAnyBasicFilter #%d (%s) unexpected "+
+				"match", i, test.name)
+			continue
+		}
+	}
+}
+
+// TestDeriveFilterHeaders tests deriving filter headers from a cfheaders
+// message.
+func TestDeriveFilterHeaders(t *testing.T) {
+	genesisFilter, err := rddwire.BuildBasicFilter(basicFilterGenesisBlock,
+		nil)
+	if err != nil {
+		t.Fatalf("BuildBasicFilter: unexpected error %v", err)
+	}
+	posvFilter, err := rddwire.BuildBasicFilter(basicFilterPoSVBlock,
+		[][]byte{p2pkhScript(0x33), p2pkhScript(0x22)})
+	if err != nil {
+		t.Fatalf("BuildBasicFilter: unexpected error %v", err)
+	}
+
+	msg := rddwire.NewMsgCFHeaders()
+	msg.FilterType = rddwire.GCSFilterRegular
+	msg.StopHash, _ = basicFilterPoSVBlock.BlockSha()
+	genesisFilterHash := rddwire.FilterHash(genesisFilter)
+	posvFilterHash := rddwire.FilterHash(posvFilter)
+	msg.AddCFHash(&genesisFilterHash)
+	msg.AddCFHash(&posvFilterHash)
+
+	wantHeaders := []string{
+		"21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750",
+		"3ddc2ffb932b3b629a0bbe0dbc17f324147ab9f2da04dca345be03313c751e21",
+	}
+	headers := rddwire.DeriveFilterHeaders(msg)
+	if len(headers) != len(wantHeaders) {
+		t.Fatalf("DeriveFilterHeaders: wrong number of headers - got "+
+			"%v, want %v", len(headers), len(wantHeaders))
+	}
+	for i, header := range headers {
+		if header.String() != wantHeaders[i] {
+			t.Errorf("DeriveFilterHeaders #%d wrong header - got %v, "+
+				"want %v", i, header, wantHeaders[i])
+		}
+	}
+}
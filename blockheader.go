@@ -8,6 +8,8 @@ import (
 	"bytes"
 	"io"
 	"time"
+
+	"code.google.com/p/go.crypto/scrypt"
 )
 
 // BlockVersion is the current latest supported block version.
@@ -47,6 +49,14 @@ type BlockHeader struct {
 // header.
 const blockHeaderLen = 80
 
+// These constants are the scrypt parameters used to calculate the proof of
+// work hash of a block header.
+const (
+	scryptN = 1024
+	scryptR = 1
+	scryptP = 1
+)
+
 // BlockSha computes the block identifier hash for the given block header.
 func (h *BlockHeader) BlockSha() (ShaHash, error) {
 	// Encode the header and run double sha256 everything prior to the
@@ -66,6 +76,27 @@ func (h *BlockHeader) BlockSha() (ShaHash, error) {
 	return sha, nil
 }
 
+// PoWHash computes the proof of work hash for the given block header.  Blocks
+// of the proof-of-work era are mined with scrypt like Litecoin, so the hash is
+// the scrypt hash of the serialized header with N=1024, r=1 and p=1 which uses
+// the serialized header as the salt as well.
+func (h *BlockHeader) PoWHash() (ShaHash, error) {
+	var buf bytes.Buffer
+	_ = writeBlockHeader(&buf, 0, h)
+	header := buf.Bytes()[0:blockHeaderLen]
+	key, err := scrypt.Key(header, header, scryptN, scryptR, scryptP,
+		HashSize)
+	if err != nil {
+		return ShaHash{}, err
+	}
+
+	// SetBytes can't fail here due to the fact the key is always the right
+	// size.
+	var hash ShaHash
+	_ = hash.SetBytes(key)
+	return hash, nil
+}
+
 // Deserialize decodes a block header from r into the receiver using a format
 // that is suitable for long-term storage such as a database while respecting
 // the Version field.
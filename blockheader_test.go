@@ -229,3 +229,66 @@ func TestBlockHeaderSerialize(t *testing.T) {
 		}
 	}
 }
+
+// scryptHeader is the header of the Litecoin genesis block, which is a block
+// header mined with scrypt.
+var scryptHeader = rddwire.BlockHeader{
+	Version:   1,
+	PrevBlock: rddwire.ShaHash{},
+	MerkleRoot: rddwire.ShaHash([rddwire.HashSize]byte{ // Make go vet happy.
+		0xd9, 0xce, 0xd4, 0xed, 0x11, 0x30, 0xf7, 0xb7,
+		0xfa, 0xad, 0x9b, 0xe2, 0x53, 0x23, 0xff, 0xaf,
+		0xa3, 0x32, 0x32, 0xa1, 0x7c, 0x3e, 0xdf, 0x6c,
+		0xfd, 0x97, 0xbe, 0xe6, 0xba, 0xfb, 0xdd, 0x97,
+	}),
+	Timestamp: time.Unix(1317972665, 0), // 2011-10-07 07:31:05 +0000 UTC
+	Bits:      0x1e0ffff0,
+	Nonce:     2084524493,
+}
+
+// TestBlockHeaderPoWHash tests the proof of work hash of block headers.
+func TestBlockHeaderPoWHash(t *testing.T) {
+	tests := []struct {
+		name   string              // Test description
+		header rddwire.BlockHeader // Block header
+		want   rddwire.ShaHash     // Expected proof of work hash
+	}{
+		{"litecoin genesis", scryptHeader,
+			rddwire.ShaHash([rddwire.HashSize]byte{ // Make go vet happy.
+				0x00, 0x1e, 0x67, 0xb0, 0x13, 0x72, 0x6f, 0xd7,
+				0x38, 0x2e, 0x9a, 0xcb, 0x69, 0x16, 0x5b, 0x4b,
+				0x63, 0x16, 0x22, 0x7f, 0xb3, 0x15, 0x6b, 0x5b,
+				0x41, 0x4b, 0xa6, 0x34, 0x0c, 0x05, 0x00, 0x00,
+			})},
+		{"reddcoin mainnet genesis",
+			rddwire.MainNetParams.GenesisBlock.Header,
+			rddwire.ShaHash([rddwire.HashSize]byte{ // Make go vet happy.
+				0x46, 0x9d, 0xf1, 0x3f, 0x84, 0x64, 0x04, 0x77,
+				0x84, 0x1c, 0xdf, 0x30, 0x72, 0xb2, 0xaf, 0x31,
+				0xbc, 0xe1, 0xad, 0x71, 0xa0, 0x0c, 0x9b, 0x34,
+				0xc9, 0x69, 0x1f, 0x14, 0x55, 0x56, 0x3d, 0xe3,
+			})},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		hash, err := test.header.PoWHash()
+		if err != nil {
+			t.Errorf("PoWHash %s: %v", test.name, err)
+			continue
+		}
+		if !hash.IsEqual(&test.want) {
+			t.Errorf("PoWHash %s: wrong hash - got %v, want %v",
+				test.name, hash, test.want)
+			continue
+		}
+
+		// The proof of work hash differs from the block hash.
+		blockHash, _ := test.header.BlockSha()
+		if hash.IsEqual(&blockHash) {
+			t.Errorf("PoWHash %s: proof of work hash matches block "+
+				"hash %v", test.name, blockHash)
+			continue
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+// MaxBlocksPerGetBlocks is the maximum number of block inventory vectors sent
+// in response to a getblocks message (MsgGetBlocks).
+const MaxBlocksPerGetBlocks = 500
+
+// blockLocatorDenseHashes is the number of most recent block hashes added to a
+// block locator before the step back starts doubling.
+const blockLocatorDenseHashes = 10
+
+// BlockLocator is used to help locate a specific block.  The algorithm for
+// building the block locator is to add the hashes in reverse order until the
+// genesis block is reached.  In order to keep the list of locator hashes to a
+// reasonable number of entries, first the most recent 10 block hashes are
+// added, then the step is doubled each loop iteration to exponentially
+// decrease the number of hashes as a function of the distance from the block
+// being located.
+//
+// For example, assume a best chain as depicted below:
+//
+//	genesis -> 1 -> 2 -> ... -> 15 -> 16 -> 17 -> 18
+//
+// The block locator for block 17, as returned by NewBlockLocatorFromHeight for
+// height 17, would be the hashes of blocks:
+// [17 16 15 14 13 12 11 10 9 8 6 2 genesis]
+type BlockLocator []*ShaHash
+
+// BlockHeightLookup provides the hashes of the blocks of a best chain by
+// height, which is all that is needed to build a block locator.
+type BlockHeightLookup interface {
+	// BestHeight returns the height of the tip of the best chain.
+	BestHeight() int32
+
+	// HashByHeight returns the hash of the block at the passed height of
+	// the best chain.
+	HashByHeight(height int32) (*ShaHash, error)
+}
+
+// MainChainLookup provides the blocks of a best chain by height and hash,
+// which is what is needed to respond to the block locator of a getblocks
+// (MsgGetBlocks) or getheaders (MsgGetHeaders) message.
+type MainChainLookup interface {
+	BlockHeightLookup
+
+	// MainChainHeight returns the height of the block with the passed
+	// hash and whether it is part of the best chain.
+	MainChainHeight(hash *ShaHash) (int32, bool)
+
+	// HeaderByHeight returns the header of the block at the passed height
+	// of the best chain.
+	HeaderByHeight(height int32) (*BlockHeader, error)
+}
+
+// NewBlockLocator returns a block locator for the tip of the best chain of the
+// passed lookup.  See BlockLocator for details on the algorithm used.
+func NewBlockLocator(lookup BlockHeightLookup) (BlockLocator, error) {
+	return NewBlockLocatorFromHeight(lookup, lookup.BestHeight())
+}
+
+// NewBlockLocatorFromHeight returns a block locator for the block at the passed
+// height of the best chain of the passed lookup.  The locator holds at most
+// MaxBlockLocatorsPerMsg hashes, the last of which is always the genesis block.
+// See BlockLocator for details on the algorithm used.
+func NewBlockLocatorFromHeight(lookup BlockHeightLookup,
+	height int32) (BlockLocator, error) {
+
+	locator := make(BlockLocator, 0, blockLocatorDenseHashes+32)
+	step := int32(1)
+	for ; height > 0; height -= step {
+		if len(locator) == MaxBlockLocatorsPerMsg-1 {
+			break
+		}
+
+		hash, err := lookup.HashByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		locator = append(locator, hash)
+
+		// Once the dense hashes have been added, double the distance
+		// between each further hash.
+		if len(locator) >= blockLocatorDenseHashes {
+			step *= 2
+		}
+	}
+
+	genesis, err := lookup.HashByHeight(0)
+	if err != nil {
+		return nil, err
+	}
+	return append(locator, genesis), nil
+}
+
+// locateForkHeight returns the height of the first hash of the passed block
+// locator which is part of the best chain of the passed lookup.  The genesis
+// block is used when none of the hashes are part of the best chain.
+func locateForkHeight(lookup MainChainLookup, locator []*ShaHash) int32 {
+	for _, hash := range locator {
+		if height, ok := lookup.MainChainHeight(hash); ok {
+			return height
+		}
+	}
+	return 0
+}
+
+// LocateBlocks returns the inv message (MsgInv) to send in response to a
+// getblocks message (MsgGetBlocks) with the passed block locator and hash stop.
+// The inventory vectors are for the blocks of the best chain of the passed
+// lookup which follow the first block in the locator which is part of the best
+// chain, or the genesis block when there is none.  It holds at most
+// MaxBlocksPerGetBlocks blocks and, as Reddcoin does, stops before the block
+// with the hash stop.  A zero hash stop doesn't stop early.
+func LocateBlocks(lookup MainChainLookup, locator []*ShaHash,
+	hashStop *ShaHash) (*MsgInv, error) {
+
+	bestHeight := lookup.BestHeight()
+	msg := NewMsgInvSizeHint(MaxBlocksPerGetBlocks)
+	height := locateForkHeight(lookup, locator) + 1
+	for ; height <= bestHeight; height++ {
+		hash, err := lookup.HashByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		if hash.IsEqual(hashStop) {
+			break
+		}
+
+		// AddInvVect can't fail since MaxBlocksPerGetBlocks is below
+		// the maximum number of inventory vectors per message.
+		_ = msg.AddInvVect(NewInvVect(InvTypeBlock, hash))
+		if len(msg.InvList) == MaxBlocksPerGetBlocks {
+			break
+		}
+	}
+
+	return msg, nil
+}
+
+// LocateHeaders returns the headers message (MsgHeaders) to send in response to
+// a getheaders message (MsgGetHeaders) with the passed block locator and hash
+// stop.  The headers are those of the blocks of the best chain of the passed
+// lookup which follow the first block in the locator which is part of the best
+// chain, or the genesis block when there is none.  It holds at most
+// MaxBlockHeadersPerMsg headers and, as Reddcoin does, ends with the header of
+// the block with the hash stop when it is reached.  A zero hash stop doesn't
+// stop early.
+//
+// An empty block locator requests only the header of the block with the hash
+// stop, so the message holds only that header when the block is part of the
+// best chain and no headers otherwise.
+func LocateHeaders(lookup MainChainLookup, locator []*ShaHash,
+	hashStop *ShaHash) (*MsgHeaders, error) {
+
+	msg := NewMsgHeaders()
+	if len(locator) == 0 {
+		height, ok := lookup.MainChainHeight(hashStop)
+		if !ok {
+			return msg, nil
+		}
+		header, err := lookup.HeaderByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		_ = msg.AddBlockHeader(header)
+		return msg, nil
+	}
+
+	bestHeight := lookup.BestHeight()
+	height := locateForkHeight(lookup, locator) + 1
+	for ; height <= bestHeight; height++ {
+		header, err := lookup.HeaderByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+
+		// AddBlockHeader can't fail since the loop ends once the
+		// message is full.
+		_ = msg.AddBlockHeader(header)
+		if len(msg.Headers) == MaxBlockHeadersPerMsg {
+			break
+		}
+
+		// Ignore the error since BlockSha can't fail in the current
+		// implementation except due to run-time panics.
+		hash, _ := header.BlockSha()
+		if hash.IsEqual(hashStop) {
+			break
+		}
+	}
+
+	return msg, nil
+}
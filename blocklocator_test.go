@@ -0,0 +1,225 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// locatorTestChain implements the rddwire.MainChainLookup interface for a
+// best chain held in memory.
+type locatorTestChain struct {
+	headers []rddwire.BlockHeader
+	hashes  []rddwire.ShaHash
+}
+
+// BestHeight returns the height of the tip of the test chain.
+func (c *locatorTestChain) BestHeight() int32 {
+	return int32(len(c.headers)) - 1
+}
+
+// HashByHeight returns the hash of the block at the passed height of the test
+// chain.
+func (c *locatorTestChain) HashByHeight(height int32) (*rddwire.ShaHash, error) {
+	if height < 0 || height >= int32(len(c.hashes)) {
+		return nil, errors.New("height out of range")
+	}
+	hash := c.hashes[height]
+	return &hash, nil
+}
+
+// MainChainHeight returns the height of the block with the passed hash and
+// whether it is part of the test chain.
+func (c *locatorTestChain) MainChainHeight(hash *rddwire.ShaHash) (int32, bool) {
+	for i := range c.hashes {
+		if c.hashes[i] == *hash {
+			return int32(i), true
+		}
+	}
+	return 0, false
+}
+
+// HeaderByHeight returns the header of the block at the passed height of the
+// test chain.
+func (c *locatorTestChain) HeaderByHeight(height int32) (*rddwire.BlockHeader, error) {
+	if height < 0 || height >= int32(len(c.headers)) {
+		return nil, errors.New("height out of range")
+	}
+	header := c.headers[height]
+	return &header, nil
+}
+
+// newLocatorTestChain returns a test chain with the passed number of blocks
+// after the genesis block.
+func newLocatorTestChain(height int32) *locatorTestChain {
+	c := locatorTestChain{}
+	var prev rddwire.ShaHash
+	for i := int32(0); i <= height; i++ {
+		header := rddwire.BlockHeader{
+			Version:   rddwire.BlockVersion,
+			PrevBlock: prev,
+			Timestamp: time.Unix(1400000000+int64(i)*60, 0),
+			Bits:      0x207fffff,
+		}
+		prev, _ = header.BlockSha()
+		c.headers = append(c.headers, header)
+		c.hashes = append(c.hashes, prev)
+	}
+	return &c
+}
+
+// TestBlockLocator tests building block locators.
+func TestBlockLocator(t *testing.T) {
+	chain := newLocatorTestChain(1000)
+
+	tests := []struct {
+		height  int32   // Height to build the locator for
+		heights []int32 // Expected heights of the locator hashes
+	}{
+		{0, []int32{0}},
+		{1, []int32{1, 0}},
+		{10, []int32{10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0}},
+		{17, []int32{17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 6, 2, 0}},
+		{1000, []int32{1000, 999, 998, 997, 996, 995, 994, 993, 992,
+			991, 989, 985, 977, 961, 929, 865, 737, 481, 0}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		locator, err := rddwire.NewBlockLocatorFromHeight(chain,
+			test.height)
+		if err != nil {
+			t.Errorf("NewBlockLocatorFromHeight #%d: %v", i, err)
+			continue
+		}
+
+		want := make(rddwire.BlockLocator, 0, len(test.heights))
+		for _, height := range test.heights {
+			hash, _ := chain.HashByHeight(height)
+			want = append(want, hash)
+		}
+		if !reflect.DeepEqual(locator, want) {
+			t.Errorf("NewBlockLocatorFromHeight #%d\n got: %s "+
+				"want: %s", i, spew.Sdump(locator),
+				spew.Sdump(want))
+			continue
+		}
+	}
+
+	// The locator for the best chain starts at its tip.
+	locator, err := rddwire.NewBlockLocator(chain)
+	if err != nil {
+		t.Fatalf("NewBlockLocator: %v", err)
+	}
+	if len(locator) != 19 || *locator[0] != chain.hashes[1000] {
+		t.Errorf("NewBlockLocator: locator does not start at the tip")
+	}
+
+	// Lookup errors are returned.
+	_, err = rddwire.NewBlockLocatorFromHeight(chain, 1001)
+	if err == nil {
+		t.Errorf("NewBlockLocatorFromHeight: expected error for " +
+			"height past the tip")
+	}
+}
+
+// TestLocateBlocks tests resolving the response to a getblocks message.
+func TestLocateBlocks(t *testing.T) {
+	chain := newLocatorTestChain(1000)
+	short := rddwire.BlockLocator{&chain.hashes[990], &chain.hashes[0]}
+	unknown := rddwire.BlockLocator{&rddwire.ShaHash{0x01}}
+
+	tests := []struct {
+		name     string               // Test description
+		locator  rddwire.BlockLocator // Block locator
+		hashStop rddwire.ShaHash      // Hash stop
+		first    int32                // Height of first block
+		count    int                  // Number of blocks
+	}{
+		{"to tip", short, rddwire.ShaHash{}, 991, 10},
+		{"hash stop", short, chain.hashes[995], 991, 4},
+		{"hash stop before fork", short, chain.hashes[100], 991, 10},
+		{"unknown locator", unknown, rddwire.ShaHash{}, 1, 500},
+		{"unknown locator hash stop", unknown, chain.hashes[11], 1, 10},
+		{"empty locator", rddwire.BlockLocator{}, rddwire.ShaHash{}, 1, 500},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		msg, err := rddwire.LocateBlocks(chain, test.locator,
+			&test.hashStop)
+		if err != nil {
+			t.Errorf("LocateBlocks %s: %v", test.name, err)
+			continue
+		}
+		if len(msg.InvList) != test.count {
+			t.Errorf("LocateBlocks %s: got %d blocks, want %d",
+				test.name, len(msg.InvList), test.count)
+			continue
+		}
+		for i, iv := range msg.InvList {
+			want := chain.hashes[test.first+int32(i)]
+			if iv.Type != rddwire.InvTypeBlock || iv.Hash != want {
+				t.Errorf("LocateBlocks %s: wrong inventory "+
+					"vector #%d - got %v, want %v", test.name,
+					i, iv.Hash, want)
+				break
+			}
+		}
+	}
+}
+
+// TestLocateHeaders tests resolving the response to a getheaders message.
+func TestLocateHeaders(t *testing.T) {
+	chain := newLocatorTestChain(2100)
+	short := rddwire.BlockLocator{&chain.hashes[2090], &chain.hashes[0]}
+	unknown := rddwire.BlockLocator{&rddwire.ShaHash{0x01}}
+
+	tests := []struct {
+		name     string               // Test description
+		locator  rddwire.BlockLocator // Block locator
+		hashStop rddwire.ShaHash      // Hash stop
+		first    int32                // Height of first header
+		count    int                  // Number of headers
+	}{
+		{"to tip", short, rddwire.ShaHash{}, 2091, 10},
+		{"hash stop", short, chain.hashes[2095], 2091, 5},
+		{"unknown locator", unknown, rddwire.ShaHash{}, 1, 2000},
+		{"unknown locator hash stop", unknown, chain.hashes[11], 1, 11},
+		{"empty locator", rddwire.BlockLocator{}, chain.hashes[50], 50, 1},
+		{"empty locator unknown hash stop", rddwire.BlockLocator{},
+			rddwire.ShaHash{0x01}, 0, 0},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		msg, err := rddwire.LocateHeaders(chain, test.locator,
+			&test.hashStop)
+		if err != nil {
+			t.Errorf("LocateHeaders %s: %v", test.name, err)
+			continue
+		}
+		if len(msg.Headers) != test.count {
+			t.Errorf("LocateHeaders %s: got %d headers, want %d",
+				test.name, len(msg.Headers), test.count)
+			continue
+		}
+		for i, header := range msg.Headers {
+			want := &chain.headers[test.first+int32(i)]
+			if !reflect.DeepEqual(header, want) {
+				t.Errorf("LocateHeaders %s: wrong header #%d\n"+
+					"got: %s want: %s", test.name, i,
+					spew.Sdump(header), spew.Sdump(want))
+				break
+			}
+		}
+	}
+}
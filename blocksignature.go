@@ -0,0 +1,273 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"code.google.com/p/go.crypto/ripemd160"
+	"github.com/conformal/btcec"
+	"github.com/conformal/fastsha256"
+)
+
+// compactSigSize is the size of a compact signature from which the public key
+// which made it can be recovered.  It is a header byte which identifies the
+// key followed by the 32-byte R and S values.
+const compactSigSize = 65
+
+// halfOrder is half the order of the secp256k1 curve.  Signatures with an S
+// value greater than it are not canonical since the S value could be replaced
+// by the order minus S to produce another valid signature.
+var halfOrder = new(big.Int).Rsh(btcec.S256().N, 1)
+
+// VerifySignature ensures the block is signed by the owner of the coins staked
+// by its coinstake transaction as Reddcoin requires of PoSV blocks.  The
+// signature must be over the block hash (BlockSha) and made by the public key
+// of the second output of the coinstake, which is the second transaction of
+// the block.  The first output of the coinstake is always empty to mark it as
+// a coinstake.  As with Reddcoin, the second output is used regardless of the
+// layout of the remaining outputs, so the block is rejected when it does not
+// pay to the key which signed the block.
+//
+// When the output is a pay-to-pubkey script, the signature must be a strictly
+// DER encoded signature made by its public key.  When it is a pay-to-pubkey-hash
+// script, the signature must be a compact signature whose recovered public key
+// hashes to the hash in the script.  In both cases the S value of the signature
+// must not be greater than half the order of the curve.
+//
+// Blocks with a version of PowBlockVersion or lower are proof-of-work blocks,
+// which carry no signature, so they only pass when Signature is empty.
+func (msg *MsgBlock) VerifySignature() error {
+	if msg.Header.Version <= PowBlockVersion {
+		if len(msg.Signature) != 0 {
+			str := "proof-of-work block has a signature"
+			return messageError("MsgBlock.VerifySignature", str)
+		}
+		return nil
+	}
+
+	if !msg.IsProofOfStake() {
+		str := "block has no coinstake transaction"
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+	pkScript, err := msg.coinStakeScript("MsgBlock.VerifySignature")
+	if err != nil {
+		return err
+	}
+
+	if len(msg.Signature) == 0 {
+		str := "block signature is empty"
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+
+	blockHash, err := msg.BlockSha()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case isPubKeyScript(pkScript):
+		ops, _ := parseScript(pkScript)
+		return verifyBlockSigPubKey(msg.Signature, ops[0].data, &blockHash)
+
+	case isPubKeyHashScript(pkScript):
+		ops, _ := parseScript(pkScript)
+		return verifyBlockSigPubKeyHash(msg.Signature, ops[2].data,
+			&blockHash)
+	}
+
+	str := fmt.Sprintf("coinstake output script %x is not a "+
+		"pay-to-pubkey or pay-to-pubkey-hash script", pkScript)
+	return messageError("MsgBlock.VerifySignature", str)
+}
+
+// Sign signs the block with the passed private key as Reddcoin requires of
+// PoSV blocks and sets Signature accordingly.  The key must be the key of the
+// second output of the coinstake.  See VerifySignature for the
+// requirements of the signature.
+//
+// The block is signed with a strictly DER encoded signature when the output is
+// a pay-to-pubkey script and with a compact signature when it is a
+// pay-to-pubkey-hash script.  In both cases the signature is deterministic as
+// specified by RFC6979 and has a low S value.  The block must not be modified
+// after it is signed since the signature is over the block hash.
+//
+// An error is returned when the block is a proof-of-work block, which carries
+// no signature, or the key does not match the output of the coinstake.
+func (msg *MsgBlock) Sign(privKey *btcec.PrivateKey) error {
+	if msg.Header.Version <= PowBlockVersion {
+		str := fmt.Sprintf("proof-of-work block version %d can't be "+
+			"signed", msg.Header.Version)
+		return messageError("MsgBlock.Sign", str)
+	}
+	if !msg.IsProofOfStake() {
+		str := "block has no coinstake transaction"
+		return messageError("MsgBlock.Sign", str)
+	}
+	pkScript, err := msg.coinStakeScript("MsgBlock.Sign")
+	if err != nil {
+		return err
+	}
+
+	blockHash, err := msg.BlockSha()
+	if err != nil {
+		return err
+	}
+
+	pubKey := privKey.PubKey()
+	switch {
+	case isPubKeyScript(pkScript):
+		ops, _ := parseScript(pkScript)
+		serializedPubKey := pubKey.SerializeCompressed()
+		if len(ops[0].data) != len(serializedPubKey) {
+			serializedPubKey = pubKey.SerializeUncompressed()
+		}
+		if !bytes.Equal(ops[0].data, serializedPubKey) {
+			str := fmt.Sprintf("private key does not match coinstake "+
+				"public key %x", ops[0].data)
+			return messageError("MsgBlock.Sign", str)
+		}
+
+		sig, err := privKey.Sign(blockHash[:])
+		if err != nil {
+			return err
+		}
+		if sig.S.Cmp(halfOrder) > 0 {
+			sig.S = new(big.Int).Sub(btcec.S256().N, sig.S)
+		}
+		msg.Signature = sig.Serialize()
+		return nil
+
+	case isPubKeyHashScript(pkScript):
+		ops, _ := parseScript(pkScript)
+		var compressed bool
+		switch {
+		case bytes.Equal(ops[2].data,
+			hash160(pubKey.SerializeCompressed())):
+			compressed = true
+
+		case !bytes.Equal(ops[2].data,
+			hash160(pubKey.SerializeUncompressed())):
+			str := fmt.Sprintf("private key does not match coinstake "+
+				"public key hash %x", ops[2].data)
+			return messageError("MsgBlock.Sign", str)
+		}
+
+		sig, err := btcec.SignCompact(btcec.S256(), privKey,
+			blockHash[:], compressed)
+		if err != nil {
+			return err
+		}
+
+		// Negating S requires flipping the parity of the public key
+		// recovery ID in the header byte.
+		s := new(big.Int).SetBytes(sig[33:])
+		if s.Cmp(halfOrder) > 0 {
+			s.Sub(btcec.S256().N, s)
+			sig[0] ^= 0x01
+			copy(sig[33:], make([]byte, 32))
+			sBytes := s.Bytes()
+			copy(sig[compactSigSize-len(sBytes):], sBytes)
+		}
+		msg.Signature = sig
+		return nil
+	}
+
+	str := fmt.Sprintf("coinstake output script %x is not a "+
+		"pay-to-pubkey or pay-to-pubkey-hash script", pkScript)
+	return messageError("MsgBlock.Sign", str)
+}
+
+// coinStakeScript returns the public key script of the second output of the
+// coinstake of the block, which must be a PoSV block.  The passed function name
+// is used for the error returned when the coinstake has no second output.
+func (msg *MsgBlock) coinStakeScript(f string) ([]byte, error) {
+	coinStake := msg.Transactions[1]
+	if len(coinStake.TxOut) < 2 {
+		str := fmt.Sprintf("coinstake has %d outputs, but the second "+
+			"output identifies the staker", len(coinStake.TxOut))
+		return nil, messageError(f, str)
+	}
+	return coinStake.TxOut[1].PkScript, nil
+}
+
+// verifyBlockSigPubKey ensures the passed DER encoded signature of the passed
+// block hash was made by the passed serialized public key.
+func verifyBlockSigPubKey(sig, serializedPubKey []byte, blockHash *ShaHash) error {
+	pubKey, err := btcec.ParsePubKey(serializedPubKey, btcec.S256())
+	if err != nil {
+		str := fmt.Sprintf("coinstake public key is invalid: %v", err)
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+
+	signature, err := btcec.ParseDERSignature(sig, btcec.S256())
+	if err != nil {
+		str := fmt.Sprintf("block signature is not strictly DER "+
+			"encoded: %v", err)
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+	if signature.S.Cmp(halfOrder) > 0 {
+		str := "block signature has a high S value"
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+
+	if !signature.Verify(blockHash[:], pubKey) {
+		str := fmt.Sprintf("block signature does not match coinstake "+
+			"public key %x", serializedPubKey)
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+
+	return nil
+}
+
+// verifyBlockSigPubKeyHash ensures the public key recovered from the passed
+// compact signature of the passed block hash hashes to the passed public key
+// hash.
+func verifyBlockSigPubKeyHash(sig, pubKeyHash []byte, blockHash *ShaHash) error {
+	if len(sig) != compactSigSize {
+		str := fmt.Sprintf("block signature is not a compact signature "+
+			"[size %v, want %v]", len(sig), compactSigSize)
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+	if new(big.Int).SetBytes(sig[33:]).Cmp(halfOrder) > 0 {
+		str := "block signature has a high S value"
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+
+	pubKey, compressed, err := btcec.RecoverCompact(btcec.S256(), sig,
+		blockHash[:])
+	if err != nil {
+		str := fmt.Sprintf("unable to recover public key from block "+
+			"signature: %v", err)
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+
+	var serializedPubKey []byte
+	if compressed {
+		serializedPubKey = pubKey.SerializeCompressed()
+	} else {
+		serializedPubKey = pubKey.SerializeUncompressed()
+	}
+	if !bytes.Equal(hash160(serializedPubKey), pubKeyHash) {
+		str := fmt.Sprintf("block signature public key %x does not "+
+			"match coinstake public key hash %x", serializedPubKey,
+			pubKeyHash)
+		return messageError("MsgBlock.VerifySignature", str)
+	}
+
+	return nil
+}
+
+// hash160 returns RIPEMD160(SHA256(b)), which is the hash of public keys used
+// by pay-to-pubkey-hash scripts.
+func hash160(b []byte) []byte {
+	sha := fastsha256.New()
+	sha.Write(b)
+	hasher := ripemd160.New()
+	hasher.Write(sha.Sum(nil))
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/conformal/btcec"
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// posvTestBlock returns a PoSV block with a coinbase and a coinstake whose
+// second output pays to the passed script.  The block is signed with
+// the passed signature.
+func posvTestBlock(pkScript []byte, sig []byte) *rddwire.MsgBlock {
+	coinbase := &rddwire.MsgTx{
+		Version: 2,
+		TxIn: []*rddwire.TxIn{
+			{
+				PreviousOutPoint: rddwire.OutPoint{
+					Hash:  rddwire.ShaHash{},
+					Index: 0xffffffff,
+				},
+				SignatureScript: []byte{0x03, 0x40, 0xfa, 0x03},
+				Sequence:        0xffffffff,
+			},
+		},
+		TxOut: []*rddwire.TxOut{
+			{
+				Value:    0,
+				PkScript: []byte{},
+			},
+		},
+		Timestamp: time.Unix(1400000000, 0),
+	}
+	coinstake := &rddwire.MsgTx{
+		Version: 2,
+		TxIn: []*rddwire.TxIn{
+			{
+				PreviousOutPoint: rddwire.OutPoint{
+					Hash:  rddwire.ShaHash{0x01},
+					Index: 1,
+				},
+				SignatureScript: []byte{},
+				Sequence:        0xffffffff,
+			},
+		},
+		TxOut: []*rddwire.TxOut{
+			{
+				Value:    0,
+				PkScript: []byte{},
+			},
+			{
+				Value:    1000000000000,
+				PkScript: pkScript,
+			},
+		},
+		Timestamp: time.Unix(1400000000, 0),
+	}
+
+	block := rddwire.MsgBlock{
+		Header: rddwire.BlockHeader{
+			Version:   3,
+			Timestamp: time.Unix(1400000000, 0),
+			Bits:      0x1e0fffff,
+		},
+		Transactions: []*rddwire.MsgTx{coinbase, coinstake},
+		Signature:    sig,
+	}
+	txHashes, _ := block.TxShas()
+	block.Header.MerkleRoot, _ = rddwire.BuildMerkleTreeStore(txHashes)
+	return &block
+}
+
+// posvTestBlockPaidLater returns a PoSV block in the same way as posvTestBlock
+// except the second output of the coinstake has an empty script, so the passed
+// script is paid by a third output instead.
+func posvTestBlockPaidLater(pkScript []byte, sig []byte) *rddwire.MsgBlock {
+	block := posvTestBlock(nil, sig)
+	coinStake := block.Transactions[1]
+	coinStake.TxOut[1].PkScript = []byte{}
+	coinStake.AddTxOut(rddwire.NewTxOut(0, pkScript))
+	txHashes, _ := block.TxShas()
+	block.Header.MerkleRoot, _ = rddwire.BuildMerkleTreeStore(txHashes)
+	return block
+}
+
+// TestBlockVerifySignature tests the verification of PoSV block signatures.
+func TestBlockVerifySignature(t *testing.T) {
+	decodeHex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatalf("DecodeString: %v", err)
+		}
+		return b
+	}
+
+	// Pay-to-pubkey and pay-to-pubkey-hash scripts for the public key of
+	// the private key e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b
+	// 917c8436b35.
+	p2pkScript := decodeHex("210339a36013301597daef41fbe593a02cc513d0b5" +
+		"5527ec2df1050e2e8ff49c85c2ac")
+	p2pkhScript := decodeHex("76a9143442193e1bb70916e914552172cd4e2dbc9d" +
+		"f81188ac")
+
+	// Signature of the block paying to p2pkScript, the same signature
+	// with a high S value, and the same signature with a needlessly
+	// padded R value.
+	p2pkSig := decodeHex("30450221009cfcca8401031e40a896aab733a2a474631d2d" +
+		"1392c1ec4c327fa2fa4974d8d1022058d4ca284b4ecfb0b129d2958a4109" +
+		"8b1af2f63c5fb2920b237fa5e9024cb9d3")
+	p2pkSigHighS := decodeHex("30460221009cfcca8401031e40a896aab733a2a47463" +
+		"1d2d1392c1ec4c327fa2fa4974d8d1022100a72b35d7b4b1304f4ed62d6a" +
+		"75bef6739fbbe6aa4f960e309c52b8a3cde9876e")
+	p2pkSigPadded := decodeHex("3046022200009cfcca8401031e40a896aab733a2a4" +
+		"74631d2d1392c1ec4c327fa2fa4974d8d1022058d4ca284b4ecfb0b129d2" +
+		"958a41098b1af2f63c5fb2920b237fa5e9024cb9d3")
+
+	// Compact signature of the block paying to p2pkhScript and the same
+	// signature with a high S value.
+	p2pkhSig := decodeHex("20295d8c6c5162f8b6cc56c0581a306e50de5f08ccb4ef" +
+		"6d09149613ccdaec0a615a76c3b0fbeff2ef73274f84d538f27365db9de7" +
+		"33a4efd8f438c1e5d4700386")
+	p2pkhSigHighS := decodeHex("21295d8c6c5162f8b6cc56c0581a306e50de5f08cc" +
+		"b4ef6d09149613ccdaec0a61a5893c4f04100d108cd8b07b2ac70d8b54d3" +
+		"3eff7ba3b062cb999ca6fbc63dbb")
+
+	// Blocks whose header no longer matches their signature.
+	p2pkWrongHash := posvTestBlock(p2pkScript, p2pkSig)
+	p2pkWrongHash.Header.Nonce++
+	p2pkhWrongHash := posvTestBlock(p2pkhScript, p2pkhSig)
+	p2pkhWrongHash.Header.Nonce++
+
+	// Proof-of-work blocks with and without a signature.
+	powBlock := posvTestBlock(p2pkScript, nil)
+	powBlock.Header.Version = rddwire.PowBlockVersion
+	powBlockSig := posvTestBlock(p2pkScript, p2pkSig)
+	powBlockSig.Header.Version = rddwire.PowBlockVersion
+
+	// Block without a coinstake.
+	noCoinStake := posvTestBlock(p2pkScript, p2pkSig)
+	noCoinStake.Transactions = noCoinStake.Transactions[:1]
+
+	// Block signed by the key of the third output of the coinstake rather
+	// than the second.
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), decodeHex("e8f32e"+
+		"723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"))
+	paidLater := posvTestBlockPaidLater(p2pkScript, nil)
+	paidLaterHash, _ := paidLater.BlockSha()
+	paidLaterSig, err := privKey.Sign(paidLaterHash[:])
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	paidLater.Signature = paidLaterSig.Serialize()
+
+	tests := []struct {
+		block *rddwire.MsgBlock // Block to verify
+		err   error             // Expected error
+	}{
+		{posvTestBlock(p2pkScript, p2pkSig), nil},
+		{posvTestBlock(p2pkhScript, p2pkhSig), nil},
+		{powBlock, nil},
+		{powBlockSig, &rddwire.MessageError{}},
+		{noCoinStake, &rddwire.MessageError{}},
+		{posvTestBlock(p2pkScript, nil), &rddwire.MessageError{}},
+		{posvTestBlock(p2pkScript, p2pkSigHighS), &rddwire.MessageError{}},
+		{posvTestBlock(p2pkScript, p2pkSigPadded), &rddwire.MessageError{}},
+		{posvTestBlock(p2pkScript, p2pkhSig), &rddwire.MessageError{}},
+		{p2pkWrongHash, &rddwire.MessageError{}},
+		{posvTestBlock(p2pkhScript, p2pkhSigHighS), &rddwire.MessageError{}},
+		{posvTestBlock(p2pkhScript, p2pkSig), &rddwire.MessageError{}},
+		{p2pkhWrongHash, &rddwire.MessageError{}},
+		{posvTestBlock([]byte{0x6a}, p2pkSig), &rddwire.MessageError{}},
+		{paidLater, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		err := test.block.VerifySignature()
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("VerifySignature #%d wrong error got: %v, "+
+				"want: %v", i, err, reflect.TypeOf(test.err))
+			continue
+		}
+	}
+}
+
+// TestBlockSign tests signing PoSV blocks and ensures the signed blocks pass
+// verification after a round trip through the wire encoding.
+func TestBlockSign(t *testing.T) {
+	decodeHex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatalf("DecodeString: %v", err)
+		}
+		return b
+	}
+
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), decodeHex("e8f32e"+
+		"723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"))
+	otherKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), decodeHex("0000"+
+		"000000000000000000000000000000000000000000000000000000000001"))
+
+	// Pay-to-pubkey scripts for the compressed and uncompressed public key
+	// and a pay-to-pubkey-hash script for the compressed public key.
+	pubKey := privKey.PubKey()
+	p2pkScript := append([]byte{0x21}, pubKey.SerializeCompressed()...)
+	p2pkScript = append(p2pkScript, 0xac)
+	p2pkUncompressed := append([]byte{0x41},
+		pubKey.SerializeUncompressed()...)
+	p2pkUncompressed = append(p2pkUncompressed, 0xac)
+	p2pkhScript := decodeHex("76a9143442193e1bb70916e914552172cd4e2dbc9d" +
+		"f81188ac")
+
+	// Expected signatures of the blocks paying to p2pkScript and
+	// p2pkhScript.
+	p2pkSig := decodeHex("30450221009cfcca8401031e40a896aab733a2a474631d2d" +
+		"1392c1ec4c327fa2fa4974d8d1022058d4ca284b4ecfb0b129d2958a4109" +
+		"8b1af2f63c5fb2920b237fa5e9024cb9d3")
+	p2pkhSig := decodeHex("20295d8c6c5162f8b6cc56c0581a306e50de5f08ccb4ef" +
+		"6d09149613ccdaec0a615a76c3b0fbeff2ef73274f84d538f27365db9de7" +
+		"33a4efd8f438c1e5d4700386")
+
+	tests := []struct {
+		block *rddwire.MsgBlock // Block to sign
+		sig   []byte            // Expected signature or nil to skip
+	}{
+		{posvTestBlock(p2pkScript, nil), p2pkSig},
+		{posvTestBlock(p2pkhScript, nil), p2pkhSig},
+		{posvTestBlock(p2pkUncompressed, nil), nil},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		err := test.block.Sign(privKey)
+		if err != nil {
+			t.Errorf("Sign #%d: %v", i, err)
+			continue
+		}
+		if test.sig != nil && !bytes.Equal(test.block.Signature, test.sig) {
+			t.Errorf("Sign #%d\n got: %s want: %s", i,
+				spew.Sdump(test.block.Signature),
+				spew.Sdump(test.sig))
+			continue
+		}
+
+		// Encode the signed block and decode it back.
+		var buf bytes.Buffer
+		err = test.block.BtcEncode(&buf, rddwire.ProtocolVersion)
+		if err != nil {
+			t.Errorf("BtcEncode #%d: %v", i, err)
+			continue
+		}
+		var block rddwire.MsgBlock
+		err = block.BtcDecode(&buf, rddwire.ProtocolVersion)
+		if err != nil {
+			t.Errorf("BtcDecode #%d: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(block.Signature, test.block.Signature) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(block.Signature),
+				spew.Sdump(test.block.Signature))
+			continue
+		}
+
+		err = block.VerifySignature()
+		if err != nil {
+			t.Errorf("VerifySignature #%d: %v", i, err)
+			continue
+		}
+	}
+
+	// Proof-of-work block and block without a coinstake.
+	powBlock := posvTestBlock(p2pkScript, nil)
+	powBlock.Header.Version = rddwire.PowBlockVersion
+	noCoinStake := posvTestBlock(p2pkScript, nil)
+	noCoinStake.Transactions = noCoinStake.Transactions[:1]
+
+	errTests := []struct {
+		block *rddwire.MsgBlock // Block to sign
+		key   *btcec.PrivateKey // Key to sign with
+	}{
+		{powBlock, privKey},
+		{noCoinStake, privKey},
+		{posvTestBlock(p2pkScript, nil), otherKey},
+		{posvTestBlock(p2pkhScript, nil), otherKey},
+		{posvTestBlock([]byte{0x6a}, nil), privKey},
+		{posvTestBlockPaidLater(p2pkScript, nil), privKey},
+	}
+
+	t.Logf("Running %d tests", len(errTests))
+	for i, test := range errTests {
+		err := test.block.Sign(test.key)
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			t.Errorf("Sign #%d wrong error got: %v, want: %T", i, err,
+				&rddwire.MessageError{})
+			continue
+		}
+		if len(test.block.Signature) != 0 {
+			t.Errorf("Sign #%d: failed signing set signature %x", i,
+				test.block.Signature)
+			continue
+		}
+	}
+}
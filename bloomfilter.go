@@ -0,0 +1,269 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	// ln2Squared is simply the square of the natural log of 2.
+	ln2Squared = math.Ln2 * math.Ln2
+
+	// minBloomFalsePositiveRate is the lowest false positive rate a
+	// BloomFilter may be created with.  Lower rates are clamped to it.
+	minBloomFalsePositiveRate = 1e-9
+
+	// bloomHashSeedMultiplier is multiplied by the number of each hash
+	// function to derive its seed.  It is the value chosen by BIP0037 since
+	// it guarantees a reasonable bit difference between the seeds.
+	bloomHashSeedMultiplier = 0xfba4c795
+)
+
+// BloomFilter defines a BIP0037 bloom filter which is used by SPV clients to
+// describe the transactions they are interested in to their peers.  The filter
+// is backed by a filterload message (MsgFilterLoad) which is what is sent
+// across the network.
+//
+// Filters are created with NewBloomFilter or, for filters received from the
+// network, LoadBloomFilter.  A BloomFilter is not safe for concurrent access.
+type BloomFilter struct {
+	msgFilterLoad *MsgFilterLoad
+}
+
+// Add adds the passed data to the filter.
+func (bf *BloomFilter) Add(data []byte) {
+	// Adding data to a filter with no bits has no effect since it already
+	// matches everything.
+	if len(bf.msgFilterLoad.Filter) == 0 {
+		return
+	}
+
+	// Adding data consists of setting all of the bit offsets which result
+	// from hashing the data using each independent hash function.
+	for i := uint32(0); i < bf.msgFilterLoad.HashFuncs; i++ {
+		idx := bf.hash(i, data)
+		bf.msgFilterLoad.Filter[idx>>3] |= 1 << (idx & 7)
+	}
+}
+
+// AddShaHash adds the passed hash to the filter.
+func (bf *BloomFilter) AddShaHash(hash *ShaHash) {
+	bf.Add(hash[:])
+}
+
+// AddOutPoint adds the passed transaction outpoint to the filter.  The
+// outpoint is added as its hash followed by its little-endian index.
+func (bf *BloomFilter) AddOutPoint(op *OutPoint) {
+	bf.Add(outPointBytes(op))
+}
+
+// Matches returns true if the filter might contain the passed data and false
+// if it definitely does not.  A filter with no bits matches everything.
+func (bf *BloomFilter) Matches(data []byte) bool {
+	if len(bf.msgFilterLoad.Filter) == 0 {
+		return true
+	}
+
+	// The filter does not contain the data if any of the bit offsets which
+	// result from hashing the data using each independent hash function
+	// are not set.
+	for i := uint32(0); i < bf.msgFilterLoad.HashFuncs; i++ {
+		idx := bf.hash(i, data)
+		if bf.msgFilterLoad.Filter[idx>>3]&(1<<(idx&7)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesShaHash returns true if the filter might contain the passed hash and
+// false if it definitely does not.
+func (bf *BloomFilter) MatchesShaHash(hash *ShaHash) bool {
+	return bf.Matches(hash[:])
+}
+
+// MatchesOutPoint returns true if the filter might contain the passed
+// transaction outpoint and false if it definitely does not.
+func (bf *BloomFilter) MatchesOutPoint(op *OutPoint) bool {
+	return bf.Matches(outPointBytes(op))
+}
+
+// MatchTxAndUpdate returns true if the filter matches the passed transaction
+// and false otherwise.  A transaction matches when the filter matches its hash,
+// any data pushed by the public key script of one of its outputs, any outpoint
+// it spends, or any data pushed by the signature script of one of its inputs.
+//
+// When the data pushed by the public key script of an output matches, the
+// outpoint of that output is added to the filter according to its
+// BloomUpdateType so transactions which spend it match as well:
+// BloomUpdateNone never adds the outpoint, BloomUpdateAll always adds it, and
+// BloomUpdateP2PubkeyOnly only adds it when the script is a standard
+// pay-to-pubkey or multisig script.
+func (bf *BloomFilter) MatchTxAndUpdate(tx *MsgTx) bool {
+	// Ignore the error since TxSha can't fail in the current
+	// implementation except due to run-time panics.
+	txHash, _ := tx.TxSha()
+	matched := bf.MatchesShaHash(&txHash)
+
+	// Check if the filter matches any data pushed by the public key
+	// scripts of the outputs.  When it does, add the outpoint of the
+	// output so transactions which spend it are matched as well without
+	// the client having to update the filter.
+	for i, txOut := range tx.TxOut {
+		if !bf.matchesScript(txOut.PkScript) {
+			continue
+		}
+
+		matched = true
+		switch bf.msgFilterLoad.Flags {
+		case BloomUpdateAll:
+			bf.AddOutPoint(NewOutPoint(&txHash, uint32(i)))
+
+		case BloomUpdateP2PubkeyOnly:
+			if isPubKeyScript(txOut.PkScript) ||
+				isMultiSigScript(txOut.PkScript) {
+
+				bf.AddOutPoint(NewOutPoint(&txHash, uint32(i)))
+			}
+		}
+	}
+	if matched {
+		return true
+	}
+
+	// Check if the filter matches any outpoints the transaction spends or
+	// any data pushed by the signature scripts of the inputs.
+	for _, txIn := range tx.TxIn {
+		if bf.MatchesOutPoint(&txIn.PreviousOutPoint) {
+			return true
+		}
+		if bf.matchesScript(txIn.SignatureScript) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesScript returns true if the filter matches any non-empty data pushed
+// by the passed script.  Scripts which fail to parse are only examined up to
+// the point of failure.
+func (bf *BloomFilter) matchesScript(script []byte) bool {
+	ops, _ := parseScript(script)
+	for _, op := range ops {
+		if len(op.data) != 0 && bf.Matches(op.data) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MsgFilterLoad returns a filterload message (MsgFilterLoad) which describes
+// the filter so it can be sent to a peer.  The returned message is a copy, so
+// it is not affected by later changes to the filter.
+func (bf *BloomFilter) MsgFilterLoad() *MsgFilterLoad {
+	filter := make([]byte, len(bf.msgFilterLoad.Filter))
+	copy(filter, bf.msgFilterLoad.Filter)
+	return NewMsgFilterLoad(filter, bf.msgFilterLoad.HashFuncs,
+		bf.msgFilterLoad.Tweak, bf.msgFilterLoad.Flags)
+}
+
+// hash returns the bit offset in the filter which corresponds to the passed
+// data for the given independent hash function number.
+func (bf *BloomFilter) hash(hashNum uint32, data []byte) uint32 {
+	seed := hashNum*bloomHashSeedMultiplier + bf.msgFilterLoad.Tweak
+	return MurmurHash3(seed, data) % (uint32(len(bf.msgFilterLoad.Filter)) << 3)
+}
+
+// outPointBytes returns the serialization of the passed outpoint which is
+// used for bloom filtering.  It is the hash followed by the little-endian
+// index.
+func outPointBytes(op *OutPoint) []byte {
+	var buf [HashSize + 4]byte
+	copy(buf[:], op.Hash[:])
+	binary.LittleEndian.PutUint32(buf[HashSize:], op.Index)
+	return buf[:]
+}
+
+// NewBloomFilter returns a new bloom filter which is sized to hold the passed
+// number of elements with the passed false positive rate.  The false positive
+// rate is the probability of a false positive where 1.0 is "match everything",
+// and it is clamped to the range [1e-9, 1.0].  The filter size and number of
+// hash functions are limited to MaxFilterLoadFilterSize and
+// MaxFilterLoadHashFuncs respectively.  The tweak is added to the seed of each
+// hash function, and flags determines how a peer updates the filter when a
+// match is found.
+func NewBloomFilter(elements, tweak uint32, fprate float64,
+	flags BloomUpdateType) *BloomFilter {
+
+	// Massage the false positive rate and number of elements to sane
+	// values.
+	if fprate > 1.0 {
+		fprate = 1.0
+	}
+	if fprate < minBloomFalsePositiveRate {
+		fprate = minBloomFalsePositiveRate
+	}
+	if elements == 0 {
+		elements = 1
+	}
+
+	// Calculate the size of the filter in bytes for the given number of
+	// elements and false positive rate.
+	//
+	// Equivalent to m = -(n*ln(p) / ln(2)^2), where m is in bits.
+	// Then clamp it to the maximum filter size and convert to bytes.
+	dataLen := uint32(-1 * float64(elements) * math.Log(fprate) / ln2Squared)
+	if dataLen > MaxFilterLoadFilterSize*8 {
+		dataLen = MaxFilterLoadFilterSize * 8
+	}
+	dataLen /= 8
+
+	// Calculate the number of hash functions based on the size of the
+	// filter calculated above and the number of elements.
+	//
+	// Equivalent to k = (m/n) * ln(2)
+	// Then clamp it to the maximum allowed hash funcs.
+	hashFuncs := uint32(float64(dataLen*8) / float64(elements) * math.Ln2)
+	if hashFuncs > MaxFilterLoadHashFuncs {
+		hashFuncs = MaxFilterLoadHashFuncs
+	}
+
+	data := make([]byte, dataLen)
+	return &BloomFilter{
+		msgFilterLoad: NewMsgFilterLoad(data, hashFuncs, tweak, flags),
+	}
+}
+
+// LoadBloomFilter returns a bloom filter described by the passed filterload
+// message (MsgFilterLoad) such as one received from a peer.  The message is
+// copied, so later changes to the filter do not affect it.  An error is
+// returned if the filter exceeds MaxFilterLoadFilterSize or uses more than
+// MaxFilterLoadHashFuncs hash functions.
+func LoadBloomFilter(msg *MsgFilterLoad) (*BloomFilter, error) {
+	size := uint64(len(msg.Filter))
+	if size > MaxFilterLoadFilterSize {
+		str := fmt.Sprintf("filterload filter size too large for message "+
+			"[size %v, max %v]", size, MaxFilterLoadFilterSize)
+		return nil, messageError("LoadBloomFilter", str)
+	}
+
+	if msg.HashFuncs > MaxFilterLoadHashFuncs {
+		str := fmt.Sprintf("too many filter hash functions for message "+
+			"[count %v, max %v]", msg.HashFuncs, MaxFilterLoadHashFuncs)
+		return nil, messageError("LoadBloomFilter", str)
+	}
+
+	filter := make([]byte, len(msg.Filter))
+	copy(filter, msg.Filter)
+	return &BloomFilter{
+		msgFilterLoad: NewMsgFilterLoad(filter, msg.HashFuncs, msg.Tweak,
+			msg.Flags),
+	}, nil
+}
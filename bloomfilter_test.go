@@ -0,0 +1,516 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestBloomFilterInsert ensures inserting data into a bloom filter causes that
+// data to be matched and the resulting filterload message is the expected
+// value for various tweaks.
+func TestBloomFilterInsert(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	elements := []struct {
+		hex    string // Hex encoded data
+		insert bool   // Whether the data is inserted
+	}{
+		{"99108ad8ed9bb6274d3980bab5a85c048f0950c8", true},
+		{"19108ad8ed9bb6274d3980bab5a85c048f0950c8", false},
+		{"b5a2c786d9ef4658287ced5914b37a1b4aa32eee", true},
+		{"b9300670b4c5366e95b2699e8b18bc75e5f729c5", true},
+	}
+
+	tests := []struct {
+		tweak uint32 // Filter tweak
+		want  string // Expected hex encoded filterload message
+	}{
+		{0, "03614e9b050000000000000001"},
+		{2147483649, "03ce4299050000000100008001"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		bf := rddwire.NewBloomFilter(3, test.tweak, 0.01,
+			rddwire.BloomUpdateAll)
+
+		for j, element := range elements {
+			data, _ := hex.DecodeString(element.hex)
+			if element.insert {
+				bf.Add(data)
+			}
+
+			result := bf.Matches(data)
+			if result != element.insert {
+				t.Errorf("Matches #%d-%d got: %v want: %v", i, j,
+					result, element.insert)
+			}
+		}
+
+		var buf bytes.Buffer
+		err := bf.MsgFilterLoad().BtcEncode(&buf, pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		want, _ := hex.DecodeString(test.want)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("MsgFilterLoad #%d\n got: %x want: %x", i,
+				buf.Bytes(), want)
+			continue
+		}
+	}
+}
+
+// TestBloomFilterFPRange ensures bloom filters created with out of range false
+// positive rates are clamped to the valid range.
+func TestBloomFilterFPRange(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	hashStr := "02981fa052f0481dbc5868f4fc2166035a10f27a03cfd2de67326471df5bc041"
+	hash, err := rddwire.NewShaHashFromStr(hashStr)
+	if err != nil {
+		t.Errorf("NewShaHashFromStr: %v", err)
+		return
+	}
+
+	tests := []struct {
+		fprate float64 // False positive rate
+		want   string  // Expected hex encoded filterload message
+	}{
+		// Rates greater than 1 are clamped to 1.
+		{20.9999999769, "00000000000000000001"},
+
+		// Rates less than the minimum are clamped to the minimum.
+		{0, "0566d97a91a91b0000000000000001"},
+		{-1, "0566d97a91a91b0000000000000001"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		bf := rddwire.NewBloomFilter(1, 0, test.fprate,
+			rddwire.BloomUpdateAll)
+		bf.AddShaHash(hash)
+		if !bf.MatchesShaHash(hash) {
+			t.Errorf("MatchesShaHash #%d: hash not matched", i)
+		}
+
+		var buf bytes.Buffer
+		err := bf.MsgFilterLoad().BtcEncode(&buf, pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		want, _ := hex.DecodeString(test.want)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("MsgFilterLoad #%d\n got: %x want: %x", i,
+				buf.Bytes(), want)
+			continue
+		}
+	}
+}
+
+// TestBloomFilterLarge ensures bloom filters for a large number of elements are
+// limited to the maximum filter size and number of hash functions.
+func TestBloomFilterLarge(t *testing.T) {
+	bf := rddwire.NewBloomFilter(100000000, 0, 0.01, rddwire.BloomUpdateNone)
+	msg := bf.MsgFilterLoad()
+	if len(msg.Filter) != rddwire.MaxFilterLoadFilterSize {
+		t.Errorf("NewBloomFilter: wrong filter size - got %v, want %v",
+			len(msg.Filter), rddwire.MaxFilterLoadFilterSize)
+	}
+	if msg.HashFuncs > rddwire.MaxFilterLoadHashFuncs {
+		t.Errorf("NewBloomFilter: too many hash funcs - got %v, max %v",
+			msg.HashFuncs, rddwire.MaxFilterLoadHashFuncs)
+	}
+}
+
+// TestBloomFilterOutPoint ensures outpoints added to a bloom filter are
+// matched.
+func TestBloomFilterOutPoint(t *testing.T) {
+	hashStr := "90b2b9d5c3d0a1a8c89b7a6a5c35f2f3e1a7c8d3b4e5f60718293a4b5c6d7e8f"
+	hash, err := rddwire.NewShaHashFromStr(hashStr)
+	if err != nil {
+		t.Errorf("NewShaHashFromStr: %v", err)
+		return
+	}
+	op := rddwire.NewOutPoint(hash, 1)
+	otherOp := rddwire.NewOutPoint(hash, 2)
+
+	bf := rddwire.NewBloomFilter(10, 0, 0.000001, rddwire.BloomUpdateAll)
+	if bf.MatchesOutPoint(op) {
+		t.Errorf("MatchesOutPoint: empty filter matched outpoint")
+	}
+	bf.AddOutPoint(op)
+	if !bf.MatchesOutPoint(op) {
+		t.Errorf("MatchesOutPoint: outpoint not matched")
+	}
+	if bf.MatchesOutPoint(otherOp) {
+		t.Errorf("MatchesOutPoint: unexpected outpoint matched")
+	}
+
+	// The outpoint is added as its hash followed by its index.
+	data := append(hash.Bytes(), 0x01, 0x00, 0x00, 0x00)
+	if !bf.Matches(data) {
+		t.Errorf("Matches: serialized outpoint not matched")
+	}
+}
+
+// TestBloomFilterMatchTx ensures bloom filters match transactions by their hash,
+// the data pushed by their scripts, and the outpoints they spend, and that
+// outputs which match are added to the filter.
+func TestBloomFilterMatchTx(t *testing.T) {
+	txStr := "01000000010b26e9b7735eb6aabdf358bab62f9816a21ba9ebdb719d5299e886" +
+		"07d722c190000000008b4830450220070aca44506c5cef3a16ed519d7c3c39f8" +
+		"aab192c4e1c90d065f37b8a4af6141022100a8e160b856c2d43d27d8fba71e5a" +
+		"ef6405b8643ac4cb7cb3c462aced7f14711a0141046d11fee51b0e60666d5049" +
+		"a9101a72741df480b96ee26488a4d3466b95c9a40ac5eeef87e10a5cd336c19a" +
+		"84565f80fa6c547957b7700ff4dfbdefe76036c339ffffffff021bff3d110000" +
+		"00001976a91404943fdd508053c75000106d3bc6e2754dbcff1988ac2f15de00" +
+		"000000001976a914a266436d2965547608b9e15d9032a7b9d64fa43188ac0000" +
+		"0000"
+	spendingTxStr := "01000000016bff7fcd4f8565ef406dd5d63d4ff94f318fe82027fd4dc451b044" +
+		"74019f74b4000000008c493046022100da0dc6aecefe1e06efdf05773757deb1" +
+		"68820930e3b0d03f46f5fcf150bf990c022100d25b5c87040076e4f253f8262e" +
+		"763e2dd51e7ff0be157727c4bc42807f17bd39014104e6c26ef67dc610d2cd19" +
+		"2484789a6cf9aea9930b944b7e2db5342b9d9e5b9ff79aff9a2ee1978dd7fd01" +
+		"dfc522ee02283d3b06a9d03acf8096968d7dbb0f9178ffffffff028ba7940e00" +
+		"0000001976a914badeecfdef0507247fc8f74241d73bc039972d7b88ac4094a8" +
+		"02000000001976a914c10932483fec93ed51f5fe95e72559f2cc7043f988ac00" +
+		"00000000"
+
+	var tx, spendingTx rddwire.MsgTx
+	txBytes, _ := hex.DecodeString(txStr)
+	err := tx.Deserialize(bytes.NewReader(txBytes))
+	if err != nil {
+		t.Errorf("Deserialize: %v", err)
+		return
+	}
+	spendingTxBytes, _ := hex.DecodeString(spendingTxStr)
+	err = spendingTx.Deserialize(bytes.NewReader(spendingTxBytes))
+	if err != nil {
+		t.Errorf("Deserialize: %v", err)
+		return
+	}
+
+	tests := []struct {
+		name  string // Description of the test
+		data  string // Hex encoded data to add to the filter
+		hash  bool   // Whether the data is a hash string
+		index uint32 // Outpoint index when the data is an outpoint hash
+		op    bool   // Whether the data is an outpoint hash
+		match bool   // Expected result
+	}{
+		{"tx hash", "b4749f017444b051c44dfd2720e88f314ff94f3dd6d56d40ef65854fcd7fff6b",
+			true, 0, false, true},
+		{"tx hash bytes", "6bff7fcd4f8565ef406dd5d63d4ff94f318fe82027fd4dc451b04474019f74b4",
+			false, 0, false, true},
+		{"input signature", "30450220070aca44506c5cef3a16ed519d7c3c39f8aab192c4e1c90d065" +
+			"f37b8a4af6141022100a8e160b856c2d43d27d8fba71e5aef6405b8643" +
+			"ac4cb7cb3c462aced7f14711a01", false, 0, false, true},
+		{"input pubkey", "046d11fee51b0e60666d5049a9101a72741df480b96ee26488a4d3466b95" +
+			"c9a40ac5eeef87e10a5cd336c19a84565f80fa6c547957b7700ff4dfbdefe" +
+			"76036c339", false, 0, false, true},
+		{"output address", "a266436d2965547608b9e15d9032a7b9d64fa431",
+			false, 0, false, true},
+		{"spent outpoint", "90c122d70786e899529d71dbeba91ba216982fb6ba58f3bdaab65e73b7e9260b",
+			true, 0, true, true},
+		{"unrelated hash", "00000009e784f32f62ef849763d4f45b98e07ba658647343b915ff832b110436",
+			true, 0, false, false},
+		{"unrelated address", "0000006d2965547608b9e15d9032a7b9d64fa431",
+			false, 0, false, false},
+		{"unspent outpoint index", "90c122d70786e899529d71dbeba91ba216982fb6ba58f3bdaab65e73b7e9260b",
+			true, 1, true, false},
+		{"unrelated outpoint", "000000d70786e899529d71dbeba91ba216982fb6ba58f3bdaab65e73b7e9260b",
+			true, 0, true, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		bf := rddwire.NewBloomFilter(10, 0, 0.000001, rddwire.BloomUpdateAll)
+		switch {
+		case test.op:
+			hash, _ := rddwire.NewShaHashFromStr(test.data)
+			bf.AddOutPoint(rddwire.NewOutPoint(hash, test.index))
+		case test.hash:
+			hash, _ := rddwire.NewShaHashFromStr(test.data)
+			bf.AddShaHash(hash)
+		default:
+			data, _ := hex.DecodeString(test.data)
+			bf.Add(data)
+		}
+
+		result := bf.MatchTxAndUpdate(&tx)
+		if result != test.match {
+			t.Errorf("MatchTxAndUpdate #%d (%s) got: %v want: %v", i,
+				test.name, result, test.match)
+			continue
+		}
+	}
+
+	// Ensure a transaction which spends an output that matched is matched
+	// since the outpoint is added to the filter.
+	bf := rddwire.NewBloomFilter(10, 0, 0.000001, rddwire.BloomUpdateAll)
+	data, _ := hex.DecodeString("04943fdd508053c75000106d3bc6e2754dbcff19")
+	bf.Add(data)
+	if !bf.MatchTxAndUpdate(&tx) {
+		t.Errorf("MatchTxAndUpdate: output address not matched")
+	}
+	if !bf.MatchTxAndUpdate(&spendingTx) {
+		t.Errorf("MatchTxAndUpdate: spending tx not matched")
+	}
+}
+
+// TestBloomFilterUpdate ensures bloom filters add the outpoints of matched
+// outputs according to their update type.
+func TestBloomFilterUpdate(t *testing.T) {
+	var block rddwire.MsgBlock
+	blockBytes, _ := hex.DecodeString(bloomTestBlock)
+	err := block.Deserialize(bytes.NewReader(blockBytes))
+	if err != nil {
+		t.Errorf("Deserialize: %v", err)
+		return
+	}
+
+	// The generation pubkey pays to a pay-to-pubkey script in the first
+	// transaction and the address is paid to by a pay-to-pubkey-hash script
+	// in the fourth transaction.
+	pubKey, _ := hex.DecodeString("04eaafc2314def4ca98ac970241bcab022b9c1e1" +
+		"f4ea423a20f134c876f2c01ec0f0dd5b2e86e7168cefe0d81113c3807420ce" +
+		"13ad1357231a2252247d97a46a91")
+	addr, _ := hex.DecodeString("b6efd80d99179f4f4ff6f4dd0a007d018c385d21")
+	genHash, _ := rddwire.NewShaHashFromStr("147caa76786596590baa4e98f5d9f" +
+		"48b86c7765e489f7a6ff3360fe5c674360b")
+	addrHash, _ := rddwire.NewShaHashFromStr("02981fa052f0481dbc5868f4fc21" +
+		"66035a10f27a03cfd2de67326471df5bc041")
+	genOutPoint := rddwire.NewOutPoint(genHash, 0)
+	addrOutPoint := rddwire.NewOutPoint(addrHash, 0)
+
+	tests := []struct {
+		flags  rddwire.BloomUpdateType // Filter update type
+		genOP  bool                    // Whether generation outpoint is added
+		addrOP bool                    // Whether address outpoint is added
+	}{
+		{rddwire.BloomUpdateNone, false, false},
+		{rddwire.BloomUpdateAll, true, true},
+		{rddwire.BloomUpdateP2PubkeyOnly, true, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		bf := rddwire.NewBloomFilter(10, 0, 0.000001, test.flags)
+		bf.Add(pubKey)
+		bf.Add(addr)
+		for _, tx := range block.Transactions {
+			bf.MatchTxAndUpdate(tx)
+		}
+
+		if result := bf.MatchesOutPoint(genOutPoint); result != test.genOP {
+			t.Errorf("MatchesOutPoint #%d (%v) generation got: %v "+
+				"want: %v", i, test.flags, result, test.genOP)
+		}
+		if result := bf.MatchesOutPoint(addrOutPoint); result != test.addrOP {
+			t.Errorf("MatchesOutPoint #%d (%v) address got: %v "+
+				"want: %v", i, test.flags, result, test.addrOP)
+		}
+	}
+}
+
+// TestBloomFilterUpdateMultiSig ensures bloom filters with the
+// BloomUpdateP2PubkeyOnly update type add the outpoints of matched multisig
+// outputs but not those of other matched outputs.
+func TestBloomFilterUpdateMultiSig(t *testing.T) {
+	pubKey := bytes.Repeat([]byte{0x02}, 33)
+	otherPubKey := bytes.Repeat([]byte{0x03}, 33)
+
+	// 1-of-2 multisig script followed by a script which pushes the public
+	// key, but isn't a standard script.
+	multiSigScript := []byte{0x51, 0x21}
+	multiSigScript = append(multiSigScript, pubKey...)
+	multiSigScript = append(multiSigScript, 0x21)
+	multiSigScript = append(multiSigScript, otherPubKey...)
+	multiSigScript = append(multiSigScript, 0x52, 0xae)
+	nonStandardScript := []byte{0x21}
+	nonStandardScript = append(nonStandardScript, pubKey...)
+	nonStandardScript = append(nonStandardScript, 0x75, 0x51)
+
+	tx := rddwire.NewMsgTx()
+	tx.AddTxIn(rddwire.NewTxIn(rddwire.NewOutPoint(&rddwire.ShaHash{}, 0),
+		nil))
+	tx.AddTxOut(rddwire.NewTxOut(1, multiSigScript))
+	tx.AddTxOut(rddwire.NewTxOut(2, nonStandardScript))
+	txHash, _ := tx.TxSha()
+
+	bf := rddwire.NewBloomFilter(10, 0, 0.000001,
+		rddwire.BloomUpdateP2PubkeyOnly)
+	bf.Add(pubKey)
+	if !bf.MatchTxAndUpdate(tx) {
+		t.Errorf("MatchTxAndUpdate: public key not matched")
+	}
+	if !bf.MatchesOutPoint(rddwire.NewOutPoint(&txHash, 0)) {
+		t.Errorf("MatchesOutPoint: multisig outpoint not added")
+	}
+	if bf.MatchesOutPoint(rddwire.NewOutPoint(&txHash, 1)) {
+		t.Errorf("MatchesOutPoint: non-standard outpoint added")
+	}
+}
+
+// TestLoadBloomFilter ensures bloom filters are loaded from filterload messages
+// properly and invalid messages are rejected.
+func TestLoadBloomFilter(t *testing.T) {
+	data := []byte{0x01, 0x02}
+
+	bf := rddwire.NewBloomFilter(3, 5, 0.01, rddwire.BloomUpdateP2PubkeyOnly)
+	bf.Add(data)
+	msg := bf.MsgFilterLoad()
+
+	// Ensure a loaded filter describes the same filter and matches the same
+	// data.
+	loaded, err := rddwire.LoadBloomFilter(msg)
+	if err != nil {
+		t.Errorf("LoadBloomFilter: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(loaded.MsgFilterLoad(), msg) {
+		t.Errorf("LoadBloomFilter\n got: %s want: %s",
+			spew.Sdump(loaded.MsgFilterLoad()), spew.Sdump(msg))
+	}
+	if !loaded.Matches(data) {
+		t.Errorf("Matches: data not matched by loaded filter")
+	}
+
+	// Ensure the loaded filter does not share data with the message and
+	// the exported message does not share data with the filter.
+	loaded.Add([]byte{0x03, 0x04})
+	if reflect.DeepEqual(loaded.MsgFilterLoad(), msg) {
+		t.Errorf("LoadBloomFilter: filter shares data with message")
+	}
+	msg.Filter[0] ^= 0xff
+	if reflect.DeepEqual(bf.MsgFilterLoad(), msg) {
+		t.Errorf("MsgFilterLoad: message shares data with filter")
+	}
+
+	tests := []struct {
+		msg *rddwire.MsgFilterLoad // Message to load
+		err error                  // Expected error
+	}{
+		// Filter larger than the max allowed.
+		{
+			rddwire.NewMsgFilterLoad(make([]byte,
+				rddwire.MaxFilterLoadFilterSize+1), 10, 0,
+				rddwire.BloomUpdateNone),
+			&rddwire.MessageError{},
+		},
+
+		// More hash functions than the max allowed.
+		{
+			rddwire.NewMsgFilterLoad(make([]byte, 10),
+				rddwire.MaxFilterLoadHashFuncs+1, 0,
+				rddwire.BloomUpdateNone),
+			&rddwire.MessageError{},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		_, err := rddwire.LoadBloomFilter(test.msg)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("LoadBloomFilter #%d wrong error got: %v, "+
+				"want: %v", i, err, reflect.TypeOf(test.err))
+			continue
+		}
+	}
+}
+
+// bloomTestBlock is the hex encoded serialization of a block with seven
+// transactions and is used in various bloom filtering tests.  The first
+// transaction pays to a pay-to-pubkey script.
+var bloomTestBlock = "0100000082bb869cf3a793432a66e826e05a6fc37469f8efb7421dc880670100" +
+	"000000007f16c5962e8bd963659c793ce370d95f093bc7e367117b3c30c1f8fd" +
+	"d0d9728776381b4d4c86041b554b852907010000000100000000000000000000" +
+	"00000000000000000000000000000000000000000000ffffffff07044c86041b" +
+	"0136ffffffff0100f2052a01000000434104eaafc2314def4ca98ac970241bca" +
+	"b022b9c1e1f4ea423a20f134c876f2c01ec0f0dd5b2e86e7168cefe0d81113c3" +
+	"807420ce13ad1357231a2252247d97a46a91ac000000000100000001bcad20a6" +
+	"a29827d1424f08989255120bf7f3e9e3cdaaa6bb31b0737fe048724300000000" +
+	"494830450220356e834b046cadc0f8ebb5a8a017b02de59c86305403dad52cd7" +
+	"7b55af062ea10221009253cd6c119d4729b77c978e1e2aa19f5ea6e0e52b3f16" +
+	"e32fa608cd5bab753901ffffffff02008d380c010000001976a9142b4b8072ec" +
+	"bba129b6453c63e129e643207249ca88ac0065cd1d000000001976a9141b8dd1" +
+	"3b994bcfc787b32aeadf58ccb3615cbd5488ac000000000100000003fdacf9b3" +
+	"eb077412e7a968d2e4f11b9a9dee312d666187ed77ee7d26af16cb0b00000000" +
+	"8c493046022100ea1608e70911ca0de5af51ba57ad23b9a51db8d28f82c53563" +
+	"c56a05c20f5a87022100a8bdc8b4a8acc8634c6b420410150775eb7f2474f561" +
+	"5f7fccd65af30f310fbf01410465fdf49e29b06b9a1582287b6279014f834edc" +
+	"317695d125ef623c1cc3aaece245bd69fcad7508666e9c74a49dc9056d5fc143" +
+	"38ef38118dc4afae5fe2c585caffffffff309e1913634ecb50f3c4f83e96e70b" +
+	"2df071b497b8973a3e75429df397b5af83000000004948304502202bdb79c596" +
+	"a9ffc24e96f4386199aba386e9bc7b6071516e2b51dda942b3a1ed022100c53a" +
+	"857e76b724fc14d45311eac5019650d415c3abb5428f3aae16d8e69bec2301ff" +
+	"ffffff2089e33491695080c9edc18a428f7d834db5b6d372df13ce2b1b0e0cbc" +
+	"b1e6c10000000049483045022100d4ce67c5896ee251c810ac1ff9ceccd328b4" +
+	"97c8f553ab6e08431e7d40bad6b5022033119c0c2b7d792d31f1187779c7bd95" +
+	"aefd93d90a715586d73801d9b47471c601ffffffff0100714460030000001976" +
+	"a914c7b55141d097ea5df7a0ed330cf794376e53ec8d88ac0000000001000000" +
+	"045bf0e214aa4069a3e792ecee1e1bf0c1d397cde8dd08138f4b72a006817434" +
+	"47000000008b48304502200c45de8c4f3e2c1821f2fc878cba97b1e6f8807d94" +
+	"930713aa1c86a67b9bf1e40221008581abfef2e30f957815fc89978423746b20" +
+	"86375ca8ecf359c85c2a5b7c88ad01410462bb73f76ca0994fcb8b4271e6fb75" +
+	"61f5c0f9ca0cf6485261c4a0dc894f4ab844c6cdfb97cd0b60ffb5018ffd6238" +
+	"f4d87270efb1d3ae37079b794a92d7ec95ffffffffd669f7d7958d40fc59d225" +
+	"3d88e0f248e29b599c80bbcec344a83dda5f9aa72c000000008a473044022078" +
+	"124c8beeaa825f9e0b30bff96e564dd859432f2d0cb3b72d3d5d93d38d7e9302" +
+	"20691d233b6c0f995be5acb03d70a7f7a65b6bc9bdd426260f38a1346669507a" +
+	"3601410462bb73f76ca0994fcb8b4271e6fb7561f5c0f9ca0cf6485261c4a0dc" +
+	"894f4ab844c6cdfb97cd0b60ffb5018ffd6238f4d87270efb1d3ae37079b794a" +
+	"92d7ec95fffffffff878af0d93f5229a68166cf051fd372bb7a537232946e0a4" +
+	"6f53636b4dafdaa4000000008c493046022100c717d1714551663f69c3c5759b" +
+	"dbb3a0fcd3fab023abc0e522fe6440de35d8290221008d9cbe25bffc44af2b18" +
+	"e81c58eb37293fd7fe1c2e7b46fc37ee8c96c50ab1e201410462bb73f76ca099" +
+	"4fcb8b4271e6fb7561f5c0f9ca0cf6485261c4a0dc894f4ab844c6cdfb97cd0b" +
+	"60ffb5018ffd6238f4d87270efb1d3ae37079b794a92d7ec95ffffffff27f2b6" +
+	"68859cd7f2f894aa0fd2d9e60963bcd07c88973f425f999b8cbfd7a1e2000000" +
+	"008c493046022100e00847147cbf517bcc2f502f3ddc6d284358d102ed20d47a" +
+	"8aa788a62f0db780022100d17b2d6fa84dcaf1c95d88d7e7c30385aecf415588" +
+	"d749afd3ec81f6022cecd701410462bb73f76ca0994fcb8b4271e6fb7561f5c0" +
+	"f9ca0cf6485261c4a0dc894f4ab844c6cdfb97cd0b60ffb5018ffd6238f4d872" +
+	"70efb1d3ae37079b794a92d7ec95ffffffff0100c817a8040000001976a914b6" +
+	"efd80d99179f4f4ff6f4dd0a007d018c385d2188ac0000000001000000018345" +
+	"37b2f1ce8ef9373a258e10545ce5a50b758df616cd4356e0032554ebd3c40000" +
+	"00008b483045022100e68f422dd7c34fdce11eeb4509ddae38201773dd62f284" +
+	"e8aa9d96f85099d0b002202243bd399ff96b649a0fad05fa759d6a882f0af8c9" +
+	"0cf7632c2840c29070aec20141045e58067e815c2f464c6a2a15f98775837420" +
+	"3895710c2d452442e28496ff38ba8f5fd901dc20e29e88477167fe4fc299bf81" +
+	"8fd0d9e1632d467b2a3d9503b1aaffffffff0280d7e636030000001976a914f3" +
+	"4c3e10eb387efe872acb614c89e78bfca7815d88ac404b4c00000000001976a9" +
+	"14a84e272933aaf87e1715d7786c51dfaeb5b65a6f88ac000000000100000001" +
+	"43ac81c8e6f6ef307dfe17f3d906d999e23e0189fda838c5510d850927e03ae7" +
+	"000000008c4930460221009c87c344760a64cb8ae6685a3eec2c1ac1bed5b88c" +
+	"87de51acd0e124f266c16602210082d07c037359c3a257b5c63ebd90f5a5edf9" +
+	"7b2ac1c434b08ca998839f346dd40141040ba7e521fa7946d12edbb1d1e95a15" +
+	"c34bd4398195e86433c92b431cd315f455fe30032ede69cad9d1e1ed6c3c4ec0" +
+	"dbfced53438c625462afb792dcb098544bffffffff0240420f00000000001976" +
+	"a9144676d1b820d63ec272f1900d59d43bc6463d96f888ac40420f0000000000" +
+	"1976a914648d04341d00d7968b3405c034adc38d4d8fb9bd88ac000000000100" +
+	"00000248cc917501ea5c55f4a8d2009c0567c40cfe037c2e71af017d0a452ff7" +
+	"05e3f1000000008b483045022100bf5fdc86dc5f08a5d5c8e43a8c9d5b1ed8c6" +
+	"5562e280007b52b133021acd9acc02205e325d613e555f772802bf413d36ba80" +
+	"7892ed1a690a77811d3033b3de226e0a01410429fa713b124484cb2bd7b5557b" +
+	"2c0b9df7b2b1fee61825eadc5ae6c37a9920d38bfccdc7dc3cb0c47d7b173dbc" +
+	"9db8d37db0a33ae487982c59c6f8606e9d1791ffffffff41ed70551dd7e84188" +
+	"3ab8f0b16bf04176b7d1480e4f0af9f3d4c3595768d068000000008b48304502" +
+	"21008513ad65187b903aed1102d1d0c47688127658c51106753fed0151ce9c16" +
+	"b80902201432b9ebcb87bd04ceb2de66035fbbaf4bf8b00d1cfe41f1a1f7338f" +
+	"9ad79d210141049d4cf80125bf50be1709f718c07ad15d0fc612b7da1f5570dd" +
+	"dc35f2a352f0f27c978b06820edca9ef982c35fda2d255afba340068c5035552" +
+	"368bc7200c1488ffffffff0100093d00000000001976a9148edb68822f1ad580" +
+	"b043c7b3df2e400f8699eb4888ac00000000"
@@ -0,0 +1,174 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	// satoshiPerCent is the number of satoshi in one hundredth of a
+	// reddcoin.  Coin-age is accumulated in cent-seconds to limit rounding.
+	satoshiPerCent = SatoshiPerReddcoin / 100
+
+	// coinYearReward is the stake reward in satoshi for one coin-year,
+	// which is an annual interest of 5%.
+	coinYearReward = 5 * satoshiPerCent
+)
+
+// PoSVRules identifies a version of the PoSV consensus rules which determine
+// the coin-age, and therefore the stake reward, of a coinstake.
+type PoSVRules uint8
+
+const (
+	// PoSV indicates the original PoSV rules, under which coin-age is
+	// weighted by the velocity curve of CoinAgeWeight.
+	PoSV PoSVRules = 1
+
+	// PoSVv2 indicates the PoSV v2 rules, under which coin-age is weighted
+	// linearly by CoinAgeWeightLinear.
+	PoSVv2 PoSVRules = 2
+)
+
+// Map of PoSV rules back to their constant names for pretty printing.
+var posvRulesStrings = map[PoSVRules]string{
+	PoSV:   "PoSV",
+	PoSVv2: "PoSVv2",
+}
+
+// String returns the PoSVRules in human-readable form.
+func (r PoSVRules) String() string {
+	if s, ok := posvRulesStrings[r]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("Unknown PoSVRules (%d)", uint8(r))
+}
+
+// weight returns the coin-age weight in seconds of coins received at the
+// passed start time and spent at the passed end time under the rules.
+func (r PoSVRules) weight(start, end time.Time) int64 {
+	if r == PoSVv2 {
+		return CoinAgeWeightLinear(start, end)
+	}
+	return CoinAgeWeight(start, end)
+}
+
+// CoinAgeWeightLinear returns the weight in seconds of coins which were
+// received at the passed start time and staked at the passed end time under
+// the PoSV v2 rules.  Coins start gaining weight once they reach StakeMinAge
+// and their weight then grows linearly with time up to StakeMaxAge.
+func CoinAgeWeightLinear(start, end time.Time) int64 {
+	seconds := end.Unix() - start.Unix() - int64(StakeMinAge/time.Second)
+	if seconds < 0 {
+		return 0
+	}
+
+	maxAge := int64(StakeMaxAge / time.Second)
+	if seconds < maxAge {
+		return seconds
+	}
+	return maxAge
+}
+
+// SpentOutput describes a transaction output spent by a transaction input as
+// needed to calculate the coin-age it contributes.
+type SpentOutput struct {
+	// Value is the value of the output in satoshi.
+	Value int64
+
+	// Timestamp is the time of the transaction which created the output.
+	Timestamp time.Time
+
+	// BlockTime is the time of the block which contains the transaction
+	// which created the output.
+	BlockTime time.Time
+}
+
+// CalcCoinAge returns the coin-age in coin-days consumed by the passed
+// transaction under the passed PoSV rules.  The spent outputs must hold the
+// output spent by each input of the transaction in order.
+//
+// Each input contributes the value of the output it spends multiplied by the
+// coin-age weight (see CoinAgeWeight and CoinAgeWeightLinear) from the time of
+// the transaction which created the output until the time of the passed
+// transaction.  Outputs whose block is younger than StakeMinAge at that time
+// contribute nothing.  Coinbase transactions have no coin-age.
+//
+// An error is returned when the number of spent outputs does not match the
+// number of inputs or the transaction is older than an output it spends.
+func CalcCoinAge(tx *MsgTx, spent []SpentOutput, rules PoSVRules) (uint64, error) {
+	if tx.IsCoinBase() {
+		return 0, nil
+	}
+
+	if len(spent) != len(tx.TxIn) {
+		str := fmt.Sprintf("number of spent outputs does not match the "+
+			"number of inputs [spent %d, inputs %d]", len(spent),
+			len(tx.TxIn))
+		return 0, messageError("CalcCoinAge", str)
+	}
+
+	// The coin-age is accumulated in cent-seconds.
+	centSeconds := new(big.Int)
+	for i := range spent {
+		prevOut := &spent[i]
+		if tx.Timestamp.Before(prevOut.Timestamp) {
+			str := fmt.Sprintf("transaction time %v is before the "+
+				"time %v of the output spent by input %d",
+				tx.Timestamp, prevOut.Timestamp, i)
+			return 0, messageError("CalcCoinAge", str)
+		}
+
+		// Only coins which meet the minimum age have coin-age.
+		if prevOut.BlockTime.Add(StakeMinAge).After(tx.Timestamp) {
+			continue
+		}
+
+		weight := rules.weight(prevOut.Timestamp, tx.Timestamp)
+		v := new(big.Int).Mul(big.NewInt(prevOut.Value),
+			big.NewInt(weight))
+		centSeconds.Add(centSeconds, v.Quo(v, big.NewInt(satoshiPerCent)))
+	}
+
+	// Convert the cent-seconds to coin-days.
+	coinDays := centSeconds.Mul(centSeconds, big.NewInt(satoshiPerCent))
+	coinDays.Quo(coinDays, big.NewInt(SatoshiPerReddcoin))
+	coinDays.Quo(coinDays, big.NewInt(secondsPerDay))
+	return coinDays.Uint64(), nil
+}
+
+// CalcStakeReward returns the stake reward in satoshi for the passed coin-age
+// in coin-days, which is an annual interest of 5% over a year of 365 and 8/33
+// days.  The reward excludes the fees of the block.
+//
+// The reward takes no PoSV rules since the PoSV v2 rules only change how the
+// coin-age of a coinstake is accumulated (see CoinAgeWeightLinear), not the
+// reward per coin-day.  Under both rules the same coin-age earns the same
+// reward, so a coinstake earns a different reward under the PoSV v2 rules only
+// by consuming a different coin-age.
+func CalcStakeReward(coinAge uint64) int64 {
+	reward := new(big.Int).SetUint64(coinAge)
+	reward.Mul(reward, big.NewInt(coinYearReward*33))
+	return reward.Quo(reward, big.NewInt(365*33+8)).Int64()
+}
+
+// CalcCoinStakeReward returns the coin-age consumed by the passed coinstake
+// and the stake reward it may claim under the passed PoSV rules.  The rules
+// determine the coin-age, which is then converted to the reward in the same
+// way under all rules.  See CalcCoinAge for the requirements of the spent
+// outputs and CalcStakeReward for the reward.
+func CalcCoinStakeReward(tx *MsgTx, spent []SpentOutput,
+	rules PoSVRules) (uint64, int64, error) {
+
+	coinAge, err := CalcCoinAge(tx, spent, rules)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return coinAge, CalcStakeReward(coinAge), nil
+}
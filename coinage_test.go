@@ -0,0 +1,215 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+)
+
+// TestCoinAgeWeightLinear tests the coin-age weight of coins under the PoSV v2
+// rules.
+func TestCoinAgeWeightLinear(t *testing.T) {
+	start := time.Unix(1400000000, 0)
+	minAge := int64(rddwire.StakeMinAge / time.Second)
+	maxAge := int64(rddwire.StakeMaxAge / time.Second)
+
+	tests := []struct {
+		age  int64 // Age of the coins in seconds
+		want int64 // Expected weight in seconds
+	}{
+		{-1000, 0},
+		{0, 0},
+		{minAge, 0},
+		{minAge + 1, 1},
+		{minAge + 86400, 86400},
+		{minAge + maxAge, maxAge},
+		{minAge + maxAge + 1, maxAge},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		end := start.Add(time.Duration(test.age) * time.Second)
+		got := rddwire.CoinAgeWeightLinear(start, end)
+		if got != test.want {
+			t.Errorf("CoinAgeWeightLinear #%d: got %d, want %d", i,
+				got, test.want)
+			continue
+		}
+	}
+}
+
+// TestCalcCoinStakeReward tests the coin-age and stake reward of coinstakes
+// under the PoSV and PoSV v2 rules.
+func TestCalcCoinStakeReward(t *testing.T) {
+	txTime := time.Unix(1400000000, 0)
+	day := 24 * time.Hour
+
+	// Coinstake which spends three outputs.
+	coinstake := rddwire.NewMsgTx()
+	for i := 0; i < 3; i++ {
+		prevOut := rddwire.NewOutPoint(&rddwire.ShaHash{0x01}, uint32(i))
+		coinstake.AddTxIn(rddwire.NewTxIn(prevOut, nil))
+	}
+	coinstake.AddTxOut(rddwire.NewTxOut(0, nil))
+	coinstake.AddTxOut(rddwire.NewTxOut(13000*rddwire.SatoshiPerReddcoin,
+		[]byte{0x51}))
+	coinstake.Timestamp = txTime
+
+	// Outputs spent by the coinstake.  The last one does not meet the
+	// minimum age, so it has no coin-age.
+	spent := []rddwire.SpentOutput{
+		{
+			Value:     10000 * rddwire.SatoshiPerReddcoin,
+			Timestamp: txTime.Add(-30 * day),
+			BlockTime: txTime.Add(-30*day + time.Minute),
+		},
+		{
+			Value:     2500*rddwire.SatoshiPerReddcoin + 12345,
+			Timestamp: txTime.Add(-3 * day),
+			BlockTime: txTime.Add(-3*day + 30*time.Second),
+		},
+		{
+			Value:     500 * rddwire.SatoshiPerReddcoin,
+			Timestamp: txTime.Add(-time.Hour),
+			BlockTime: txTime.Add(-50 * time.Minute),
+		},
+	}
+
+	// Coinstake which only spends the last output.
+	youngStake := coinstake.Copy()
+	youngStake.TxIn = youngStake.TxIn[2:]
+
+	tests := []struct {
+		tx      *rddwire.MsgTx        // Coinstake
+		spent   []rddwire.SpentOutput // Outputs spent by the coinstake
+		rules   rddwire.PoSVRules     // PoSV rules
+		coinAge uint64                // Expected coin-age
+		reward  int64                 // Expected stake reward
+	}{
+		{coinstake, spent, rddwire.PoSV, 212794, 2913051522},
+		{coinstake, spent, rddwire.PoSVv2, 303333, 4152488592},
+		{youngStake, spent[2:], rddwire.PoSV, 0, 0},
+		{youngStake, spent[2:], rddwire.PoSVv2, 0, 0},
+		{multiTx, nil, rddwire.PoSV, 0, 0},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		coinAge, reward, err := rddwire.CalcCoinStakeReward(test.tx,
+			test.spent, test.rules)
+		if err != nil {
+			t.Errorf("CalcCoinStakeReward #%d (%v): %v", i,
+				test.rules, err)
+			continue
+		}
+		if coinAge != test.coinAge {
+			t.Errorf("CalcCoinStakeReward #%d (%v): got coin-age %d, "+
+				"want %d", i, test.rules, coinAge, test.coinAge)
+			continue
+		}
+		if reward != test.reward {
+			t.Errorf("CalcCoinStakeReward #%d (%v): got reward %d, "+
+				"want %d", i, test.rules, reward, test.reward)
+			continue
+		}
+
+		// The rules only change the coin-age, so the reward must be
+		// the reward of the coin-age regardless of the rules.
+		if want := rddwire.CalcStakeReward(coinAge); reward != want {
+			t.Errorf("CalcCoinStakeReward #%d (%v): reward %d is not "+
+				"the reward %d of coin-age %d", i, test.rules, reward,
+				want, coinAge)
+			continue
+		}
+	}
+}
+
+// TestCalcStakeReward tests the stake reward of coin-ages, which is the same
+// under the PoSV and PoSV v2 rules.
+func TestCalcStakeReward(t *testing.T) {
+	tests := []struct {
+		coinAge uint64 // Coin-age in coin-days
+		reward  int64  // Expected stake reward
+	}{
+		{0, 0},
+		{1, 13689},
+		{365, 4996681},
+		{212794, 2913051522},
+		{303333, 4152488592},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		reward := rddwire.CalcStakeReward(test.coinAge)
+		if reward != test.reward {
+			t.Errorf("CalcStakeReward #%d: got %d, want %d", i,
+				reward, test.reward)
+			continue
+		}
+	}
+}
+
+// TestCalcCoinAgeErrors performs negative tests against calculating the
+// coin-age of transactions.
+func TestCalcCoinAgeErrors(t *testing.T) {
+	txTime := time.Unix(1400000000, 0)
+	tx := rddwire.NewMsgTx()
+	tx.AddTxIn(rddwire.NewTxIn(&rddwire.OutPoint{}, nil))
+	tx.Timestamp = txTime
+
+	spent := rddwire.SpentOutput{
+		Value:     rddwire.SatoshiPerReddcoin,
+		Timestamp: txTime.Add(-30 * 24 * time.Hour),
+		BlockTime: txTime.Add(-30 * 24 * time.Hour),
+	}
+	future := spent
+	future.Timestamp = txTime.Add(time.Second)
+
+	tests := []struct {
+		spent []rddwire.SpentOutput // Outputs spent by the transaction
+		err   error                 // Expected error
+	}{
+		{[]rddwire.SpentOutput{spent}, nil},
+		{nil, &rddwire.MessageError{}},
+		{[]rddwire.SpentOutput{spent, spent}, &rddwire.MessageError{}},
+		{[]rddwire.SpentOutput{future}, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		_, err := rddwire.CalcCoinAge(tx, test.spent, rddwire.PoSV)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("CalcCoinAge #%d wrong error got: %v, want: %v",
+				i, err, reflect.TypeOf(test.err))
+			continue
+		}
+	}
+}
+
+// TestPoSVRulesStringer tests the stringized output for PoSVRules.
+func TestPoSVRulesStringer(t *testing.T) {
+	tests := []struct {
+		in   rddwire.PoSVRules
+		want string
+	}{
+		{rddwire.PoSV, "PoSV"},
+		{rddwire.PoSVv2, "PoSVv2"},
+		{0xff, "Unknown PoSVRules (255)"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := test.in.String()
+		if result != test.want {
+			t.Errorf("String #%d\n got: %s want: %s", i, result,
+				test.want)
+			continue
+		}
+	}
+}
@@ -149,6 +149,15 @@ func readElement(r io.Reader, element interface{}) error {
 		}
 		*e = RejectCode(b[0])
 		return nil
+
+	case *FilterType:
+		b := scratch[0:1]
+		_, err := io.ReadFull(r, b)
+		if err != nil {
+			return err
+		}
+		*e = FilterType(b[0])
+		return nil
 	}
 
 	// Fall back to the slower binary.Read if a fast path was not available
@@ -299,6 +308,15 @@ func writeElement(w io.Writer, element interface{}) error {
 			return err
 		}
 		return nil
+
+	case FilterType:
+		b := scratch[0:1]
+		b[0] = uint8(e)
+		_, err := w.Write(b)
+		if err != nil {
+			return err
+		}
+		return nil
 	}
 
 	// Fall back to the slower binary.Write if a fast path was not available
@@ -0,0 +1,241 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"math/big"
+)
+
+var (
+	// bigOne is 1 represented as a big.Int.  It is defined here to avoid
+	// the overhead of creating it multiple times.
+	bigOne = big.NewInt(1)
+
+	// oneLsh256 is 1 shifted left 256 bits.  It is defined here to avoid
+	// the overhead of creating it multiple times.
+	oneLsh256 = new(big.Int).Lsh(bigOne, 256)
+)
+
+var (
+	// MainNetPowLimit is the highest proof of work value a Reddcoin block
+	// can have for the main network.  It is the value 2^236 - 1.
+	MainNetPowLimit = new(big.Int).Sub(new(big.Int).Lsh(bigOne, 236), bigOne)
+
+	// TestNetPowLimit is the highest proof of work value a Reddcoin block
+	// can have for the regression test network.  It is the value
+	// 2^255 - 1.
+	TestNetPowLimit = new(big.Int).Sub(new(big.Int).Lsh(bigOne, 255), bigOne)
+
+	// TestNet3PowLimit is the highest proof of work value a Reddcoin block
+	// can have for the test network (version 3).  It is the value
+	// 2^236 - 1.
+	TestNet3PowLimit = new(big.Int).Sub(new(big.Int).Lsh(bigOne, 236), bigOne)
+
+	// SimNetPowLimit is the highest proof of work value a Reddcoin block
+	// can have for the simulation test network.  It is the value 2^255 - 1.
+	SimNetPowLimit = new(big.Int).Sub(new(big.Int).Lsh(bigOne, 255), bigOne)
+)
+
+// hashToBig converts a ShaHash into a big.Int that can be used to perform
+// math comparisons.
+func hashToBig(hash *ShaHash) *big.Int {
+	// A ShaHash is in little-endian, but the big package wants the bytes in
+	// big-endian, so reverse them.
+	buf := *hash
+	blen := len(buf)
+	for i := 0; i < blen/2; i++ {
+		buf[i], buf[blen-1-i] = buf[blen-1-i], buf[i]
+	}
+
+	return new(big.Int).SetBytes(buf[:])
+}
+
+// CompactToBig converts the compact representation of a whole number N, which
+// is an unsigned 32-bit number, to a big integer.  The representation is
+// similar to IEEE754 floating point numbers.
+//
+// Like IEEE754 floating point, there are three basic components: the sign,
+// the exponent, and the mantissa.  They are broken out as follows:
+//
+//   - the most significant 8 bits represent the unsigned base 256 exponent
+//
+//   - bit 23 (the 24th bit) represents the sign bit
+//
+//   - the least significant 23 bits represent the mantissa
+//
+//     -------------------------------------------------
+//     |   Exponent     |    Sign    |    Mantissa     |
+//     -------------------------------------------------
+//     | 8 bits [31-24] | 1 bit [23] | 23 bits [22-00] |
+//     -------------------------------------------------
+//
+// The formula to calculate N is:
+//
+//	N = (-1^sign) * mantissa * 256^(exponent-3)
+//
+// This compact form is only used in Reddcoin to encode unsigned 256-bit
+// numbers which represent difficulty targets, such as the Bits field of a
+// block header.
+func CompactToBig(compact uint32) *big.Int {
+	// Extract the mantissa, sign bit, and exponent.
+	mantissa := compact & 0x007fffff
+	isNegative := compact&0x00800000 != 0
+	exponent := uint(compact >> 24)
+
+	// Since the base for the exponent is 256, the exponent can be treated
+	// as the number of bytes to represent the full 256-bit number.  So,
+	// treat the exponent as the number of bytes and shift the mantissa
+	// right or left accordingly.  This is equivalent to:
+	// N = mantissa * 256^(exponent-3)
+	var bn *big.Int
+	if exponent <= 3 {
+		mantissa >>= 8 * (3 - exponent)
+		bn = big.NewInt(int64(mantissa))
+	} else {
+		bn = big.NewInt(int64(mantissa))
+		bn.Lsh(bn, 8*(exponent-3))
+	}
+
+	// Make it negative if the sign bit is set.
+	if isNegative {
+		bn = bn.Neg(bn)
+	}
+
+	return bn
+}
+
+// BigToCompact converts a whole number N to a compact representation using
+// an unsigned 32-bit number.  The compact representation only provides 23 bits
+// of precision, so values larger than (2^23 - 1) only encode the most
+// significant digits of the number.  See CompactToBig for details.
+func BigToCompact(n *big.Int) uint32 {
+	// No need to do any work if it's zero.
+	if n.Sign() == 0 {
+		return 0
+	}
+
+	// Since the base for the exponent is 256, the exponent can be treated
+	// as the number of bytes.  So, shift the number right or left
+	// accordingly.  This is equivalent to:
+	// mantissa = mantissa / 256^(exponent-3)
+	var mantissa uint32
+	exponent := uint(len(n.Bytes()))
+	if exponent <= 3 {
+		mantissa = uint32(n.Bits()[0])
+		mantissa <<= 8 * (3 - exponent)
+	} else {
+		// Use a copy to avoid modifying the caller's original number.
+		tn := new(big.Int).Set(n)
+		mantissa = uint32(tn.Rsh(tn, 8*(exponent-3)).Bits()[0])
+	}
+
+	// When the mantissa already has the sign bit set, the number is too
+	// large to fit into the available 23-bits, so divide the number by 256
+	// and increment the exponent accordingly.
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	// Pack the exponent, sign bit, and mantissa into an unsigned 32-bit
+	// int and return it.
+	compact := uint32(exponent<<24) | mantissa
+	if n.Sign() < 0 {
+		compact |= 0x00800000
+	}
+	return compact
+}
+
+// CalcWork calculates a work value from difficulty bits.  Reddcoin increases
+// the difficulty for generating a block by decreasing the value which the
+// generated hash must be less than.  This difficulty target is stored in each
+// block header using a compact representation as described in the
+// documentation for CompactToBig.  The main chain is selected by choosing the
+// chain that has the most proof of work (highest difficulty).  Since a lower
+// target difficulty value equates to higher actual difficulty, the work value
+// which will be accumulated must be the inverse of the difficulty.  Also, in
+// order to avoid potential division by zero and really small floating point
+// numbers, the result adds 1 to the denominator and multiplies the numerator
+// by 2^256.
+func CalcWork(bits uint32) *big.Int {
+	// Return a work value of zero if the passed difficulty bits represent
+	// a negative number.  Note this should not happen in practice with
+	// valid blocks, but an invalid block could trigger it.
+	difficultyNum := CompactToBig(bits)
+	if difficultyNum.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+
+	// (1 << 256) / (difficultyNum + 1)
+	denominator := new(big.Int).Add(difficultyNum, bigOne)
+	return new(big.Int).Div(oneLsh256, denominator)
+}
+
+// CheckProofOfWork ensures the passed block header at the passed height meets
+// the proof of work rules of the network described by the passed parameters
+// and returns whether the header is a PoSV header whose proof of stake the
+// caller must still check.
+//
+// The target difficulty described by the Bits of the header must be in the
+// range 1 through the proof of work limit of the network.  Headers below the
+// PoSV height of the network must be proof-of-work headers (Version <=
+// PowBlockVersion) whose scrypt proof of work hash (see PoWHash) does not
+// exceed the target.  Headers from the PoSV height onwards must be PoSV headers
+// (Version > PowBlockVersion).  Their Bits describe the stake target which the
+// stake kernel hash of the block must meet instead, so their hash is not
+// checked and true is returned.  Passing this check then proves nothing about
+// a PoSV header, which must not be accepted, nor its trust credited (see
+// CalcBlockTrust), until its stake kernel was checked with StakeKernel.Check.
+//
+// The genesis block of a network is exempt from the proof of work check and
+// must not be passed to this function.  In particular, the proof of work hash
+// of the main network genesis block (e33d5655...) is higher than the target of
+// its Bits, so it fails the check even though it is valid.  HeaderChain only
+// checks the headers which are added after the genesis block.
+func CheckProofOfWork(header *BlockHeader, height int32, params *Params) (bool, error) {
+	// The target difficulty must be larger than zero.
+	target := CompactToBig(header.Bits)
+	if target.Sign() <= 0 {
+		str := fmt.Sprintf("block target difficulty of %064x is too "+
+			"low", target)
+		return false, messageError("CheckProofOfWork", str)
+	}
+
+	// The target difficulty must be less than the maximum allowed.
+	if target.Cmp(params.PowLimit) > 0 {
+		str := fmt.Sprintf("block target difficulty of %064x is higher "+
+			"than max of %064x", target, params.PowLimit)
+		return false, messageError("CheckProofOfWork", str)
+	}
+
+	// The version of the header must match the consensus era of its
+	// height, so a header can't avoid the proof of work check by claiming
+	// to be a PoSV header.
+	isPoSV := header.Version > PowBlockVersion
+	if isPoSV != params.IsPoSV(height) {
+		str := fmt.Sprintf("block header at height %d has version %d, "+
+			"but the PoSV height of the network is %d", height,
+			header.Version, params.PoSVHeight)
+		return false, messageError("CheckProofOfWork", str)
+	}
+	if isPoSV {
+		return true, nil
+	}
+
+	// The proof of work hash must be less than the claimed target.
+	hash, err := header.PoWHash()
+	if err != nil {
+		return false, err
+	}
+	if hashToBig(&hash).Cmp(target) > 0 {
+		str := fmt.Sprintf("block proof of work hash of %064x is "+
+			"higher than expected max of %064x", hashToBig(&hash),
+			target)
+		return false, messageError("CheckProofOfWork", str)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+)
+
+// TestCompactToBig tests the conversions between the compact representation
+// of a whole number and a big integer.
+func TestCompactToBig(t *testing.T) {
+	tests := []struct {
+		compact   uint32 // Compact representation
+		n         string // Expected number in hex
+		canonical bool   // Whether the number converts back to compact
+	}{
+		{0x00000000, "0", true},
+		{0x01003456, "0", false},
+		{0x01123456, "12", false},
+		{0x02008000, "80", true},
+		{0x05009234, "92340000", true},
+		{0x04923456, "-12345600", true},
+		{0x04123456, "12345600", true},
+		{0x1d00ffff, "ffff0000000000000000000000000000000000000000000000000000", true},
+		{0x1e0fffff, "fffff000000000000000000000000000000000000000000000000000000", true},
+		{0x207fffff, "7fffff0000000000000000000000000000000000000000000000000000000000", true},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		want, ok := new(big.Int).SetString(test.n, 16)
+		if !ok {
+			t.Errorf("SetString #%d: invalid number %s", i, test.n)
+			continue
+		}
+
+		n := rddwire.CompactToBig(test.compact)
+		if n.Cmp(want) != 0 {
+			t.Errorf("CompactToBig #%d: got %x want %x", i, n, want)
+			continue
+		}
+
+		if !test.canonical {
+			continue
+		}
+		compact := rddwire.BigToCompact(want)
+		if compact != test.compact {
+			t.Errorf("BigToCompact #%d: got %08x want %08x", i,
+				compact, test.compact)
+			continue
+		}
+	}
+}
+
+// TestBigToCompact tests that the proof of work limits of each network convert
+// to their expected compact representations.
+func TestBigToCompact(t *testing.T) {
+	tests := []struct {
+		name    string   // Test description
+		n       *big.Int // Number to convert
+		compact uint32   // Expected compact representation
+	}{
+		{"MainNet", rddwire.MainNetPowLimit, 0x1e0fffff},
+		{"TestNet", rddwire.TestNetPowLimit, 0x207fffff},
+		{"TestNet3", rddwire.TestNet3PowLimit, 0x1e0fffff},
+		{"SimNet", rddwire.SimNetPowLimit, 0x207fffff},
+		{"large mantissa", big.NewInt(0x800000), 0x04008000},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		compact := rddwire.BigToCompact(test.n)
+		if compact != test.compact {
+			t.Errorf("BigToCompact %s: got %08x want %08x", test.name,
+				compact, test.compact)
+			continue
+		}
+	}
+}
+
+// TestCalcWork tests calculating the work value from difficulty bits.
+func TestCalcWork(t *testing.T) {
+	tests := []struct {
+		bits uint32 // Difficulty bits
+		work int64  // Expected work value
+	}{
+		{0x1d00ffff, 0x100010001},
+		{0x1e0fffff, 0x100001},
+		{0x207fffff, 2},
+		{0x00000000, 0},
+		{0x04923456, 0},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		work := rddwire.CalcWork(test.bits)
+		if work.Cmp(big.NewInt(test.work)) != 0 {
+			t.Errorf("CalcWork #%d: got %v want %v", i, work, test.work)
+			continue
+		}
+	}
+}
+
+// TestCheckProofOfWork tests checking the proof of work of block headers
+// against their difficulty bits.
+func TestCheckProofOfWork(t *testing.T) {
+	mainNet := &rddwire.MainNetParams
+	simNet := &rddwire.SimNetParams
+	posvHeight := mainNet.PoSVHeight
+
+	// Scrypt mined header with a nonce which no longer meets the target.
+	badNonce := scryptHeader
+	badNonce.Nonce++
+
+	// Scrypt mined header with a target above the main network limit.
+	highTarget := scryptHeader
+	highTarget.Bits = 0x1f00ffff
+
+	// PoSV block headers are only checked for the range of the target.
+	posv := badNonce
+	posv.Version = rddwire.BlockVersion
+	posvHighTarget := highTarget
+	posvHighTarget.Version = rddwire.BlockVersion
+
+	// The main network genesis block doesn't meet its own target, which is
+	// why the genesis block is exempt from the check.
+	genesis := rddwire.MainNetParams.GenesisBlock.Header
+
+	// Negative and zero targets.
+	negative := scryptHeader
+	negative.Bits = 0x1d80ffff
+	zero := scryptHeader
+	zero.Bits = 0
+
+	tests := []struct {
+		name   string              // Test description
+		header rddwire.BlockHeader // Block header to check
+		height int32               // Height of the block
+		params *rddwire.Params     // Network parameters
+		posv   bool                // Expected stake check result
+		err    error               // Expected error
+	}{
+		{"scrypt header", scryptHeader, 1, mainNet, false, nil},
+		{"sha256d header", blockOne.Header, 1, mainNet, false,
+			&rddwire.MessageError{}},
+		{"mainnet genesis", genesis, 1, mainNet, false,
+			&rddwire.MessageError{}},
+		{"bad nonce", badNonce, 1, mainNet, false,
+			&rddwire.MessageError{}},
+		{"high target", highTarget, 1, mainNet, false,
+			&rddwire.MessageError{}},
+		{"scrypt header at PoSV height", scryptHeader, posvHeight,
+			mainNet, false, &rddwire.MessageError{}},
+		{"posv", posv, posvHeight, mainNet, true, nil},
+		{"posv before PoSV height", posv, posvHeight - 1, mainNet,
+			false, &rddwire.MessageError{}},
+		{"posv high target", posvHighTarget, posvHeight, mainNet, false,
+			&rddwire.MessageError{}},
+		{"negative target", negative, 1, simNet, false,
+			&rddwire.MessageError{}},
+		{"zero target", zero, 1, simNet, false, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		posv, err := rddwire.CheckProofOfWork(&test.header, test.height,
+			test.params)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("CheckProofOfWork %s: wrong error - got %v, "+
+				"want %v", test.name, err, test.err)
+			continue
+		}
+		if posv != test.posv {
+			t.Errorf("CheckProofOfWork %s: wrong stake check result "+
+				"- got %v, want %v", test.name, posv, test.posv)
+			continue
+		}
+	}
+}
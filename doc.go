@@ -88,6 +88,28 @@ the following constants:
 	rddwire.TestNet3 (Test network version 3)
 	rddwire.SimNet   (Simulation test network)
 
+Network Parameters
+
+The parameters of a Reddcoin network, such as its default port, genesis block,
+proof of work limit, PoSV activation heights, address magics, DNS seeds and
+checkpoints, are defined by a Params.  The parameters of the main, regression
+test and simulation test networks are registered by default and are available
+as MainNetParams, TestNetParams and SimNetParams, or by network via
+ParamsForNet.  Applications may register the parameters of custom networks,
+such as a private regression test network, with Register.  For example:
+
+	var regTestParams = rddwire.Params{
+		Name: "RegTest",
+		Net:  0xdab5bffa,
+		...
+	}
+
+	func init() {
+		if err := rddwire.Register(&regTestParams); err != nil {
+			panic(err)
+		}
+	}
+
 Determining Message Type
 
 As discussed in the Reddcoin message overview section, this package reads
@@ -155,5 +177,13 @@ This package includes spec changes outlined by the following BIPs:
 		BIP0031 (https://en.Bitcoin.it/wiki/BIP_0031)
 		BIP0035 (https://en.Bitcoin.it/wiki/BIP_0035)
 		BIP0037 (https://en.Bitcoin.it/wiki/BIP_0037)
+		BIP0130 (https://github.com/bitcoin/bips/blob/master/bip-0130.mediawiki)
+		BIP0133 (https://github.com/bitcoin/bips/blob/master/bip-0133.mediawiki)
+		BIP0144 (https://github.com/bitcoin/bips/blob/master/bip-0144.mediawiki)
+		BIP0152 (https://github.com/bitcoin/bips/blob/master/bip-0152.mediawiki)
+		BIP0155 (https://github.com/bitcoin/bips/blob/master/bip-0155.mediawiki)
+		BIP0157 (https://github.com/bitcoin/bips/blob/master/bip-0157.mediawiki)
+		BIP0158 (https://github.com/bitcoin/bips/blob/master/bip-0158.mediawiki)
+		BIP0339 (https://github.com/bitcoin/bips/blob/master/bip-0339.mediawiki)
 */
 package rddwire
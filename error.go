@@ -32,3 +32,118 @@ func (e *MessageError) Error() string {
 func messageError(f string, desc string) *MessageError {
 	return &MessageError{Func: f, Description: desc}
 }
+
+// ErrorCode identifies a kind of rule violation reported by the sanity checks
+// of transactions and blocks (see CheckTransactionSanity and CheckBlockSanity).
+type ErrorCode int
+
+// These constants are used to identify a specific RuleError.
+const (
+	// ErrNoTxInputs indicates a transaction does not have any inputs.
+	ErrNoTxInputs ErrorCode = iota
+
+	// ErrNoTxOutputs indicates a transaction does not have any outputs.
+	ErrNoTxOutputs
+
+	// ErrTxTooBig indicates a transaction exceeds the maximum allowed size
+	// when serialized.
+	ErrTxTooBig
+
+	// ErrNegativeTxOutValue indicates an output of a transaction has a
+	// negative value.
+	ErrNegativeTxOutValue
+
+	// ErrTxOutValueTooHigh indicates an output of a transaction, or the
+	// total of all of its outputs, has a value higher than MaxSatoshi.
+	ErrTxOutValueTooHigh
+
+	// ErrDuplicateTxInputs indicates a transaction spends the same outpoint
+	// more than once.
+	ErrDuplicateTxInputs
+
+	// ErrBadCoinbaseScriptLen indicates the length of the signature script
+	// of a coinbase transaction is not within the allowed range.
+	ErrBadCoinbaseScriptLen
+
+	// ErrBadTxInput indicates an input of a transaction other than a
+	// coinbase spends the null outpoint.
+	ErrBadTxInput
+
+	// ErrNoTransactions indicates a block does not have any transactions.
+	ErrNoTransactions
+
+	// ErrBlockTooBig indicates a block exceeds MaxBlockPayload when
+	// serialized.
+	ErrBlockTooBig
+
+	// ErrFirstTxNotCoinbase indicates the first transaction of a block is
+	// not a coinbase.
+	ErrFirstTxNotCoinbase
+
+	// ErrMultipleCoinbases indicates a block has more than one coinbase.
+	ErrMultipleCoinbases
+
+	// ErrCoinStakeMisplaced indicates a block has a coinstake other than
+	// as the second transaction of a PoSV block.
+	ErrCoinStakeMisplaced
+
+	// ErrCoinbaseNotEmpty indicates the coinbase of a PoSV block has an
+	// output which is not empty.
+	ErrCoinbaseNotEmpty
+
+	// ErrTxTimestampTooNew indicates a transaction of a PoSV block has a
+	// Timestamp after the timestamp of the block.
+	ErrTxTimestampTooNew
+
+	// ErrBadMerkleRoot indicates the merkle root in the header of a block
+	// does not match the transactions of the block or the merkle tree of
+	// the transactions was mutated.
+	ErrBadMerkleRoot
+)
+
+// Map of ErrorCode values back to their constant names for pretty printing.
+var errorCodeStrings = map[ErrorCode]string{
+	ErrNoTxInputs:           "ErrNoTxInputs",
+	ErrNoTxOutputs:          "ErrNoTxOutputs",
+	ErrTxTooBig:             "ErrTxTooBig",
+	ErrNegativeTxOutValue:   "ErrNegativeTxOutValue",
+	ErrTxOutValueTooHigh:    "ErrTxOutValueTooHigh",
+	ErrDuplicateTxInputs:    "ErrDuplicateTxInputs",
+	ErrBadCoinbaseScriptLen: "ErrBadCoinbaseScriptLen",
+	ErrBadTxInput:           "ErrBadTxInput",
+	ErrNoTransactions:       "ErrNoTransactions",
+	ErrBlockTooBig:          "ErrBlockTooBig",
+	ErrFirstTxNotCoinbase:   "ErrFirstTxNotCoinbase",
+	ErrMultipleCoinbases:    "ErrMultipleCoinbases",
+	ErrCoinStakeMisplaced:   "ErrCoinStakeMisplaced",
+	ErrCoinbaseNotEmpty:     "ErrCoinbaseNotEmpty",
+	ErrTxTimestampTooNew:    "ErrTxTimestampTooNew",
+	ErrBadMerkleRoot:        "ErrBadMerkleRoot",
+}
+
+// String returns the ErrorCode as a human-readable name.
+func (e ErrorCode) String() string {
+	if s := errorCodeStrings[e]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown ErrorCode (%d)", int(e))
+}
+
+// RuleError describes a transaction or block which violates a rule checked by
+// CheckTransactionSanity or CheckBlockSanity.  The caller can type assert the
+// error and use the ErrorCode field to determine the specific rule which was
+// violated.
+type RuleError struct {
+	ErrorCode   ErrorCode // Describes the kind of error
+	Description string    // Human readable description of the issue
+}
+
+// Error satisfies the error interface and prints human-readable errors.
+func (e *RuleError) Error() string {
+	return e.Description
+}
+
+// ruleError creates a RuleError given a set of arguments.
+func ruleError(c ErrorCode, desc string) *RuleError {
+	return &RuleError{ErrorCode: c, Description: desc}
+}
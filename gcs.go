@@ -0,0 +1,331 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+
+	"github.com/dchest/siphash"
+)
+
+// GCSKeySize is the size in bytes of the SipHash key used to hash the items
+// of a Golomb-coded set filter.
+const GCSKeySize = 16
+
+// maxGCSFilterP is the maximum Golomb-Rice coding parameter supported by
+// GCSFilter.
+const maxGCSFilterP = 32
+
+// GCSFilter describes an immutable Golomb-coded set filter as defined by
+// BIP0158.  The filter commits to a set of N items which are hashed with
+// SipHash into the range [0, N*M), sorted, and then the differences between
+// successive values are Golomb-Rice coded with parameter P.
+//
+// Filters are created with NewGCSFilter or, for filters received from the
+// network, NewGCSFilterFromBytes.
+type GCSFilter struct {
+	n       uint32
+	p       uint8
+	modulus uint64
+	data    []byte
+}
+
+// N returns the number of items committed to by the filter.
+func (f *GCSFilter) N() uint32 {
+	return f.n
+}
+
+// P returns the Golomb-Rice coding parameter of the filter.
+func (f *GCSFilter) P() uint8 {
+	return f.p
+}
+
+// Bytes returns the serialized filter which is the number of items encoded as
+// a variable length integer followed by the Golomb-Rice coded set.  This is
+// the format carried by the cfilter message (MsgCFilter).
+func (f *GCSFilter) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.Grow(VarIntSerializeSize(uint64(f.n)) + len(f.data))
+
+	// Writing to a bytes.Buffer can't fail.
+	_ = writeVarInt(&buf, 0, uint64(f.n))
+	buf.Write(f.data)
+	return buf.Bytes()
+}
+
+// Match returns whether the passed item is likely a member of the set
+// committed to by the filter.  False positives occur at a rate of roughly
+// 1/M, but there are no false negatives.  The key must be the same key that
+// was used to create the filter.
+func (f *GCSFilter) Match(key [GCSKeySize]byte, item []byte) bool {
+	if f.n == 0 {
+		return false
+	}
+	target := gcsHashToRange(key, item, f.modulus)
+
+	r := newBitReader(f.data)
+	var value uint64
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := r.readGolombRice(f.p)
+		if err != nil {
+			return false
+		}
+		value += delta
+		switch {
+		case value == target:
+			return true
+		case value > target:
+			return false
+		}
+	}
+
+	return false
+}
+
+// MatchAny returns whether any of the passed items are likely members of the
+// set committed to by the filter.  It is more efficient than calling Match for
+// each item since the filter only needs to be decoded once.  The key must be
+// the same key that was used to create the filter.
+func (f *GCSFilter) MatchAny(key [GCSKeySize]byte, items [][]byte) bool {
+	if f.n == 0 || len(items) == 0 {
+		return false
+	}
+
+	targets := make([]uint64, 0, len(items))
+	for _, item := range items {
+		targets = append(targets, gcsHashToRange(key, item, f.modulus))
+	}
+	sort.Sort(uint64Slice(targets))
+
+	// Walk the sorted filter values and the sorted targets together
+	// looking for a value in common.
+	r := newBitReader(f.data)
+	var value uint64
+	var ti int
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := r.readGolombRice(f.p)
+		if err != nil {
+			return false
+		}
+		value += delta
+		for ti < len(targets) && targets[ti] < value {
+			ti++
+		}
+		if ti == len(targets) {
+			return false
+		}
+		if targets[ti] == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewGCSFilter builds a new Golomb-coded set filter with the Golomb-Rice
+// coding parameter p and false positive rate 1/m which commits to the passed
+// items using the passed SipHash key.  The items are expected to be unique.
+func NewGCSFilter(p uint8, m uint64, key [GCSKeySize]byte,
+	items [][]byte) (*GCSFilter, error) {
+
+	if p > maxGCSFilterP {
+		str := fmt.Sprintf("filter parameter P is too large [p %v, "+
+			"max %v]", p, maxGCSFilterP)
+		return nil, messageError("NewGCSFilter", str)
+	}
+	if uint64(len(items)) > uint64(^uint32(0)) {
+		str := fmt.Sprintf("too many items for filter [count %v, "+
+			"max %v]", len(items), ^uint32(0))
+		return nil, messageError("NewGCSFilter", str)
+	}
+
+	f := GCSFilter{
+		n:       uint32(len(items)),
+		p:       p,
+		modulus: uint64(len(items)) * m,
+	}
+
+	// Hash each item into the range [0, N*M) and sort the results so the
+	// differences between successive values can be encoded.
+	values := make([]uint64, 0, len(items))
+	for _, item := range items {
+		values = append(values, gcsHashToRange(key, item, f.modulus))
+	}
+	sort.Sort(uint64Slice(values))
+
+	var w bitWriter
+	var lastValue uint64
+	for _, v := range values {
+		w.writeGolombRice(v-lastValue, p)
+		lastValue = v
+	}
+	f.data = w.bytes()
+
+	return &f, nil
+}
+
+// NewGCSFilterFromBytes returns a Golomb-coded set filter with the Golomb-Rice
+// coding parameter p and false positive rate 1/m from the passed serialized
+// filter as returned by Bytes.
+func NewGCSFilterFromBytes(p uint8, m uint64, serialized []byte) (*GCSFilter, error) {
+	if p > maxGCSFilterP {
+		str := fmt.Sprintf("filter parameter P is too large [p %v, "+
+			"max %v]", p, maxGCSFilterP)
+		return nil, messageError("NewGCSFilterFromBytes", str)
+	}
+
+	r := bytes.NewReader(serialized)
+	n, err := readVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(^uint32(0)) {
+		str := fmt.Sprintf("too many items for filter [count %v, "+
+			"max %v]", n, ^uint32(0))
+		return nil, messageError("NewGCSFilterFromBytes", str)
+	}
+
+	data := make([]byte, r.Len())
+	copy(data, serialized[len(serialized)-r.Len():])
+	return &GCSFilter{
+		n:       uint32(n),
+		p:       p,
+		modulus: n * m,
+		data:    data,
+	}, nil
+}
+
+// gcsHashToRange hashes the passed item with SipHash-2-4 using the passed key
+// and maps the result uniformly into the range [0, modulus) by taking the
+// upper 64 bits of the 128-bit product of the hash and the modulus.
+func gcsHashToRange(key [GCSKeySize]byte, item []byte, modulus uint64) uint64 {
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	hi, _ := bits.Mul64(siphash.Hash(k0, k1, item), modulus)
+	return hi
+}
+
+// uint64Slice implements sort.Interface to allow a slice of uint64 values to
+// be sorted.
+type uint64Slice []uint64
+
+// Len returns the number of values in the slice.  It is part of the
+// sort.Interface implementation.
+func (s uint64Slice) Len() int { return len(s) }
+
+// Swap swaps the values at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s uint64Slice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// Less returns whether the value with index i should sort before the value
+// with index j.  It is part of the sort.Interface implementation.
+func (s uint64Slice) Less(i, j int) bool { return s[i] < s[j] }
+
+// bitWriter writes a stream of bits most significant bit first.
+type bitWriter struct {
+	data  []byte
+	nbits uint
+}
+
+// writeBit appends a single bit to the stream.
+func (w *bitWriter) writeBit(bit bool) {
+	if w.nbits%8 == 0 {
+		w.data = append(w.data, 0)
+	}
+	if bit {
+		w.data[len(w.data)-1] |= 1 << (7 - w.nbits%8)
+	}
+	w.nbits++
+}
+
+// writeBits appends the low count bits of value to the stream, most
+// significant bit first.
+func (w *bitWriter) writeBits(value uint64, count uint8) {
+	for i := int(count) - 1; i >= 0; i-- {
+		w.writeBit(value&(1<<uint(i)) != 0)
+	}
+}
+
+// writeGolombRice appends value to the stream encoded with Golomb-Rice coding
+// using parameter p.  The quotient is written in unary followed by the p-bit
+// remainder.
+func (w *bitWriter) writeGolombRice(value uint64, p uint8) {
+	for q := value >> p; q > 0; q-- {
+		w.writeBit(true)
+	}
+	w.writeBit(false)
+	w.writeBits(value, p)
+}
+
+// bytes returns the written stream padded with zero bits to a byte boundary.
+func (w *bitWriter) bytes() []byte {
+	return w.data
+}
+
+// bitReader reads a stream of bits most significant bit first.
+type bitReader struct {
+	data []byte
+	pos  uint
+}
+
+// newBitReader returns a bitReader for the passed stream.
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: data}
+}
+
+// readBit reads a single bit from the stream.  io.EOF is returned when the
+// stream is exhausted.
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos/8 >= uint(len(r.data)) {
+		return false, io.EOF
+	}
+	bit := r.data[r.pos/8]&(1<<(7-r.pos%8)) != 0
+	r.pos++
+	return bit, nil
+}
+
+// readBits reads count bits from the stream and returns them as the low bits
+// of the result.
+func (r *bitReader) readBits(count uint8) (uint64, error) {
+	var value uint64
+	for i := uint8(0); i < count; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+	return value, nil
+}
+
+// readGolombRice reads a value encoded with Golomb-Rice coding using parameter
+// p from the stream.
+func (r *bitReader) readGolombRice(p uint8) (uint64, error) {
+	var quotient uint64
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		quotient++
+	}
+
+	remainder, err := r.readBits(p)
+	if err != nil {
+		return 0, err
+	}
+	return quotient<<p | remainder, nil
+}
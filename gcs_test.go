@@ -0,0 +1,287 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// gcsTestKey is the SipHash key used to build the test filters.
+var gcsTestKey = [rddwire.GCSKeySize]byte{
+	0x4c, 0xb1, 0xab, 0x12, 0x57, 0x62, 0x1e, 0x41,
+	0x3b, 0x8b, 0x0e, 0x26, 0x64, 0x8d, 0x4a, 0x15,
+}
+
+// gcsTestContents is the set of items committed to by the test filters.
+var gcsTestContents = [][]byte{
+	[]byte("Alex"),
+	[]byte("Bob"),
+	[]byte("Charlie"),
+	[]byte("Dick"),
+	[]byte("Ed"),
+	[]byte("Frank"),
+	[]byte("George"),
+	[]byte("Harry"),
+	[]byte("Ilya"),
+	[]byte("John"),
+	[]byte("Kevin"),
+	[]byte("Larry"),
+	[]byte("Michael"),
+	[]byte("Nate"),
+	[]byte("Owen"),
+	[]byte("Paul"),
+	[]byte("Quentin"),
+}
+
+// gcsTestFilterBytes is the serialized filter for gcsTestContents built with
+// gcsTestKey and the basic filter parameters.  It was produced by this
+// implementation and only guards against regressions.  The encoding is checked
+// against the independent BIP0158 test vectors by TestGCSFilterBIP0158.
+var gcsTestFilterBytes = []byte{
+	0x11, // Varint for number of items
+	0x05, 0x6f, 0xf7, 0x9e, 0x6c, 0x29, 0x94, 0xba,
+	0x5d, 0x91, 0x40, 0x2f, 0x32, 0x7f, 0x80, 0x70,
+	0x97, 0xc5, 0xc5, 0x71, 0xf8, 0xd2, 0x12, 0x51,
+	0x1a, 0x82, 0x37, 0xf0, 0x05, 0x33, 0x13, 0x46,
+	0x10, 0x2b, 0x41, 0x96, 0x7f, 0x35, 0xef, 0x48,
+	0x84, 0x06, 0xc3, 0x8a, 0x88,
+}
+
+// TestGCSFilter tests the GCSFilter API.
+func TestGCSFilter(t *testing.T) {
+	filter, err := rddwire.NewGCSFilter(rddwire.BasicFilterP,
+		rddwire.BasicFilterM, gcsTestKey, gcsTestContents)
+	if err != nil {
+		t.Fatalf("NewGCSFilter: unexpected error %v", err)
+	}
+
+	// Ensure the filter parameters and serialization are the expected
+	// values.
+	if filter.N() != uint32(len(gcsTestContents)) {
+		t.Errorf("N: wrong number of items - got %v, want %v",
+			filter.N(), len(gcsTestContents))
+	}
+	if filter.P() != rddwire.BasicFilterP {
+		t.Errorf("P: wrong parameter - got %v, want %v", filter.P(),
+			rddwire.BasicFilterP)
+	}
+	if !bytes.Equal(filter.Bytes(), gcsTestFilterBytes) {
+		t.Errorf("Bytes: wrong serialized filter\n got: %s want: %s",
+			spew.Sdump(filter.Bytes()), spew.Sdump(gcsTestFilterBytes))
+	}
+
+	// Ensure a filter deserialized from the bytes is identical.
+	filter2, err := rddwire.NewGCSFilterFromBytes(rddwire.BasicFilterP,
+		rddwire.BasicFilterM, filter.Bytes())
+	if err != nil {
+		t.Fatalf("NewGCSFilterFromBytes: unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(filter, filter2) {
+		t.Errorf("NewGCSFilterFromBytes: mismatched filter\n got: %s "+
+			"want: %s", spew.Sdump(filter2), spew.Sdump(filter))
+	}
+
+	// Ensure every item matches.
+	for _, item := range gcsTestContents {
+		if !filter.Match(gcsTestKey, item) {
+			t.Errorf("Match: %q did not match", item)
+		}
+	}
+
+	tests := []struct {
+		items [][]byte // Items to match
+		match bool     // Expected match result
+	}{
+		{[][]byte{[]byte("Nate")}, true},
+		{[][]byte{[]byte("Quentin2")}, false},
+		{[][]byte{[]byte("Zoe"), []byte("Yolanda")}, false},
+		{[][]byte{[]byte("Zoe"), []byte("Alex")}, true},
+		{[][]byte{[]byte("Xavier"), []byte("Zoe"), []byte("Quentin")}, true},
+		{[][]byte{}, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		if len(test.items) == 1 {
+			match := filter.Match(gcsTestKey, test.items[0])
+			if match != test.match {
+				t.Errorf("Match #%d wrong result - got %v, "+
+					"want %v", i, match, test.match)
+				continue
+			}
+		}
+
+		match := filter.MatchAny(gcsTestKey, test.items)
+		if match != test.match {
+			t.Errorf("MatchAny #%d wrong result - got %v, want %v",
+				i, match, test.match)
+			continue
+		}
+	}
+
+	// Ensure a different key does not match.
+	var otherKey [rddwire.GCSKeySize]byte
+	if filter.MatchAny(otherKey, gcsTestContents[:1]) {
+		t.Errorf("MatchAny: unexpected match with different key")
+	}
+
+	// Ensure a truncated filter does not match items beyond the truncated
+	// portion.
+	truncated, err := rddwire.NewGCSFilterFromBytes(rddwire.BasicFilterP,
+		rddwire.BasicFilterM, gcsTestFilterBytes[:2])
+	if err != nil {
+		t.Fatalf("NewGCSFilterFromBytes: unexpected error %v", err)
+	}
+	if truncated.MatchAny(gcsTestKey, gcsTestContents) {
+		t.Errorf("MatchAny: unexpected match with truncated filter")
+	}
+}
+
+// TestGCSFilterBIP0158 ensures filters built with the basic filter parameters
+// match the filters of the BIP0158 test vectors.  Each of the blocks commits to
+// the single output script of its coinbase and the filter is keyed by the block
+// hash.
+func TestGCSFilterBIP0158(t *testing.T) {
+	tests := []struct {
+		name      string // Short description of test
+		blockHash string // Hash of the block the filter is for
+		script    string // Committed output script
+		filter    []byte // Expected serialized filter
+	}{
+		{
+			name:      "test network genesis block",
+			blockHash: "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943",
+			script: "4104678afdb0fe5548271967f1a67130b7105cd6a828e03909" +
+				"a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c" +
+				"384df7ba0b8d578a4c702b6bf11d5fac",
+			filter: []byte{0x01, 0x9d, 0xfc, 0xa8},
+		},
+		{
+			name:      "test network block 2",
+			blockHash: "000000006c02c8ea6e4ff69651f7fcde348fb9d557a06e6957b65552002a7820",
+			script:    "21038a7f6ef1c8ca0c588aa53fa860128077c9e6c11e6830f4d7ee4e763a56b7718fac",
+			filter:    []byte{0x01, 0x74, 0xa1, 0x70},
+		},
+		{
+			name:      "test network block 3",
+			blockHash: "000000008b896e272758da5297bcd98fdc6d97c9b765ecec401e286dc1fdbe10",
+			script:    "2103f6d9ff4c12959445ca5549c811683bf9c88e637b222dd2e0311154c4c85cf423ac",
+			filter:    []byte{0x01, 0x6c, 0xf7, 0xa0},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		blockHash, err := rddwire.NewShaHashFromStr(test.blockHash)
+		if err != nil {
+			t.Errorf("NewShaHashFromStr #%d (%s) unexpected error %v",
+				i, test.name, err)
+			continue
+		}
+		script, err := hex.DecodeString(test.script)
+		if err != nil {
+			t.Errorf("DecodeString #%d (%s) unexpected error %v", i,
+				test.name, err)
+			continue
+		}
+
+		key := rddwire.BasicFilterKey(blockHash)
+		filter, err := rddwire.NewGCSFilter(rddwire.BasicFilterP,
+			rddwire.BasicFilterM, key, [][]byte{script})
+		if err != nil {
+			t.Errorf("NewGCSFilter #%d (%s) unexpected error %v", i,
+				test.name, err)
+			continue
+		}
+		if !bytes.Equal(filter.Bytes(), test.filter) {
+			t.Errorf("NewGCSFilter #%d (%s)\n got: %s want: %s", i,
+				test.name, spew.Sdump(filter.Bytes()),
+				spew.Sdump(test.filter))
+			continue
+		}
+
+		// Ensure a filter deserialized from the vector matches the
+		// committed script.
+		filter, err = rddwire.NewGCSFilterFromBytes(rddwire.BasicFilterP,
+			rddwire.BasicFilterM, test.filter)
+		if err != nil {
+			t.Errorf("NewGCSFilterFromBytes #%d (%s) unexpected "+
+				"error %v", i, test.name, err)
+			continue
+		}
+		if !filter.Match(key, script) {
+			t.Errorf("Match #%d (%s) script did not match", i,
+				test.name)
+			continue
+		}
+	}
+}
+
+// TestGCSFilterEmpty tests the GCSFilter API for a filter with no items.
+func TestGCSFilterEmpty(t *testing.T) {
+	filter, err := rddwire.NewGCSFilter(rddwire.BasicFilterP,
+		rddwire.BasicFilterM, gcsTestKey, nil)
+	if err != nil {
+		t.Fatalf("NewGCSFilter: unexpected error %v", err)
+	}
+	if filter.N() != 0 {
+		t.Errorf("N: wrong number of items - got %v, want 0", filter.N())
+	}
+	if !bytes.Equal(filter.Bytes(), []byte{0x00}) {
+		t.Errorf("Bytes: wrong serialized filter - got %x, want 00",
+			filter.Bytes())
+	}
+	if filter.Match(gcsTestKey, gcsTestContents[0]) {
+		t.Errorf("Match: unexpected match on empty filter")
+	}
+	if filter.MatchAny(gcsTestKey, gcsTestContents) {
+		t.Errorf("MatchAny: unexpected match on empty filter")
+	}
+}
+
+// TestGCSFilterErrors performs negative tests against the GCSFilter
+// constructors to confirm error paths work correctly.
+func TestGCSFilterErrors(t *testing.T) {
+	rddwireErr := &rddwire.MessageError{}
+
+	// Force error with P too large.
+	_, err := rddwire.NewGCSFilter(33, rddwire.BasicFilterM, gcsTestKey,
+		gcsTestContents)
+	if reflect.TypeOf(err) != reflect.TypeOf(rddwireErr) {
+		t.Errorf("NewGCSFilter: wrong error got: %v, want: %v", err,
+			rddwireErr)
+	}
+	_, err = rddwire.NewGCSFilterFromBytes(33, rddwire.BasicFilterM,
+		gcsTestFilterBytes)
+	if reflect.TypeOf(err) != reflect.TypeOf(rddwireErr) {
+		t.Errorf("NewGCSFilterFromBytes: wrong error got: %v, want: %v",
+			err, rddwireErr)
+	}
+
+	// Force error with missing number of items.
+	_, err = rddwire.NewGCSFilterFromBytes(rddwire.BasicFilterP,
+		rddwire.BasicFilterM, nil)
+	if err != io.EOF {
+		t.Errorf("NewGCSFilterFromBytes: wrong error got: %v, want: %v",
+			err, io.EOF)
+	}
+
+	// Force error with more items than fit in a uint32.
+	_, err = rddwire.NewGCSFilterFromBytes(rddwire.BasicFilterP,
+		rddwire.BasicFilterM, []byte{
+			0xff, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		})
+	if reflect.TypeOf(err) != reflect.TypeOf(rddwireErr) {
+		t.Errorf("NewGCSFilterFromBytes: wrong error got: %v, want: %v",
+			err, rddwireErr)
+	}
+}
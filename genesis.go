@@ -0,0 +1,133 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"time"
+)
+
+// genesisCoinbaseTx is the coinbase transaction for the genesis blocks of all
+// Reddcoin networks.
+var genesisCoinbaseTx = MsgTx{
+	Version: 1,
+	TxIn: []*TxIn{
+		{
+			PreviousOutPoint: OutPoint{
+				Hash:  ShaHash{},
+				Index: 0xffffffff,
+			},
+			SignatureScript: []byte{
+				0x04, 0xff, 0xff, 0x00, 0x1d, 0x01, 0x04, 0x28, /* |.......(| */
+				0x4a, 0x61, 0x6e, 0x75, 0x61, 0x72, 0x79, 0x20, /* |January | */
+				0x32, 0x31, 0x73, 0x74, 0x20, 0x32, 0x30, 0x31, /* |21st 201| */
+				0x34, 0x20, 0x77, 0x61, 0x73, 0x20, 0x73, 0x75, /* |4 was su| */
+				0x63, 0x68, 0x20, 0x61, 0x20, 0x6e, 0x69, 0x63, /* |ch a nic| */
+				0x65, 0x20, 0x64, 0x61, 0x79, 0x2e, 0x2e, 0x2e, /* |e day...| */
+			},
+			Sequence: 0xffffffff,
+		},
+	},
+	TxOut: []*TxOut{
+		{
+			Value: 10000 * SatoshiPerReddcoin,
+			PkScript: []byte{
+				0x41, 0x04, 0x01, 0x84, 0x71, 0x0f, 0xa6, 0x89, /* |A...q...| */
+				0xad, 0x50, 0x23, 0x69, 0x0c, 0x80, 0xf3, 0xa4, /* |.P#i....| */
+				0x9c, 0x8f, 0x13, 0xf8, 0xd4, 0x5b, 0x8c, 0x85, /* |.....[..| */
+				0x7f, 0xbc, 0xbc, 0x8b, 0xc4, 0xa8, 0xe4, 0xd3, /* |........| */
+				0xeb, 0x4b, 0x10, 0xf4, 0xd4, 0x60, 0x4f, 0xa0, /* |.K...`O.| */
+				0x8d, 0xce, 0x60, 0x1a, 0xaf, 0x0f, 0x47, 0x02, /* |..`...G.| */
+				0x16, 0xfe, 0x1b, 0x51, 0x85, 0x0b, 0x4a, 0xcf, /* |...Q..J.| */
+				0x21, 0xb1, 0x79, 0xc4, 0x50, 0x70, 0xac, 0x7b, /* |!.y.Pp.{| */
+				0x03, 0xa9, 0xac, /* |...| */
+			},
+		},
+	},
+	LockTime: 0,
+
+	// Transactions of the proof-of-work era have no timestamp, which
+	// decodes as the unix epoch.
+	Timestamp: time.Unix(0, 0),
+}
+
+// genesisMerkleRoot is the hash of the first transaction in the genesis block
+// for all Reddcoin networks.
+var genesisMerkleRoot = ShaHash([HashSize]byte{ // Make go vet happy.
+	0xff, 0x79, 0xaf, 0x16, 0xa9, 0xff, 0xeb, 0x1b,
+	0x82, 0x6d, 0xe1, 0xea, 0x7f, 0x24, 0x53, 0x9a,
+	0x2f, 0xe3, 0x70, 0x2f, 0xe9, 0x87, 0x91, 0x2b,
+	0x09, 0x07, 0x2b, 0xc4, 0x1d, 0xbc, 0x02, 0xb5,
+})
+
+// mainNetGenesisHash is the hash of the first block in the block chain for the
+// main network (genesis block).
+var mainNetGenesisHash = ShaHash([HashSize]byte{ // Make go vet happy.
+	0xcc, 0xde, 0xc1, 0x74, 0xeb, 0xd4, 0xfa, 0x10,
+	0x31, 0x4b, 0x3b, 0x9e, 0xf9, 0xcb, 0x8a, 0xdc,
+	0xf9, 0xaa, 0x87, 0xe5, 0x7e, 0xc6, 0xad, 0x0d,
+	0x0e, 0x3c, 0x3c, 0x5a, 0xd9, 0xe0, 0x68, 0xb8,
+})
+
+// mainNetGenesisBlock defines the genesis block of the block chain which serves
+// as the public transaction ledger for the main network.
+var mainNetGenesisBlock = MsgBlock{
+	Header: BlockHeader{
+		Version:    1,
+		PrevBlock:  ShaHash{},                // 0000000000000000000000000000000000000000000000000000000000000000
+		MerkleRoot: genesisMerkleRoot,        // b502bc1dc42b07092b9187e92f70e32f9a53247feae16d821bebffa916af79ff
+		Timestamp:  time.Unix(1390280400, 0), // 2014-01-21 05:00:00 +0000 UTC
+		Bits:       0x1e0ffff0,               // 504365040 [00000ffff0000000000000000000000000000000000000000000000000000000]
+		Nonce:      222583475,
+	},
+	Transactions: []*MsgTx{&genesisCoinbaseTx},
+}
+
+// testNetGenesisHash is the hash of the first block in the block chain for the
+// regression test network (genesis block).
+var testNetGenesisHash = ShaHash([HashSize]byte{ // Make go vet happy.
+	0x0f, 0x94, 0x93, 0x5f, 0x62, 0xa4, 0x5b, 0x8b,
+	0x98, 0x42, 0x15, 0x8e, 0x2b, 0xbf, 0x01, 0x26,
+	0x9f, 0xab, 0xf3, 0xc2, 0x89, 0x1e, 0x33, 0x45,
+	0xb3, 0x0f, 0xb0, 0x30, 0xbe, 0x82, 0x8a, 0xd8,
+})
+
+// testNetGenesisBlock defines the genesis block of the block chain which serves
+// as the public transaction ledger for the regression test network.  It uses
+// the coinbase of the main network with the minimum difficulty of the network.
+var testNetGenesisBlock = MsgBlock{
+	Header: BlockHeader{
+		Version:    1,
+		PrevBlock:  ShaHash{},                // 0000000000000000000000000000000000000000000000000000000000000000
+		MerkleRoot: genesisMerkleRoot,        // b502bc1dc42b07092b9187e92f70e32f9a53247feae16d821bebffa916af79ff
+		Timestamp:  time.Unix(1390280400, 0), // 2014-01-21 05:00:00 +0000 UTC
+		Bits:       0x207fffff,               // 545259519 [7fffff0000000000000000000000000000000000000000000000000000000000]
+		Nonce:      3,
+	},
+	Transactions: []*MsgTx{&genesisCoinbaseTx},
+}
+
+// simNetGenesisHash is the hash of the first block in the block chain for the
+// simulation test network.
+var simNetGenesisHash = ShaHash([HashSize]byte{ // Make go vet happy.
+	0x4b, 0x35, 0x88, 0x09, 0x97, 0xdf, 0x73, 0x84,
+	0x21, 0xc4, 0x01, 0x49, 0x9c, 0x17, 0xa8, 0x65,
+	0xee, 0x50, 0xa3, 0x90, 0x24, 0x9c, 0xac, 0xff,
+	0x38, 0x91, 0xd2, 0x41, 0x5e, 0xcc, 0xc5, 0xfd,
+})
+
+// simNetGenesisBlock defines the genesis block of the block chain which serves
+// as the public transaction ledger for the simulation test network.  It uses
+// the coinbase of the main network with the minimum difficulty of the network.
+var simNetGenesisBlock = MsgBlock{
+	Header: BlockHeader{
+		Version:    1,
+		PrevBlock:  ShaHash{},                // 0000000000000000000000000000000000000000000000000000000000000000
+		MerkleRoot: genesisMerkleRoot,        // b502bc1dc42b07092b9187e92f70e32f9a53247feae16d821bebffa916af79ff
+		Timestamp:  time.Unix(1401292357, 0), // 2014-05-28 15:52:37 +0000 UTC
+		Bits:       0x207fffff,               // 545259519 [7fffff0000000000000000000000000000000000000000000000000000000000]
+		Nonce:      4,
+	},
+	Transactions: []*MsgTx{&genesisCoinbaseTx},
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGenesisBlock tests the genesis blocks of the default networks for
+// validity by checking their encoding and hashes.
+func TestGenesisBlock(t *testing.T) {
+	// Encode the main network genesis block to raw bytes.
+	var buf bytes.Buffer
+	err := rddwire.MainNetParams.GenesisBlock.Serialize(&buf)
+	if err != nil {
+		t.Fatalf("TestGenesisBlock: %v", err)
+	}
+
+	// Ensure the encoded block matches the expected bytes.
+	if !bytes.Equal(buf.Bytes(), mainNetGenesisBlockBytes) {
+		t.Fatalf("TestGenesisBlock: Genesis block does not appear valid - "+
+			"got %v, want %v", spew.Sdump(buf.Bytes()),
+			spew.Sdump(mainNetGenesisBlockBytes))
+	}
+
+	// Ensure the decoded block is the genesis block.
+	var block rddwire.MsgBlock
+	err = block.Deserialize(bytes.NewReader(mainNetGenesisBlockBytes))
+	if err != nil {
+		t.Fatalf("TestGenesisBlock: %v", err)
+	}
+	if !reflect.DeepEqual(&block, rddwire.MainNetParams.GenesisBlock) {
+		t.Errorf("TestGenesisBlock: decoded block mismatch - got %v, "+
+			"want %v", spew.Sdump(&block),
+			spew.Sdump(rddwire.MainNetParams.GenesisBlock))
+	}
+
+	tests := []*rddwire.Params{
+		&rddwire.MainNetParams,
+		&rddwire.TestNetParams,
+		&rddwire.SimNetParams,
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, params := range tests {
+		// Check hash of the block against expected hash.
+		hash, err := params.GenesisBlock.BlockSha()
+		if err != nil {
+			t.Errorf("BlockSha %s: %v", params.Name, err)
+			continue
+		}
+		if !params.GenesisHash.IsEqual(&hash) {
+			t.Errorf("TestGenesisBlock %s: Genesis block hash does "+
+				"not appear valid - got %v, want %v", params.Name,
+				hash, params.GenesisHash)
+			continue
+		}
+
+		// The genesis block must have a valid merkle root and pass the
+		// sanity checks.
+		if err := rddwire.CheckBlockSanity(params.GenesisBlock); err != nil {
+			t.Errorf("CheckBlockSanity %s: %v", params.Name, err)
+			continue
+		}
+	}
+}
+
+// mainNetGenesisBlockBytes are the serialized bytes of the genesis block of the
+// main network.
+var mainNetGenesisBlockBytes = []byte{
+	0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, /* |........| */
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, /* |........| */
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, /* |........| */
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, /* |........| */
+	0x00, 0x00, 0x00, 0x00, 0xff, 0x79, 0xaf, 0x16, /* |.....y..| */
+	0xa9, 0xff, 0xeb, 0x1b, 0x82, 0x6d, 0xe1, 0xea, /* |.....m..| */
+	0x7f, 0x24, 0x53, 0x9a, 0x2f, 0xe3, 0x70, 0x2f, /* |.$S./.p/| */
+	0xe9, 0x87, 0x91, 0x2b, 0x09, 0x07, 0x2b, 0xc4, /* |...+..+.| */
+	0x1d, 0xbc, 0x02, 0xb5, 0xd0, 0xfe, 0xdd, 0x52, /* |.......R| */
+	0xf0, 0xff, 0x0f, 0x1e, 0xb3, 0x5a, 0x44, 0x0d, /* |.....ZD.| */
+	0x01, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, /* |........| */
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, /* |........| */
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, /* |........| */
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, /* |........| */
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, /* |........| */
+	0xff, 0xff, 0x30, 0x04, 0xff, 0xff, 0x00, 0x1d, /* |..0.....| */
+	0x01, 0x04, 0x28, 0x4a, 0x61, 0x6e, 0x75, 0x61, /* |..(Janua| */
+	0x72, 0x79, 0x20, 0x32, 0x31, 0x73, 0x74, 0x20, /* |ry 21st | */
+	0x32, 0x30, 0x31, 0x34, 0x20, 0x77, 0x61, 0x73, /* |2014 was| */
+	0x20, 0x73, 0x75, 0x63, 0x68, 0x20, 0x61, 0x20, /* | such a | */
+	0x6e, 0x69, 0x63, 0x65, 0x20, 0x64, 0x61, 0x79, /* |nice day| */
+	0x2e, 0x2e, 0x2e, 0xff, 0xff, 0xff, 0xff, 0x01, /* |........| */
+	0x00, 0x10, 0xa5, 0xd4, 0xe8, 0x00, 0x00, 0x00, /* |........| */
+	0x43, 0x41, 0x04, 0x01, 0x84, 0x71, 0x0f, 0xa6, /* |CA...q..| */
+	0x89, 0xad, 0x50, 0x23, 0x69, 0x0c, 0x80, 0xf3, /* |..P#i...| */
+	0xa4, 0x9c, 0x8f, 0x13, 0xf8, 0xd4, 0x5b, 0x8c, /* |......[.| */
+	0x85, 0x7f, 0xbc, 0xbc, 0x8b, 0xc4, 0xa8, 0xe4, /* |........| */
+	0xd3, 0xeb, 0x4b, 0x10, 0xf4, 0xd4, 0x60, 0x4f, /* |..K...`O| */
+	0xa0, 0x8d, 0xce, 0x60, 0x1a, 0xaf, 0x0f, 0x47, /* |...`...G| */
+	0x02, 0x16, 0xfe, 0x1b, 0x51, 0x85, 0x0b, 0x4a, /* |....Q..J| */
+	0xcf, 0x21, 0xb1, 0x79, 0xc4, 0x50, 0x70, 0xac, /* |.!.y.Pp.| */
+	0x7b, 0x03, 0xa9, 0xac, 0x00, 0x00, 0x00, 0x00, /* |{.......| */
+}
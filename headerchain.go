@@ -0,0 +1,493 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+)
+
+// retargetAdjustmentFactor is the largest factor by which the target difficulty
+// of a block may be harder than the target difficulty of its previous block.
+// The targets of a Reddcoin chain change gradually, so this bounds the work
+// claimed by a header without reproducing the retarget rules.
+const retargetAdjustmentFactor = 4
+
+// MaxFutureBlockTime is the maximum amount of time the timestamp of a block may
+// be ahead of the network adjusted time (see MedianTimeSource.AdjustedTime).
+const MaxFutureBlockTime = 2 * time.Hour
+
+// headerNode houses a block header accepted by a HeaderChain along with its
+// position in the block tree.
+type headerNode struct {
+	hash    ShaHash
+	header  BlockHeader
+	parent  *headerNode
+	height  int32
+	workSum *big.Int
+}
+
+// HeaderChain follows the best Reddcoin block chain using block headers only,
+// which is what SPV clients need.  Headers are linked by PrevBlock into a tree
+// rooted at the genesis block of the network parameters of the chain, and the
+// best chain is the one with the most cumulative work.  When another branch
+// ends up with more cumulative work than the best chain, the chain reorganizes
+// to it.
+//
+// The proof of stake of a PoSV block can't be verified from its header, so the
+// trust of its stake target (see CalcBlockTrust) can't be credited.  Instead,
+// every PoSV header adds the work of the proof of work limit of the network,
+// which is the least work a block can have.  The cumulative work is therefore
+// the sum of the work of the PoW blocks and the number of PoSV blocks, scaled
+// by that least work, so the longest branch wins among branches which fork
+// after the PoSV height.  Since such headers can be made without proving any
+// work or stake, forks before the latest checkpoint reached by the best chain
+// are rejected, and the headers of the PoSV era should only be taken from
+// trusted peers.
+//
+// A HeaderChain created with OpenHeaderChain appends every header it accepts to
+// a flat file, so it can be restored without downloading the headers again.
+//
+// A HeaderChain is not safe for concurrent access.
+type HeaderChain struct {
+	params     *Params
+	timeSource MedianTimeSource
+	nodes      map[ShaHash]*headerNode
+	bestChain  []*headerNode
+	file       *os.File
+}
+
+// Ensure the HeaderChain type implements the HeaderLookup and MainChainLookup
+// interfaces.
+var (
+	_ HeaderLookup    = (*HeaderChain)(nil)
+	_ MainChainLookup = (*HeaderChain)(nil)
+)
+
+// CalcBlockTrust returns the amount the passed block header adds to the
+// cumulative work of a chain.  For PoW blocks, this is the work of the block
+// (see CalcWork).  Reddcoin computes the trust of PoSV blocks the same way from
+// the stake target in their Bits, so a chain with harder stake targets is
+// preferred just like a chain with harder PoW targets.
+//
+// The Bits of a PoSV header are only backed by the stake of its block, so the
+// trust must only be credited once the stake kernel of the block has met the
+// stake target (see StakeKernel.Check).  HeaderChain can't check stake kernels
+// and credits PoSV headers with the least work of the network instead.
+func CalcBlockTrust(header *BlockHeader) *big.Int {
+	return CalcWork(header.Bits)
+}
+
+// BestTip returns the hash and height of the tip of the best chain.
+func (c *HeaderChain) BestTip() (ShaHash, int32) {
+	tip := c.bestChain[len(c.bestChain)-1]
+	return tip.hash, tip.height
+}
+
+// BestHeight returns the height of the tip of the best chain.
+func (c *HeaderChain) BestHeight() int32 {
+	return int32(len(c.bestChain)) - 1
+}
+
+// BestHeader returns the block header of the tip of the best chain.
+func (c *HeaderChain) BestHeader() *BlockHeader {
+	header := c.bestChain[len(c.bestChain)-1].header
+	return &header
+}
+
+// BestWork returns the cumulative work of the best chain.
+func (c *HeaderChain) BestWork() *big.Int {
+	return new(big.Int).Set(c.bestChain[len(c.bestChain)-1].workSum)
+}
+
+// PastMedianTime returns the median time of the last MedianTimeBlocks blocks of
+// the best chain (see CalcPastMedianTime).  The timestamp of the next block must
+// be after it.
+func (c *HeaderChain) PastMedianTime() time.Time {
+	return c.pastMedianTime(c.bestChain[len(c.bestChain)-1])
+}
+
+// pastMedianTime returns the median time of the last MedianTimeBlocks blocks
+// of the branch which ends with the passed node.
+func (c *HeaderChain) pastMedianTime(node *headerNode) time.Time {
+	headers := make([]*BlockHeader, MedianTimeBlocks)
+	i := MedianTimeBlocks
+	for ; i > 0 && node != nil; node = node.parent {
+		i--
+		headers[i] = &node.header
+	}
+	return CalcPastMedianTime(headers[i:])
+}
+
+// HaveHeader returns whether the block header with the passed hash has been
+// accepted by the chain, regardless of whether it is part of the best chain.
+func (c *HeaderChain) HaveHeader(hash *ShaHash) bool {
+	_, ok := c.nodes[*hash]
+	return ok
+}
+
+// HeaderByHash returns the block header with the passed hash along with its
+// height.  An error is returned if the header is not known.
+func (c *HeaderChain) HeaderByHash(hash *ShaHash) (*BlockHeader, int32, error) {
+	node, ok := c.nodes[*hash]
+	if !ok {
+		str := fmt.Sprintf("block header %v is not known", hash)
+		return nil, 0, messageError("HeaderChain.HeaderByHash", str)
+	}
+
+	header := node.header
+	return &header, node.height, nil
+}
+
+// HashByHeight returns the hash of the block at the passed height of the best
+// chain.  An error is returned if the height is out of range.
+func (c *HeaderChain) HashByHeight(height int32) (*ShaHash, error) {
+	if height < 0 || height >= int32(len(c.bestChain)) {
+		str := fmt.Sprintf("no block at height %d of the best chain "+
+			"[best height %d]", height, len(c.bestChain)-1)
+		return nil, messageError("HeaderChain.HashByHeight", str)
+	}
+
+	hash := c.bestChain[height].hash
+	return &hash, nil
+}
+
+// HeaderByHeight returns the block header at the passed height of the best
+// chain.  An error is returned if the height is out of range.
+func (c *HeaderChain) HeaderByHeight(height int32) (*BlockHeader, error) {
+	if height < 0 || height >= int32(len(c.bestChain)) {
+		str := fmt.Sprintf("no block at height %d of the best chain "+
+			"[best height %d]", height, len(c.bestChain)-1)
+		return nil, messageError("HeaderChain.HeaderByHeight", str)
+	}
+
+	header := c.bestChain[height].header
+	return &header, nil
+}
+
+// MainChainHeight returns the height of the block header with the passed hash
+// and whether it is part of the best chain.
+func (c *HeaderChain) MainChainHeight(hash *ShaHash) (int32, bool) {
+	node, ok := c.nodes[*hash]
+	if !ok || node.height >= int32(len(c.bestChain)) ||
+		c.bestChain[node.height] != node {
+
+		return 0, false
+	}
+	return node.height, true
+}
+
+// MainChainHasHeader returns whether the block header with the passed hash is
+// part of the best chain.
+func (c *HeaderChain) MainChainHasHeader(hash *ShaHash) bool {
+	_, ok := c.MainChainHeight(hash)
+	return ok
+}
+
+// BlockLocator returns a block locator for the tip of the best chain which can
+// be used in a getblocks (MsgGetBlocks) or getheaders (MsgGetHeaders) message.
+// See BlockLocator for details on the algorithm used.
+func (c *HeaderChain) BlockLocator() BlockLocator {
+	// Ignore the error since HashByHeight can't fail for the heights of
+	// the best chain.
+	locator, _ := NewBlockLocator(c)
+	return locator
+}
+
+// AddHeader adds the passed block header to the chain.  The header must
+// connect to a header which has already been accepted and must be a PoW header
+// below the PoSV height of the network and a PoSV header from it onwards.  Its
+// target difficulty must not exceed the proof of work limit of the network or
+// be more than retargetAdjustmentFactor times harder than the target difficulty
+// of the previous block, and the proof of work of PoW headers must meet it (see
+// CheckProofOfWork).  The header must match the checkpoint at its height, must
+// not fork from the best chain before the latest checkpoint it has reached, and
+// its timestamp must be after the past median time of the blocks before it (see
+// PastMedianTime) and no more than MaxFutureBlockTime after the adjusted time
+// of the time source of the chain.  Headers which have already been accepted
+// are ignored.  The best chain is reorganized when the header gives another
+// branch more cumulative work than the best chain.
+//
+// The header is appended to the flat file of a chain opened with
+// OpenHeaderChain before it is accepted, so an error is returned and the header
+// is not accepted if writing it fails.
+func (c *HeaderChain) AddHeader(header *BlockHeader) error {
+	node, err := c.checkHeader(header)
+	if node == nil || err != nil {
+		return err
+	}
+
+	if c.file != nil {
+		var buf bytes.Buffer
+		buf.Grow(blockHeaderLen)
+		err := header.Serialize(&buf)
+		if err != nil {
+			return err
+		}
+		_, err = c.file.Write(buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	c.connectNode(node)
+	return nil
+}
+
+// AddHeaders adds the block headers of the passed headers message (MsgHeaders)
+// to the chain in order.  See AddHeader for details.  Processing stops at the
+// first header which is not accepted.
+func (c *HeaderChain) AddHeaders(msg *MsgHeaders) error {
+	for _, header := range msg.Headers {
+		err := c.AddHeader(header)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the flat file of a chain opened with OpenHeaderChain.  It does
+// nothing for a chain created with NewHeaderChain.
+func (c *HeaderChain) Close() error {
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// checkHeader ensures the passed block header can be accepted by the chain and
+// returns the node which houses it.  A nil node is returned without an error
+// when the header has already been accepted.
+func (c *HeaderChain) checkHeader(header *BlockHeader) (*headerNode, error) {
+	hash, err := header.BlockSha()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := c.nodes[hash]; ok {
+		return nil, nil
+	}
+
+	parent, ok := c.nodes[header.PrevBlock]
+	if !ok {
+		str := fmt.Sprintf("previous block %v of block header %v is "+
+			"not known", header.PrevBlock, hash)
+		return nil, messageError("HeaderChain.AddHeader", str)
+	}
+	height := parent.height + 1
+
+	// PoSV headers pass the proof of work check without any proof, so the
+	// result is needed to avoid crediting them with the trust of their
+	// stake target below.
+	isPoSV, err := CheckProofOfWork(header, height, c.params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.checkCheckpoint(hash, height)
+	if err != nil {
+		return nil, err
+	}
+
+	// The target difficulty must not be much harder than the target
+	// difficulty of the previous block.
+	target := CompactToBig(header.Bits)
+	minTarget := CompactToBig(parent.header.Bits)
+	minTarget.Div(minTarget, big.NewInt(retargetAdjustmentFactor))
+	if target.Cmp(minTarget) < 0 {
+		str := fmt.Sprintf("block target difficulty of %064x is more "+
+			"than %d times harder than the target difficulty of "+
+			"%064x of the previous block", target,
+			retargetAdjustmentFactor, CompactToBig(parent.header.Bits))
+		return nil, messageError("HeaderChain.AddHeader", str)
+	}
+
+	medianTime := c.pastMedianTime(parent)
+	if !header.Timestamp.After(medianTime) {
+		str := fmt.Sprintf("timestamp %v of block header %v is not "+
+			"after the past median time %v", header.Timestamp, hash,
+			medianTime)
+		return nil, messageError("HeaderChain.AddHeader", str)
+	}
+
+	// The timestamp must not be too far in the future.
+	maxTimestamp := c.timeSource.AdjustedTime().Add(MaxFutureBlockTime)
+	if header.Timestamp.After(maxTimestamp) {
+		str := fmt.Sprintf("timestamp %v of block header %v is too far "+
+			"in the future [max %v]", header.Timestamp, hash,
+			maxTimestamp)
+		return nil, messageError("HeaderChain.AddHeader", str)
+	}
+
+	// The trust of the stake target of PoSV headers is not backed by
+	// anything the chain can check, so they only add the least work.
+	work := CalcBlockTrust(header)
+	if isPoSV {
+		work = CalcWork(c.params.PowLimitBits)
+	}
+	workSum := new(big.Int).Add(parent.workSum, work)
+	return &headerNode{
+		hash:    hash,
+		header:  *header,
+		parent:  parent,
+		height:  height,
+		workSum: workSum,
+	}, nil
+}
+
+// checkCheckpoint ensures the block with the passed hash at the passed height
+// matches the checkpoint at the height, if any, and does not fork from the best
+// chain before the latest checkpoint the best chain has reached.
+func (c *HeaderChain) checkCheckpoint(hash ShaHash, height int32) error {
+	bestHeight := c.BestHeight()
+	for i := len(c.params.Checkpoints) - 1; i >= 0; i-- {
+		checkpoint := &c.params.Checkpoints[i]
+		if checkpoint.Height == height && !checkpoint.Hash.IsEqual(&hash) {
+			str := fmt.Sprintf("block header %v at height %d does "+
+				"not match checkpoint %v", hash, height,
+				checkpoint.Hash)
+			return messageError("HeaderChain.AddHeader", str)
+		}
+		if checkpoint.Height <= bestHeight && height <= checkpoint.Height {
+			str := fmt.Sprintf("block header %v at height %d forks "+
+				"from the best chain before the checkpoint at "+
+				"height %d", hash, height, checkpoint.Height)
+			return messageError("HeaderChain.AddHeader", str)
+		}
+	}
+	return nil
+}
+
+// connectNode adds the passed node to the block tree and makes the branch it
+// ends the best chain when it has more cumulative work than the best chain.
+// Ties are resolved in favor of the branch which was seen first.
+func (c *HeaderChain) connectNode(node *headerNode) {
+	c.nodes[node.hash] = node
+
+	tip := c.bestChain[len(c.bestChain)-1]
+	if node.workSum.Cmp(tip.workSum) <= 0 {
+		return
+	}
+
+	// Extending the best chain only requires appending the node.
+	// Otherwise, replace the best chain from the fork point onwards with
+	// the branch which ends at the node.
+	if node.parent == tip {
+		c.bestChain = append(c.bestChain, node)
+		return
+	}
+	if int32(len(c.bestChain)) > node.height+1 {
+		c.bestChain = c.bestChain[:node.height+1]
+	} else {
+		c.bestChain = append(c.bestChain,
+			make([]*headerNode, node.height+1-int32(len(c.bestChain)))...)
+	}
+	for n := node; c.bestChain[n.height] != n; n = n.parent {
+		c.bestChain[n.height] = n
+	}
+}
+
+// NewHeaderChain returns a new header chain which starts with the genesis block
+// of the passed network parameters and accepts headers according to them.  The
+// passed time source provides the network adjusted time which limits how far in
+// the future the timestamps of headers may be, and is typically shared with the
+// rest of the application, which adds the time samples of its peers to it.  The
+// chain is only kept in memory.
+func NewHeaderChain(params *Params, timeSource MedianTimeSource) *HeaderChain {
+	// Ignore the error since BlockSha can't fail in the current
+	// implementation except due to run-time panics.
+	genesis := &params.GenesisBlock.Header
+	hash, _ := genesis.BlockSha()
+	node := &headerNode{
+		hash:    hash,
+		header:  *genesis,
+		workSum: CalcBlockTrust(genesis),
+	}
+	return &HeaderChain{
+		params:     params,
+		timeSource: timeSource,
+		nodes:      map[ShaHash]*headerNode{hash: node},
+		bestChain:  []*headerNode{node},
+	}
+}
+
+// OpenHeaderChain returns a header chain like NewHeaderChain which is backed by
+// the flat file at the passed path.  The file is created if it doesn't exist.
+// Otherwise, the block headers it holds are added to the chain in the order
+// they were accepted, which restores the chain as it was when the file was
+// last written.
+//
+// The file is append-only and holds the serialized block headers accepted by
+// the chain after the genesis block.  A partial header at the end of the file,
+// such as one left by a crash during a write, is discarded.  An error is
+// returned if the file holds a header which can't be accepted, such as when
+// the file belongs to a chain with a different genesis block.
+func OpenHeaderChain(path string, params *Params,
+	timeSource MedianTimeSource) (*HeaderChain, error) {
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := NewHeaderChain(params, timeSource)
+	offset, err := chain.loadHeaders(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Discard any partial header at the end of the file and position the
+	// file for appending new headers.
+	err = file.Truncate(offset)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	chain.file = file
+	return chain, nil
+}
+
+// loadHeaders adds the block headers held by the passed flat file to the chain
+// and returns the offset of the end of the last complete header.
+func (c *HeaderChain) loadHeaders(r io.Reader) (int64, error) {
+	var offset int64
+	buf := make([]byte, blockHeaderLen)
+	for {
+		_, err := io.ReadFull(r, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		var header BlockHeader
+		err = header.Deserialize(bytes.NewReader(buf))
+		if err != nil {
+			return 0, err
+		}
+		node, err := c.checkHeader(&header)
+		if err != nil {
+			return 0, err
+		}
+		if node != nil {
+			c.connectNode(node)
+		}
+		offset += blockHeaderLen
+	}
+}
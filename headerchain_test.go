@@ -0,0 +1,492 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+)
+
+// headerChainTestHeader returns a block header which builds on the passed
+// previous block.  PoW headers are solved for the passed difficulty bits, which
+// are expected to be easy, while PoSV headers are left unsolved.
+func headerChainTestHeader(prev *rddwire.ShaHash, version int32, bits uint32,
+	seq int64) *rddwire.BlockHeader {
+
+	header := rddwire.BlockHeader{
+		Version:   version,
+		PrevBlock: *prev,
+		Timestamp: time.Unix(1400000000+seq*60, 0),
+		Bits:      bits,
+	}
+	for version <= rddwire.PowBlockVersion {
+		_, err := rddwire.CheckProofOfWork(&header, 1,
+			&rddwire.SimNetParams)
+		if err == nil {
+			break
+		}
+		header.Nonce++
+	}
+	return &header
+}
+
+// headerChainTestParams returns the simulation test network parameters with
+// the passed genesis block header and PoSV height and without checkpoints.
+func headerChainTestParams(genesis *rddwire.BlockHeader,
+	posvHeight int32) *rddwire.Params {
+
+	genesisHash, _ := genesis.BlockSha()
+	params := rddwire.SimNetParams
+	params.GenesisBlock = &rddwire.MsgBlock{Header: *genesis}
+	params.GenesisHash = &genesisHash
+	params.PoSVHeight = posvHeight
+	params.Checkpoints = nil
+	return &params
+}
+
+// headerChainTestBranch returns count headers which build on the passed
+// previous block in order.
+func headerChainTestBranch(prev *rddwire.ShaHash, version int32, bits uint32,
+	seq int64, count int) []*rddwire.BlockHeader {
+
+	headers := make([]*rddwire.BlockHeader, 0, count)
+	for i := 0; i < count; i++ {
+		header := headerChainTestHeader(prev, version, bits, seq+int64(i))
+		headers = append(headers, header)
+		hash, _ := header.BlockSha()
+		prev = &hash
+	}
+	return headers
+}
+
+// TestHeaderChain tests following the best chain with a HeaderChain including
+// forks and reorganizations.
+func TestHeaderChain(t *testing.T) {
+	genesis := headerChainTestHeader(&rddwire.ShaHash{}, 1, 0x207fffff, 0)
+	genesisHash, _ := genesis.BlockSha()
+	chain := rddwire.NewHeaderChain(headerChainTestParams(genesis, 11),
+		rddwire.NewMedianTime())
+
+	// Main chain of 10 PoW blocks, each with a work of 2, followed by 10
+	// PoSV blocks, each with the least work of 2.
+	mainHeaders := headerChainTestBranch(&genesisHash, 1, 0x207fffff, 1, 10)
+	posvFrom, _ := mainHeaders[9].BlockSha()
+	mainHeaders = append(mainHeaders, headerChainTestBranch(&posvFrom,
+		rddwire.BlockVersion, 0x207fffff, 11, 10)...)
+	msg := rddwire.NewMsgHeaders()
+	for _, header := range mainHeaders {
+		msg.AddBlockHeader(header)
+	}
+	err := chain.AddHeaders(msg)
+	if err != nil {
+		t.Fatalf("AddHeaders: %v", err)
+	}
+	mainTip, _ := mainHeaders[19].BlockSha()
+
+	// A fork from height 15 with 5 PoSV blocks doesn't have more
+	// cumulative work, but a sixth block does.
+	forkFrom, _ := mainHeaders[14].BlockSha()
+	fork := headerChainTestBranch(&forkFrom, rddwire.BlockVersion,
+		0x207fffff, 100, 6)
+	forkTip, _ := fork[5].BlockSha()
+
+	// A PoSV block with a harder stake target only adds the least work
+	// since its stake can't be checked.
+	hardStake := headerChainTestHeader(&forkTip, rddwire.BlockVersion,
+		0x20200000, 106)
+	hardStakeTip, _ := hardStake.BlockSha()
+
+	// A fork from height 5 with two PoW blocks whose targets are each
+	// four times harder, and so have a work of 7 and 31, has more
+	// cumulative work.
+	heavyFrom, _ := mainHeaders[4].BlockSha()
+	heavy := headerChainTestHeader(&heavyFrom, 1, 0x20200000, 200)
+	heavyHash, _ := heavy.BlockSha()
+	heavier := headerChainTestHeader(&heavyHash, 1, 0x20080000, 201)
+	heavyTip, _ := heavier.BlockSha()
+
+	tests := []struct {
+		name    string                 // Test description
+		headers []*rddwire.BlockHeader // Headers to add
+		tip     rddwire.ShaHash        // Expected best tip
+		height  int32                  // Expected best height
+		work    int64                  // Expected best work
+	}{
+		{"main chain", nil, mainTip, 20, 42},
+		{"duplicate", mainHeaders[10:12], mainTip, 20, 42},
+		{"equal work fork", fork[:5], mainTip, 20, 42},
+		{"longer fork", fork[5:], forkTip, 21, 44},
+		{"harder stake target", []*rddwire.BlockHeader{hardStake},
+			hardStakeTip, 22, 46},
+		{"heavier fork", []*rddwire.BlockHeader{heavy, heavier}, heavyTip,
+			7, 50},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		for _, header := range test.headers {
+			err := chain.AddHeader(header)
+			if err != nil {
+				t.Fatalf("AddHeader %s: %v", test.name, err)
+			}
+		}
+
+		tip, height := chain.BestTip()
+		if tip != test.tip || height != test.height {
+			t.Errorf("BestTip %s: got %v (%d), want %v (%d)",
+				test.name, tip, height, test.tip, test.height)
+			continue
+		}
+		if work := chain.BestWork(); work.Int64() != test.work {
+			t.Errorf("BestWork %s: got %v, want %v", test.name,
+				work, test.work)
+			continue
+		}
+
+		// The best chain must link back to the genesis block.
+		for h := height; h > 0; h-- {
+			hash, err := chain.HashByHeight(h)
+			if err != nil {
+				t.Fatalf("HashByHeight %s: %v", test.name, err)
+			}
+			header, gotHeight, err := chain.HeaderByHash(hash)
+			if err != nil {
+				t.Fatalf("HeaderByHash %s: %v", test.name, err)
+			}
+			prev, _ := chain.HashByHeight(h - 1)
+			if gotHeight != h || header.PrevBlock != *prev {
+				t.Errorf("%s: best chain broken at height %d",
+					test.name, h)
+				break
+			}
+		}
+	}
+
+	// Headers of stale branches are still known but not in the main chain.
+	if !chain.HaveHeader(&mainTip) || chain.MainChainHasHeader(&mainTip) {
+		t.Errorf("stale main chain tip has wrong status")
+	}
+	if !chain.MainChainHasHeader(&heavyFrom) {
+		t.Errorf("fork point is not in the main chain")
+	}
+	if mtp := chain.PastMedianTime(); mtp.Unix() != 1400000000+4*60 {
+		t.Errorf("PastMedianTime: got %v, want %v", mtp,
+			time.Unix(1400000000+4*60, 0))
+	}
+	if _, err := chain.HashByHeight(8); err == nil {
+		t.Errorf("HashByHeight: unexpected block above best height")
+	}
+	if _, _, err := chain.HeaderByHash(&rddwire.ShaHash{}); err == nil {
+		t.Errorf("HeaderByHash: unexpected unknown header")
+	}
+}
+
+// TestHeaderChainErrors performs negative tests against adding headers to a
+// HeaderChain to confirm error paths work correctly.
+func TestHeaderChainErrors(t *testing.T) {
+	genesis := headerChainTestHeader(&rddwire.ShaHash{}, 1, 0x207fffff, 0)
+	genesisHash, _ := genesis.BlockSha()
+	powParams := headerChainTestParams(genesis, 100)
+	posvParams := headerChainTestParams(genesis, 1)
+	mainNetGenesisHash := *rddwire.MainNetParams.GenesisHash
+
+	// The adjusted time of the chains is a day after the genesis block.
+	now := time.Unix(1400000000, 0).Add(24 * time.Hour)
+	timeSource := rddwire.TstNewMedianTime(func() time.Time { return now })
+
+	// Header building on an unknown block.
+	orphan := headerChainTestHeader(&rddwire.ShaHash{0x01}, 1, 0x207fffff, 1)
+
+	// Header whose target is above the proof of work limit.
+	highTarget := headerChainTestHeader(&genesisHash, 1, 0x207fffff, 1)
+	highTarget.Bits = 0x217fffff
+
+	// Header whose hash does not meet its target.
+	badPow := headerChainTestHeader(&genesisHash, 1, 0x207fffff, 1)
+	for {
+		_, err := rddwire.CheckProofOfWork(badPow, 1, powParams)
+		if err != nil {
+			break
+		}
+		badPow.Nonce++
+	}
+
+	// Header whose target is more than four times harder than the target
+	// of its previous block.
+	hardTarget := headerChainTestHeader(&genesisHash, 1, 0x201fffff, 1)
+
+	// Header whose timestamp is not after the past median time.
+	early := headerChainTestHeader(&genesisHash, 1, 0x207fffff, 0)
+
+	// Headers whose timestamp is at and just after the latest allowed
+	// timestamp.
+	maxTime := headerChainTestHeader(&genesisHash, rddwire.BlockVersion,
+		0x207fffff, 1)
+	maxTime.Timestamp = now.Add(rddwire.MaxFutureBlockTime)
+	future := headerChainTestHeader(&genesisHash, rddwire.BlockVersion,
+		0x207fffff, 1)
+	future.Timestamp = now.Add(rddwire.MaxFutureBlockTime + time.Second)
+
+	// PoSV header below the PoSV height and PoW header from it onwards.
+	earlyPoSV := headerChainTestHeader(&genesisHash, rddwire.BlockVersion,
+		0x207fffff, 1)
+	latePow := headerChainTestHeader(&genesisHash, 1, 0x207fffff, 1)
+
+	// Unsolved PoSV header whose stake target claims an enormous trust on
+	// a network where PoSV starts right after the genesis block.
+	forgedStake := headerChainTestHeader(&genesisHash, rddwire.BlockVersion,
+		0x03000001, 1)
+
+	// Unsolved headers which claim an enormous work on top of the main
+	// network genesis block.
+	forgedMainNet := headerChainTestHeader(&mainNetGenesisHash,
+		rddwire.BlockVersion, 0x03000001, 1)
+	forgedMainNetPow := headerChainTestHeader(&mainNetGenesisHash,
+		rddwire.BlockVersion, 0x03000001, 1)
+	forgedMainNetPow.Version = 1
+
+	tests := []struct {
+		name   string               // Test description
+		params *rddwire.Params      // Network parameters of the chain
+		header *rddwire.BlockHeader // Header to add
+		err    error                // Expected error
+	}{
+		{"orphan", powParams, orphan, &rddwire.MessageError{}},
+		{"timestamp too early", powParams, early, &rddwire.MessageError{}},
+		{"timestamp too far in future", posvParams, future,
+			&rddwire.MessageError{}},
+		{"high target", powParams, highTarget, &rddwire.MessageError{}},
+		{"bad proof of work", powParams, badPow, &rddwire.MessageError{}},
+		{"target too hard", powParams, hardTarget, &rddwire.MessageError{}},
+		{"PoSV before PoSV height", powParams, earlyPoSV,
+			&rddwire.MessageError{}},
+		{"PoW after PoSV height", posvParams, latePow,
+			&rddwire.MessageError{}},
+		{"forged stake target", posvParams, forgedStake,
+			&rddwire.MessageError{}},
+		{"forged PoSV on main network", &rddwire.MainNetParams,
+			forgedMainNet, &rddwire.MessageError{}},
+		{"forged PoW on main network", &rddwire.MainNetParams,
+			forgedMainNetPow, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		chain := rddwire.NewHeaderChain(test.params, timeSource)
+		err := chain.AddHeader(test.header)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("AddHeader %s: wrong error - got %v, want %v",
+				test.name, err, test.err)
+			continue
+		}
+
+		tip, height := chain.BestTip()
+		if tip != *test.params.GenesisHash || height != 0 {
+			t.Errorf("AddHeader %s: rejected header changed the "+
+				"best tip to %v (%d)", test.name, tip, height)
+			continue
+		}
+	}
+
+	// A header with the latest allowed timestamp is accepted.
+	chain := rddwire.NewHeaderChain(posvParams, timeSource)
+	if err := chain.AddHeader(maxTime); err != nil {
+		t.Errorf("AddHeader: header at max timestamp %v", err)
+	}
+}
+
+// TestHeaderChainCheckpoints ensures a HeaderChain rejects headers which don't
+// match the checkpoints of its network or fork before a checkpoint the best
+// chain has reached.
+func TestHeaderChainCheckpoints(t *testing.T) {
+	genesis := headerChainTestHeader(&rddwire.ShaHash{}, 1, 0x207fffff, 0)
+	genesisHash, _ := genesis.BlockSha()
+	params := headerChainTestParams(genesis, 100)
+
+	mainHeaders := headerChainTestBranch(&genesisHash, 1, 0x207fffff, 1, 6)
+	checkpointHash, _ := mainHeaders[2].BlockSha()
+	params.Checkpoints = []rddwire.Checkpoint{
+		{Height: 3, Hash: &checkpointHash},
+		{Height: 10, Hash: &rddwire.ShaHash{0x01}},
+	}
+
+	// Fork which replaces the checkpoint at height 3.
+	fork := headerChainTestBranch(&genesisHash, 1, 0x207fffff, 100, 3)
+
+	// Fork from height 2 before the checkpoint once the checkpoint has
+	// been reached, and fork from height 3 after it.
+	forkFrom, _ := mainHeaders[1].BlockSha()
+	beforeCheckpoint := headerChainTestHeader(&forkFrom, 1, 0x207fffff, 200)
+	afterFrom, _ := mainHeaders[2].BlockSha()
+	afterCheckpoint := headerChainTestHeader(&afterFrom, 1, 0x207fffff, 300)
+
+	chain := rddwire.NewHeaderChain(params, rddwire.NewMedianTime())
+	for _, header := range fork[:2] {
+		if err := chain.AddHeader(header); err != nil {
+			t.Fatalf("AddHeader: %v", err)
+		}
+	}
+	err := chain.AddHeader(fork[2])
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("AddHeader: wrong error for header not matching "+
+			"checkpoint got: %v, want: %T", err, &rddwire.MessageError{})
+	}
+
+	for _, header := range mainHeaders {
+		if err := chain.AddHeader(header); err != nil {
+			t.Fatalf("AddHeader: %v", err)
+		}
+	}
+	err = chain.AddHeader(beforeCheckpoint)
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("AddHeader: wrong error for fork before checkpoint "+
+			"got: %v, want: %T", err, &rddwire.MessageError{})
+	}
+	if err := chain.AddHeader(afterCheckpoint); err != nil {
+		t.Errorf("AddHeader: fork after checkpoint %v", err)
+	}
+
+	mainTip, _ := mainHeaders[5].BlockSha()
+	if tip, height := chain.BestTip(); tip != mainTip || height != 6 {
+		t.Errorf("BestTip: got %v (%d), want %v (6)", tip, height,
+			mainTip)
+	}
+}
+
+// TestHeaderChainLocator tests the block locator of a HeaderChain.
+func TestHeaderChainLocator(t *testing.T) {
+	genesis := headerChainTestHeader(&rddwire.ShaHash{}, 1, 0x207fffff, 0)
+	genesisHash, _ := genesis.BlockSha()
+	chain := rddwire.NewHeaderChain(headerChainTestParams(genesis, 1),
+		rddwire.NewMedianTime())
+	headers := headerChainTestBranch(&genesisHash, rddwire.BlockVersion,
+		0x207fffff, 1, 100)
+	for _, header := range headers {
+		err := chain.AddHeader(header)
+		if err != nil {
+			t.Fatalf("AddHeader: %v", err)
+		}
+	}
+
+	heights := []int32{100, 99, 98, 97, 96, 95, 94, 93, 92, 91, 89, 85,
+		77, 61, 29, 0}
+	locator := chain.BlockLocator()
+	if len(locator) != len(heights) {
+		t.Fatalf("BlockLocator: got %d hashes, want %d", len(locator),
+			len(heights))
+	}
+	for i, height := range heights {
+		hash, _ := chain.HashByHeight(height)
+		if *locator[i] != *hash {
+			t.Errorf("BlockLocator #%d: got %v, want %v (height %d)",
+				i, locator[i], hash, height)
+		}
+	}
+}
+
+// TestHeaderChainFile tests restoring a HeaderChain from its flat file.
+func TestHeaderChainFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "headerchain")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "headers.dat")
+
+	genesis := headerChainTestHeader(&rddwire.ShaHash{}, 1, 0x207fffff, 0)
+	genesisHash, _ := genesis.BlockSha()
+	params := headerChainTestParams(genesis, 4)
+	chain, err := rddwire.OpenHeaderChain(path, params,
+		rddwire.NewMedianTime())
+	if err != nil {
+		t.Fatalf("OpenHeaderChain: %v", err)
+	}
+
+	// Add a main chain of PoW and PoSV blocks along with a fork which is
+	// later reorganized to.
+	mainHeaders := headerChainTestBranch(&genesisHash, 1, 0x207fffff, 1, 3)
+	posvFrom, _ := mainHeaders[2].BlockSha()
+	mainHeaders = append(mainHeaders, headerChainTestBranch(&posvFrom,
+		rddwire.BlockVersion, 0x207fffff, 4, 7)...)
+	forkFrom, _ := mainHeaders[2].BlockSha()
+	fork := headerChainTestBranch(&forkFrom, rddwire.BlockVersion,
+		0x207fffff, 100, 9)
+	for _, header := range append(mainHeaders, fork...) {
+		err := chain.AddHeader(header)
+		if err != nil {
+			t.Fatalf("AddHeader: %v", err)
+		}
+	}
+	wantTip, wantHeight := chain.BestTip()
+	wantWork := chain.BestWork()
+	chain.Close()
+
+	// Append a partial header as if a write was interrupted.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	file.Write([]byte{0x01, 0x00, 0x00, 0x00, 0xff})
+	file.Close()
+
+	// Reopen the chain and ensure it was restored and the partial header
+	// was discarded.
+	chain, err = rddwire.OpenHeaderChain(path, params,
+		rddwire.NewMedianTime())
+	if err != nil {
+		t.Fatalf("OpenHeaderChain: %v", err)
+	}
+	tip, height := chain.BestTip()
+	if tip != wantTip || height != wantHeight {
+		t.Errorf("BestTip: got %v (%d), want %v (%d)", tip, height,
+			wantTip, wantHeight)
+	}
+	if work := chain.BestWork(); work.Cmp(wantWork) != 0 {
+		t.Errorf("BestWork: got %v, want %v", work, wantWork)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size() != 19*80 {
+		t.Errorf("partial header not discarded - file size %d, want %d",
+			info.Size(), 19*80)
+	}
+
+	// Headers added after reopening are appended to the file.
+	tipHeader := headerChainTestHeader(&wantTip, rddwire.BlockVersion,
+		0x207fffff, 200)
+	err = chain.AddHeader(tipHeader)
+	if err != nil {
+		t.Fatalf("AddHeader: %v", err)
+	}
+	chain.Close()
+	chain, err = rddwire.OpenHeaderChain(path, params,
+		rddwire.NewMedianTime())
+	if err != nil {
+		t.Fatalf("OpenHeaderChain: %v", err)
+	}
+	if _, height := chain.BestTip(); height != wantHeight+1 {
+		t.Errorf("BestTip: got height %d, want %d", height, wantHeight+1)
+	}
+	chain.Close()
+
+	// A file which belongs to a chain with another genesis block is
+	// rejected.
+	otherGenesis := headerChainTestHeader(&rddwire.ShaHash{0x01}, 1,
+		0x207fffff, 0)
+	_, err = rddwire.OpenHeaderChain(path,
+		headerChainTestParams(otherGenesis, 4), rddwire.NewMedianTime())
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("OpenHeaderChain: wrong error got: %v, want: %T", err,
+			&rddwire.MessageError{})
+	}
+}
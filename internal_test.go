@@ -13,6 +13,7 @@ package rddwire
 
 import (
 	"io"
+	"time"
 )
 
 const (
@@ -33,6 +34,14 @@ const (
 	MaxCountSetSubVer = maxCountSetSubVer
 )
 
+// TstNewMedianTime makes the internal medianTime type available to the test
+// package with the passed function used as the local clock.
+func TstNewMedianTime(now func() time.Time) MedianTimeSource {
+	m := NewMedianTime().(*medianTime)
+	m.now = now
+	return m
+}
+
 // TstRandomUint64 makes the internal randomUint64 function available to the
 // test package.
 func TstRandomUint64(r io.Reader) (uint64, error) {
@@ -105,6 +114,18 @@ func TstMaxNetAddressPayload(pver uint32) uint32 {
 	return maxNetAddressPayload(pver)
 }
 
+// TstReadNetAddressV2 makes the internal readNetAddressV2 function available
+// to the test package.
+func TstReadNetAddressV2(r io.Reader, pver uint32, na *NetAddressV2) error {
+	return readNetAddressV2(r, pver, na)
+}
+
+// TstWriteNetAddressV2 makes the internal writeNetAddressV2 function available
+// to the test package.
+func TstWriteNetAddressV2(w io.Writer, pver uint32, na *NetAddressV2) error {
+	return writeNetAddressV2(w, pver, na)
+}
+
 // TstReadInvVect makes the internal readInvVect function available to the test
 // package.
 func TstReadInvVect(r io.Reader, pver uint32, iv *InvVect) error {
@@ -22,6 +22,12 @@ func TestInvTypeStringer(t *testing.T) {
 		{rddwire.InvTypeError, "ERROR"},
 		{rddwire.InvTypeTx, "MSG_TX"},
 		{rddwire.InvTypeBlock, "MSG_BLOCK"},
+		{rddwire.InvTypeFilteredBlock, "MSG_FILTERED_BLOCK"},
+		{rddwire.InvTypeCmpctBlock, "MSG_CMPCT_BLOCK"},
+		{rddwire.InvTypeWtx, "MSG_WTX"},
+		{rddwire.InvTypeWitnessTx, "MSG_WITNESS_TX"},
+		{rddwire.InvTypeWitnessBlock, "MSG_WITNESS_BLOCK"},
+		{rddwire.InvTypeFilteredWitnessBlock, "MSG_FILTERED_WITNESS_BLOCK"},
 		{0xffffffff, "Unknown InvType (4294967295)"},
 	}
 
@@ -110,6 +116,22 @@ func TestInvVectWire(t *testing.T) {
 		0x26, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Block 203707 hash
 	}
 
+	// witnessTxInvVect is an inventory vector representing a transaction
+	// with witness data.
+	witnessTxInvVect := rddwire.InvVect{
+		Type: rddwire.InvTypeWitnessTx,
+		Hash: *baseHash,
+	}
+
+	// witnessTxInvVectEncoded is the wire encoded bytes of witnessTxInvVect.
+	witnessTxInvVectEncoded := []byte{
+		0x01, 0x00, 0x00, 0x40, // InvTypeWitnessTx
+		0xdc, 0xe9, 0x69, 0x10, 0x94, 0xda, 0x23, 0xc7,
+		0xe7, 0x67, 0x13, 0xd0, 0x75, 0xd4, 0xa1, 0x0b,
+		0x79, 0x40, 0x08, 0xa6, 0x36, 0xac, 0xc2, 0x4b,
+		0x26, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Block 203707 hash
+	}
+
 	tests := []struct {
 		in   rddwire.InvVect // NetAddress to encode
 		out  rddwire.InvVect // Expected decoded NetAddress
@@ -140,6 +162,14 @@ func TestInvVectWire(t *testing.T) {
 			rddwire.ProtocolVersion,
 		},
 
+		// Latest protocol version witness tx inventory vector.
+		{
+			witnessTxInvVect,
+			witnessTxInvVect,
+			witnessTxInvVectEncoded,
+			rddwire.ProtocolVersion,
+		},
+
 		// Protocol version BIP0035Version error inventory vector.
 		{
 			errInvVect,
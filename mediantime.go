@@ -0,0 +1,262 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxAllowedOffsetSecs is the maximum number of seconds in either
+	// direction that local clock will be adjusted.  When the median time
+	// of the network is outside of this range, no offset will be applied.
+	MaxAllowedOffsetSecs = 70 * 60 // 1 hour 10 minutes
+
+	// similarTimeSecs is the number of seconds in either direction from the
+	// local clock that is used to determine that it is likely wrong and
+	// hence to show a warning.
+	similarTimeSecs = 5 * 60 // 5 minutes
+
+	// maxMedianTimeEntries is the maximum number of entries allowed in the
+	// median time data.
+	maxMedianTimeEntries = 200
+
+	// MedianTimeBlocks is the number of previous blocks which are used to
+	// calculate the median time used to validate block timestamps.
+	MedianTimeBlocks = 11
+)
+
+// MedianTimeSource provides a mechanism to add several time samples which are
+// used to determine a median time which is then used as an offset to the local
+// clock.
+type MedianTimeSource interface {
+	// AdjustedTime returns the current time adjusted by the median time
+	// offset as calculated from the time samples added by AddTimeSample.
+	AdjustedTime() time.Time
+
+	// AddTimeSample adds a time sample that is used when determining the
+	// median time of the added samples.  The time sample is typically the
+	// Timestamp of the version message (MsgVersion) received from a peer,
+	// and only the first sample from each source is used.
+	AddTimeSample(id string, timeVal time.Time)
+
+	// Offset returns the number of seconds to adjust the local clock based
+	// upon the median of the time samples added by AddTimeSample.
+	Offset() time.Duration
+
+	// ClockWarning returns whether the median offset of the time samples
+	// exceeded the maximum allowed offset while no sample was close to the
+	// local clock, which indicates the local clock is likely wrong.
+	ClockWarning() bool
+}
+
+// int64Sorter implements sort.Interface to allow a slice of 64-bit integers to
+// be sorted.
+type int64Sorter []int64
+
+// Len returns the number of 64-bit integers in the slice.  It is part of the
+// sort.Interface implementation.
+func (s int64Sorter) Len() int {
+	return len(s)
+}
+
+// Swap swaps the 64-bit integers at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s int64Sorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the 64-bit integer with index i should sort before the
+// 64-bit integer with index j.  It is part of the sort.Interface
+// implementation.
+func (s int64Sorter) Less(i, j int) bool {
+	return s[i] < s[j]
+}
+
+// medianTime provides an implementation of the MedianTimeSource interface.
+// It is limited to maxMedianTimeEntries and includes the same buggy behavior as
+// the time offset mechanism in Reddcoin, which is inherited from Bitcoin Core.
+// This is necessary because it is used in the consensus code.
+type medianTime struct {
+	mtx                sync.Mutex
+	knownIDs           map[string]struct{}
+	offsets            []int64
+	offsetSecs         int64
+	invalidTimeChecked bool
+	clockWarning       bool
+	now                func() time.Time
+}
+
+// Ensure the medianTime type implements the MedianTimeSource interface.
+var _ MedianTimeSource = (*medianTime)(nil)
+
+// AdjustedTime returns the current time adjusted by the median time offset as
+// calculated from the time samples added by AddTimeSample.
+//
+// This function is safe for concurrent access and is part of the
+// MedianTimeSource interface implementation.
+func (m *medianTime) AdjustedTime() time.Time {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	// Limit the adjusted time to 1 second precision.
+	now := time.Unix(m.now().Unix(), 0)
+	return now.Add(time.Duration(m.offsetSecs) * time.Second)
+}
+
+// AddTimeSample adds a time sample that is used when determining the median
+// time of the added samples.
+//
+// This function is safe for concurrent access and is part of the
+// MedianTimeSource interface implementation.
+func (m *medianTime) AddTimeSample(sourceID string, timeVal time.Time) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	// Don't add time data from the same source.
+	if _, exists := m.knownIDs[sourceID]; exists {
+		return
+	}
+	m.knownIDs[sourceID] = struct{}{}
+
+	// Truncate the provided offset to seconds and append it to the slice
+	// of offsets while respecting the maximum number of allowed entries by
+	// replacing the oldest entry with the new entry once the maximum number
+	// of entries is reached.
+	now := time.Unix(m.now().Unix(), 0)
+	offsetSecs := int64(timeVal.Sub(now).Seconds())
+	numOffsets := len(m.offsets)
+	if numOffsets == maxMedianTimeEntries {
+		m.offsets = m.offsets[1:]
+		numOffsets--
+	}
+	m.offsets = append(m.offsets, offsetSecs)
+	numOffsets++
+
+	// Sort the offsets so the median can be obtained as needed later.
+	sortedOffsets := make([]int64, numOffsets)
+	copy(sortedOffsets, m.offsets)
+	sort.Sort(int64Sorter(sortedOffsets))
+
+	// NOTE: The following code intentionally has a bug to mirror the
+	// buggy behavior in Bitcoin Core since the median time is used in the
+	// consensus rules.
+	//
+	// In particular, the offset is only updated when the number of entries
+	// is odd, but the max number of entries is 200, an even number.  Thus,
+	// the offset will never be updated again once the max number of entries
+	// is reached.
+
+	// The median offset is only updated when there are enough offsets and
+	// the number of offsets is odd so the middle value is the true median.
+	// Thus, there is nothing to do when those conditions are not met.
+	if numOffsets < 5 || numOffsets&0x01 != 1 {
+		return
+	}
+
+	// At this point the number of offsets in the list is odd, so the
+	// middle value of the sorted offsets is the median.
+	median := sortedOffsets[numOffsets/2]
+
+	// Set the new offset when the median offset is within the allowed
+	// offset range.
+	if math.Abs(float64(median)) < MaxAllowedOffsetSecs {
+		m.offsetSecs = median
+		return
+	}
+
+	// The median offset of all added time data is larger than the maximum
+	// allowed offset, so don't use an offset.  This effectively limits how
+	// far the local clock can be skewed.
+	m.offsetSecs = 0
+
+	if !m.invalidTimeChecked {
+		m.invalidTimeChecked = true
+
+		// Find if any time samples have a time that is close to the
+		// local time.
+		var remoteHasCloseTime bool
+		for _, offset := range sortedOffsets {
+			if math.Abs(float64(offset)) < similarTimeSecs {
+				remoteHasCloseTime = true
+				break
+			}
+		}
+
+		// Warn if none of the time samples are close.
+		if !remoteHasCloseTime {
+			m.clockWarning = true
+		}
+	}
+}
+
+// Offset returns the number of seconds to adjust the local clock based upon the
+// median of the time samples added by AddTimeSample.
+//
+// This function is safe for concurrent access and is part of the
+// MedianTimeSource interface implementation.
+func (m *medianTime) Offset() time.Duration {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return time.Duration(m.offsetSecs) * time.Second
+}
+
+// ClockWarning returns whether the median offset of the time samples exceeded
+// the maximum allowed offset while no sample was close to the local clock.
+//
+// This function is safe for concurrent access and is part of the
+// MedianTimeSource interface implementation.
+func (m *medianTime) ClockWarning() bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.clockWarning
+}
+
+// NewMedianTime returns a new instance of concurrency-safe implementation of
+// the MedianTimeSource interface.  The returned implementation contains the
+// rules necessary for proper time handling in the chain consensus rules and
+// expects the time samples to be added from the timestamp field of the version
+// message received from remote peers that successfully connect and negotiate.
+func NewMedianTime() MedianTimeSource {
+	return &medianTime{
+		knownIDs: make(map[string]struct{}),
+		offsets:  make([]int64, 0, maxMedianTimeEntries),
+		now:      time.Now,
+	}
+}
+
+// CalcPastMedianTime returns the median of the timestamps of the last
+// MedianTimeBlocks of the passed block headers, which are expected to be in
+// chain order.  Reddcoin requires the timestamp of a block to be after the
+// past median time of the blocks before it, so the headers passed to check a
+// block end with its previous block.  The zero time is returned when no
+// headers are passed.
+func CalcPastMedianTime(headers []*BlockHeader) time.Time {
+	if len(headers) == 0 {
+		return time.Time{}
+	}
+	if len(headers) > MedianTimeBlocks {
+		headers = headers[len(headers)-MedianTimeBlocks:]
+	}
+
+	timestamps := make([]int64, 0, len(headers))
+	for _, header := range headers {
+		timestamps = append(timestamps, header.Timestamp.Unix())
+	}
+	sort.Sort(int64Sorter(timestamps))
+
+	// NOTE: The consensus rules incorrectly calculate the median for even
+	// numbers of blocks.  A true median averages the middle two elements
+	// for a set with an even number of elements in it.  Since the constant
+	// for the previous number of blocks to be used is odd, this is only an
+	// issue for a few blocks near the beginning of the chain.  This code
+	// follows suit to ensure the same rules are used.
+	return time.Unix(timestamps[len(timestamps)/2], 0)
+}
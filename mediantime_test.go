@@ -0,0 +1,140 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+)
+
+// TestMedianTime tests the median time calculations.
+func TestMedianTime(t *testing.T) {
+	now := time.Unix(1400000000, 0)
+	clock := func() time.Time { return now }
+
+	// Offsets of 199 peers followed by 200 peers which are ignored since
+	// the maximum number of entries has been reached.
+	var maxEntries []int64
+	for i := 0; i < 199; i++ {
+		maxEntries = append(maxEntries, 1000)
+	}
+	for i := 0; i < 200; i++ {
+		maxEntries = append(maxEntries, 0)
+	}
+
+	tests := []struct {
+		name        string  // Test description
+		in          []int64 // Offsets of the time samples in seconds
+		useDupID    bool    // Whether to add every sample with the same id
+		wantOffset  int64   // Expected offset in seconds
+		wantWarning bool    // Whether the clock warning is expected
+	}{
+		// Not enough samples must result in an offset of 0.
+		{"no samples", nil, false, 0, false},
+		{"one sample", []int64{1}, false, 0, false},
+		{"four samples", []int64{1, 2, 3, 4}, false, 0, false},
+
+		// Various number of entries.  The expected offset is only
+		// updated on odd number of elements.
+		{"five samples", []int64{-10, 20, 30, -40, 50}, false, 20, false},
+		{"six samples", []int64{-10, 20, 30, -40, 50, -60}, false, 20,
+			false},
+		{"seven samples", []int64{-10, 20, 30, -40, 50, -60, -70},
+			false, -10, false},
+
+		// Duplicate samples from the same peer are ignored.
+		{"duplicate ids", []int64{-10, 20, 30, -40, 50}, true, 0, false},
+
+		// Offsets beyond the maximum allowed offset are not used and
+		// warn when no peer is close to the local clock.
+		{"max offset", []int64{4199, 4199, 4199, 4199, 4199}, false,
+			4199, false},
+		{"beyond max offset", []int64{4200, 4201, 4202, 4203, 4204},
+			false, 0, true},
+		{"beyond max negative offset", []int64{-4200, -4201, -4202,
+			-4203, -4204}, false, 0, true},
+		{"beyond max offset with close peer", []int64{4200, 4201, 4202,
+			4203, 299}, false, 0, false},
+		{"beyond max offset with far peer", []int64{4200, 4201, 4202,
+			4203, 300}, false, 0, true},
+
+		// The offset is not updated once the maximum number of entries
+		// is reached.
+		{"max entries", maxEntries, false, 1000, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		filter := rddwire.TstNewMedianTime(clock)
+		for i, offset := range test.in {
+			id := strconv.Itoa(i)
+			if test.useDupID {
+				id = "dup"
+			}
+			filter.AddTimeSample(id, now.Add(time.Duration(offset)*
+				time.Second))
+		}
+
+		wantOffset := time.Duration(test.wantOffset) * time.Second
+		if offset := filter.Offset(); offset != wantOffset {
+			t.Errorf("Offset %s: unexpected offset - got %v, want %v",
+				test.name, offset, wantOffset)
+			continue
+		}
+		adjusted := filter.AdjustedTime()
+		if want := now.Add(wantOffset); !adjusted.Equal(want) {
+			t.Errorf("AdjustedTime %s: unexpected result - got %v, "+
+				"want %v", test.name, adjusted, want)
+			continue
+		}
+		if warning := filter.ClockWarning(); warning != test.wantWarning {
+			t.Errorf("ClockWarning %s: got %v, want %v", test.name,
+				warning, test.wantWarning)
+			continue
+		}
+	}
+}
+
+// TestCalcPastMedianTime tests calculating the median time of previous blocks.
+func TestCalcPastMedianTime(t *testing.T) {
+	tests := []struct {
+		name       string  // Test description
+		timestamps []int64 // Timestamps of the blocks in chain order
+		want       int64   // Expected past median time
+	}{
+		{"no blocks", nil, 0},
+		{"one block", []int64{1400000000}, 1400000000},
+		{"two blocks", []int64{1400000000, 1400000060}, 1400000060},
+		{"unordered", []int64{1400000300, 1400000000, 1400000120,
+			1400000060, 1400000240}, 1400000120},
+		{"eleven blocks", []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, 6},
+		{"last eleven blocks", []int64{100, 100, 100, 100, 1, 2, 3, 4,
+			5, 6, 7, 8, 9, 10, 11}, 6},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		headers := make([]*rddwire.BlockHeader, 0, len(test.timestamps))
+		for _, timestamp := range test.timestamps {
+			headers = append(headers, &rddwire.BlockHeader{
+				Timestamp: time.Unix(timestamp, 0),
+			})
+		}
+
+		want := time.Unix(test.want, 0)
+		if len(test.timestamps) == 0 {
+			want = time.Time{}
+		}
+		got := rddwire.CalcPastMedianTime(headers)
+		if !got.Equal(want) {
+			t.Errorf("CalcPastMedianTime %s: got %v, want %v",
+				test.name, got, want)
+			continue
+		}
+	}
+}
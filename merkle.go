@@ -0,0 +1,204 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+)
+
+// hashMerkleBranches returns the hash of the merkle tree node which has the
+// passed left and right children.  It is the double SHA-256 of the left hash
+// followed by the right hash.
+func hashMerkleBranches(left, right *ShaHash) *ShaHash {
+	var buf [HashSize * 2]byte
+	copy(buf[:HashSize], left[:])
+	copy(buf[HashSize:], right[:])
+
+	// SetBytes can't fail here due to the fact DoubleSha256 always returns
+	// a []byte of the right size regardless of input.
+	var hash ShaHash
+	_ = hash.SetBytes(DoubleSha256(buf[:]))
+	return &hash
+}
+
+// nextPowerOfTwo returns the next highest power of two from the passed number
+// or the number itself if it is already a power of two.
+func nextPowerOfTwo(n int) int {
+	if n&(n-1) == 0 {
+		return n
+	}
+
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// BuildMerkleTreeStore builds the merkle tree of the passed transaction hashes
+// and returns its root along with the tree stored as a linear array.
+//
+// The array holds the leaves first, padded with nil entries up to the next
+// power of two, followed by each level of the tree in turn up to the root,
+// which is the last entry.  For example, a tree of five transactions is stored
+// as follows where the numbers are the transaction hashes and the letters are
+// the interior nodes:
+//
+//	[1 2 3 4 5 nil nil nil A B C nil D E F]
+//
+// As in Reddcoin's merkle tree, a node without a right child is hashed with
+// itself, and nodes without any children are nil.  The root of an empty list
+// of hashes is all zeros and its store is nil.
+func BuildMerkleTreeStore(txHashes []ShaHash) (ShaHash, []*ShaHash) {
+	if len(txHashes) == 0 {
+		return ShaHash{}, nil
+	}
+
+	// Calculate how many entries are required to hold the binary merkle
+	// tree as a linear array and create an array of that size.
+	nextPoT := nextPowerOfTwo(len(txHashes))
+	arraySize := nextPoT*2 - 1
+	merkles := make([]*ShaHash, arraySize)
+
+	// Create the base transaction hashes and populate the array with them.
+	for i := range txHashes {
+		hash := txHashes[i]
+		merkles[i] = &hash
+	}
+
+	// Start the array offset after the last transaction and adjusted to the
+	// next power of two.
+	offset := nextPoT
+	for i := 0; i < arraySize-1; i += 2 {
+		switch {
+		// When there is no left child node, the parent is nil too.
+		case merkles[i] == nil:
+			merkles[offset] = nil
+
+		// When there is no right child, the parent is generated by
+		// hashing the concatenation of the left child with itself.
+		case merkles[i+1] == nil:
+			merkles[offset] = hashMerkleBranches(merkles[i], merkles[i])
+
+		// The normal case sets the parent node to the double sha256
+		// of the concatenation of the left and right children.
+		default:
+			merkles[offset] = hashMerkleBranches(merkles[i], merkles[i+1])
+		}
+		offset++
+	}
+
+	return *merkles[arraySize-1], merkles
+}
+
+// IsMerkleTreeMutated returns whether the passed merkle tree store, as returned
+// by BuildMerkleTreeStore, has a node whose left and right children are
+// identical.  This happens when a list of transactions is mutated by
+// duplicating transactions at its end (CVE-2012-2459), which results in the
+// same merkle root as the original list, so blocks with such trees must be
+// rejected even though their merkle root matches.
+func IsMerkleTreeMutated(store []*ShaHash) bool {
+	// Every level of the tree has an even number of entries, so the
+	// children of each node are at an even index and the one following
+	// it.
+	for i := 0; i+1 < len(store); i += 2 {
+		left, right := store[i], store[i+1]
+		if left != nil && right != nil && left.IsEqual(right) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MerkleBranch returns the merkle branch of the transaction at the passed index
+// of the passed merkle tree store, as returned by BuildMerkleTreeStore.  The
+// branch holds the sibling of each node on the path from the transaction to
+// the root, starting with the sibling of the transaction itself, and along
+// with the index it proves the transaction is included in the tree.  See
+// MerkleBranchRoot.
+func MerkleBranch(store []*ShaHash, index uint32) ([]ShaHash, error) {
+	numLeaves := uint64(len(store)+1) / 2
+	if uint64(index) >= numLeaves || store[index] == nil {
+		str := fmt.Sprintf("no transaction at index %d of merkle tree",
+			index)
+		return nil, messageError("MerkleBranch", str)
+	}
+
+	var branch []ShaHash
+	offset, pos := 0, int(index)
+	for width := int(numLeaves); width > 1; width /= 2 {
+		// A node without a sibling is hashed with itself.
+		sibling := store[offset+(pos^1)]
+		if sibling == nil {
+			sibling = store[offset+pos]
+		}
+		branch = append(branch, *sibling)
+
+		offset += width
+		pos /= 2
+	}
+
+	return branch, nil
+}
+
+// MerkleBranchRoot returns the merkle root which results from hashing the
+// passed transaction hash together with the hashes of the passed merkle branch
+// for the transaction at the passed index, as returned by MerkleBranch.  The
+// transaction is included in a block when the result matches the merkle root
+// in its header.
+func MerkleBranchRoot(txHash *ShaHash, branch []ShaHash, index uint32) ShaHash {
+	root, _ := merkleBranchRoot(txHash, branch, index)
+	return root
+}
+
+// merkleBranchRoot returns the merkle root in the same way as MerkleBranchRoot
+// along with whether any node of the path to the root is a right node equal to
+// its left sibling.  Such a node is the copy a node without a sibling is hashed
+// with, so the path does not lead to a transaction of the block.
+func merkleBranchRoot(txHash *ShaHash, branch []ShaHash, index uint32) (ShaHash, bool) {
+	hash := txHash
+	duplicate := false
+	for i := range branch {
+		if index&1 == 0 {
+			hash = hashMerkleBranches(hash, &branch[i])
+		} else {
+			if branch[i].IsEqual(hash) {
+				duplicate = true
+			}
+			hash = hashMerkleBranches(&branch[i], hash)
+		}
+		index >>= 1
+	}
+
+	return *hash, duplicate
+}
+
+// CheckMerkleRoot ensures the merkle root in the block header matches the
+// merkle root of the transactions in the block and that the merkle tree of the
+// transactions was not mutated by duplicating transactions.  See
+// IsMerkleTreeMutated.
+func (msg *MsgBlock) CheckMerkleRoot() error {
+	txHashes, err := msg.TxShas()
+	if err != nil {
+		return err
+	}
+
+	root, store := BuildMerkleTreeStore(txHashes)
+	if !root.IsEqual(&msg.Header.MerkleRoot) {
+		str := fmt.Sprintf("block merkle root is invalid - block "+
+			"header indicates %v, but calculated value is %v",
+			msg.Header.MerkleRoot, root)
+		return messageError("MsgBlock.CheckMerkleRoot", str)
+	}
+
+	if IsMerkleTreeMutated(store) {
+		str := "block contains duplicate transactions which mutate " +
+			"its merkle tree"
+		return messageError("MsgBlock.CheckMerkleRoot", str)
+	}
+
+	return nil
+}
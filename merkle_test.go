@@ -0,0 +1,198 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+)
+
+// decodeBloomTestBlock returns bloomTestBlock deserialized into a block.
+func decodeBloomTestBlock(t *testing.T) *rddwire.MsgBlock {
+	var block rddwire.MsgBlock
+	blockBytes, _ := hex.DecodeString(bloomTestBlock)
+	err := block.Deserialize(bytes.NewReader(blockBytes))
+	if err != nil {
+		t.Fatalf("Deserialize: %v", err)
+	}
+	return &block
+}
+
+// TestBuildMerkleTreeStore tests the merkle tree construction of the
+// transactions of blocks.
+func TestBuildMerkleTreeStore(t *testing.T) {
+	tests := []struct {
+		block   *rddwire.MsgBlock // Block to build the merkle tree of
+		numNode int               // Expected number of entries in store
+	}{
+		{&blockOne, 1},
+		{decodeBloomTestBlock(t), 15},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		txHashes, err := test.block.TxShas()
+		if err != nil {
+			t.Errorf("TxShas #%d: %v", i, err)
+			continue
+		}
+
+		root, store := rddwire.BuildMerkleTreeStore(txHashes)
+		if !root.IsEqual(&test.block.Header.MerkleRoot) {
+			t.Errorf("BuildMerkleTreeStore #%d: got root %v, want %v",
+				i, root, test.block.Header.MerkleRoot)
+			continue
+		}
+		if len(store) != test.numNode {
+			t.Errorf("BuildMerkleTreeStore #%d: got %d entries, "+
+				"want %d", i, len(store), test.numNode)
+			continue
+		}
+		if !store[len(store)-1].IsEqual(&root) {
+			t.Errorf("BuildMerkleTreeStore #%d: last entry %v is "+
+				"not the root %v", i, store[len(store)-1], root)
+			continue
+		}
+		for j := range txHashes {
+			if !store[j].IsEqual(&txHashes[j]) {
+				t.Errorf("BuildMerkleTreeStore #%d: leaf %d is %v, "+
+					"want %v", i, j, store[j], txHashes[j])
+			}
+		}
+		for j := len(txHashes); j < (len(store)+1)/2; j++ {
+			if store[j] != nil {
+				t.Errorf("BuildMerkleTreeStore #%d: padding leaf "+
+					"%d is %v, want nil", i, j, store[j])
+			}
+		}
+	}
+
+	// An empty list of hashes has a zero root and no tree.
+	root, store := rddwire.BuildMerkleTreeStore(nil)
+	if !root.IsEqual(&rddwire.ShaHash{}) || store != nil {
+		t.Errorf("BuildMerkleTreeStore: got root %v and store %v for "+
+			"no hashes, want zero root and nil store", root, store)
+	}
+}
+
+// TestMerkleBranch ensures the merkle branch of every transaction of a block
+// results in the merkle root of the block.
+func TestMerkleBranch(t *testing.T) {
+	blocks := []*rddwire.MsgBlock{&blockOne, decodeBloomTestBlock(t)}
+
+	t.Logf("Running %d tests", len(blocks))
+	for i, block := range blocks {
+		txHashes, _ := block.TxShas()
+		_, store := rddwire.BuildMerkleTreeStore(txHashes)
+		for j := range txHashes {
+			branch, err := rddwire.MerkleBranch(store, uint32(j))
+			if err != nil {
+				t.Errorf("MerkleBranch #%d tx %d: %v", i, j, err)
+				continue
+			}
+
+			root := rddwire.MerkleBranchRoot(&txHashes[j], branch,
+				uint32(j))
+			if !root.IsEqual(&block.Header.MerkleRoot) {
+				t.Errorf("MerkleBranchRoot #%d tx %d: got %v, "+
+					"want %v", i, j, root,
+					block.Header.MerkleRoot)
+				continue
+			}
+
+			// The branch must not prove the transaction at the index
+			// of its sibling when there is one.
+			if j^1 >= len(txHashes) {
+				continue
+			}
+			root = rddwire.MerkleBranchRoot(&txHashes[j], branch,
+				uint32(j^1))
+			if root.IsEqual(&block.Header.MerkleRoot) {
+				t.Errorf("MerkleBranchRoot #%d tx %d: branch "+
+					"proves wrong index", i, j)
+				continue
+			}
+		}
+	}
+
+	// Ensure indices without a transaction are rejected.
+	txHashes, _ := blocks[1].TxShas()
+	_, store := rddwire.BuildMerkleTreeStore(txHashes)
+	indices := []uint32{uint32(len(txHashes)), 8, 100}
+	for _, index := range indices {
+		_, err := rddwire.MerkleBranch(store, index)
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			t.Errorf("MerkleBranch: wrong error for index %d got: "+
+				"%v, want: %T", index, err, &rddwire.MessageError{})
+		}
+	}
+}
+
+// TestCheckMerkleRoot ensures the merkle root check of blocks rejects merkle
+// roots which don't match and merkle trees which have been mutated.
+func TestCheckMerkleRoot(t *testing.T) {
+	block := decodeBloomTestBlock(t)
+
+	wrongRoot := rddwire.NewMsgBlock(&block.Header)
+	wrongRoot.Header.MerkleRoot = rddwire.ShaHash{}
+	for _, tx := range block.Transactions {
+		wrongRoot.AddTransaction(tx)
+	}
+
+	// Block made of the first three transactions and the block with its
+	// last transaction duplicated, which has the same merkle root.
+	threeTxns := rddwire.NewMsgBlock(&block.Header)
+	for _, tx := range block.Transactions[:3] {
+		threeTxns.AddTransaction(tx)
+	}
+	txHashes, _ := threeTxns.TxShas()
+	threeTxns.Header.MerkleRoot, _ = rddwire.BuildMerkleTreeStore(txHashes)
+
+	mutated := rddwire.NewMsgBlock(&threeTxns.Header)
+	for _, tx := range threeTxns.Transactions {
+		mutated.AddTransaction(tx)
+	}
+	mutated.AddTransaction(threeTxns.Transactions[2])
+
+	tests := []struct {
+		block *rddwire.MsgBlock // Block to check
+		err   error             // Expected error
+	}{
+		{&blockOne, nil},
+		{block, nil},
+		{threeTxns, nil},
+		{wrongRoot, &rddwire.MessageError{}},
+		{mutated, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		err := test.block.CheckMerkleRoot()
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("CheckMerkleRoot #%d wrong error got: %v, "+
+				"want: %v", i, err, reflect.TypeOf(test.err))
+			continue
+		}
+	}
+
+	// The mutated merkle tree has the same root as the original one.
+	mutatedHashes, _ := mutated.TxShas()
+	root, store := rddwire.BuildMerkleTreeStore(mutatedHashes)
+	if !root.IsEqual(&threeTxns.Header.MerkleRoot) {
+		t.Errorf("BuildMerkleTreeStore: mutated root %v, want %v", root,
+			threeTxns.Header.MerkleRoot)
+	}
+	if !rddwire.IsMerkleTreeMutated(store) {
+		t.Errorf("IsMerkleTreeMutated: mutated tree not detected")
+	}
+	_, store = rddwire.BuildMerkleTreeStore(txHashes)
+	if rddwire.IsMerkleTreeMutated(store) {
+		t.Errorf("IsMerkleTreeMutated: unmutated tree detected")
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+)
+
+// merkleBlock houses the intermediate information needed to build the partial
+// merkle tree of a merkleblock message (MsgMerkleBlock).
+type merkleBlock struct {
+	numTx       uint32
+	allHashes   []*ShaHash
+	finalHashes []*ShaHash
+	matchedBits []byte
+	bits        []byte
+}
+
+// calcTreeWidth returns the number of nodes (width) of the merkle tree at the
+// given height, where the leaves are at height zero.
+func (m *merkleBlock) calcTreeWidth(height uint32) uint32 {
+	return (m.numTx + (1 << height) - 1) >> height
+}
+
+// calcHash returns the hash of the node at the given height and position of
+// the merkle tree.
+func (m *merkleBlock) calcHash(height, pos uint32) *ShaHash {
+	if height == 0 {
+		return m.allHashes[pos]
+	}
+
+	// A node without a right child is hashed with itself.
+	left := m.calcHash(height-1, pos*2)
+	right := left
+	if pos*2+1 < m.calcTreeWidth(height-1) {
+		right = m.calcHash(height-1, pos*2+1)
+	}
+	return hashMerkleBranches(left, right)
+}
+
+// traverseAndBuild builds the partial merkle tree depth first starting at the
+// node with the given height and position.  Each node visited adds a bit that
+// is set when it is the ancestor of a matched transaction, and the hash of
+// each node which is either a leaf or not the ancestor of a matched
+// transaction is included since its children are not visited.
+func (m *merkleBlock) traverseAndBuild(height, pos uint32) {
+	// Determine whether the node is the ancestor of a matched leaf.
+	var isParent byte
+	for i := pos << height; i < (pos+1)<<height && i < m.numTx; i++ {
+		isParent |= m.matchedBits[i]
+	}
+	m.bits = append(m.bits, isParent)
+
+	// Include the hash of leaves and of nodes which are not ancestors of
+	// a matched leaf since their children are not visited.
+	if height == 0 || isParent == 0x00 {
+		m.finalHashes = append(m.finalHashes, m.calcHash(height, pos))
+		return
+	}
+
+	// Descend into the left child and then the right child if there is
+	// one.
+	m.traverseAndBuild(height-1, pos*2)
+	if pos*2+1 < m.calcTreeWidth(height-1) {
+		m.traverseAndBuild(height-1, pos*2+1)
+	}
+}
+
+// NewMerkleBlock returns a merkleblock message (MsgMerkleBlock) for the passed
+// block which proves the inclusion of every transaction matched by the passed
+// filter, along with the matched transactions in the order they appear in the
+// block.  As defined by BIP0037, the matched transactions are sent to the peer
+// in tx messages (MsgTx) immediately following the merkleblock message.
+//
+// Each transaction is matched with MatchTxAndUpdate, so the filter is updated
+// according to its BloomUpdateType as the block is processed.
+func NewMerkleBlock(block *MsgBlock, filter *BloomFilter) (*MsgMerkleBlock, []*MsgTx) {
+	numTx := uint32(len(block.Transactions))
+	mBlock := merkleBlock{
+		numTx:       numTx,
+		allHashes:   make([]*ShaHash, 0, numTx),
+		matchedBits: make([]byte, 0, numTx),
+	}
+
+	// Find and keep track of any transactions that match the filter.
+	var matchedTxns []*MsgTx
+	for _, tx := range block.Transactions {
+		if filter.MatchTxAndUpdate(tx) {
+			mBlock.matchedBits = append(mBlock.matchedBits, 0x01)
+			matchedTxns = append(matchedTxns, tx)
+		} else {
+			mBlock.matchedBits = append(mBlock.matchedBits, 0x00)
+		}
+
+		// Ignore the error since TxSha can't fail in the current
+		// implementation except due to run-time panics.
+		txHash, _ := tx.TxSha()
+		mBlock.allHashes = append(mBlock.allHashes, &txHash)
+	}
+
+	// Calculate the number of merkle branches (height) in the tree and
+	// build the partial merkle tree from its root.
+	height := uint32(0)
+	for mBlock.calcTreeWidth(height) > 1 {
+		height++
+	}
+	if numTx > 0 {
+		mBlock.traverseAndBuild(height, 0)
+	}
+
+	msg := MsgMerkleBlock{
+		Header:       block.Header,
+		Transactions: numTx,
+		Hashes:       make([]*ShaHash, 0, len(mBlock.finalHashes)),
+		Flags:        make([]byte, (len(mBlock.bits)+7)/8),
+	}
+	for _, hash := range mBlock.finalHashes {
+		_ = msg.AddTxHash(hash)
+	}
+	for i := uint32(0); i < uint32(len(mBlock.bits)); i++ {
+		msg.Flags[i/8] |= mBlock.bits[i] << (i % 8)
+	}
+	return &msg, matchedTxns
+}
+
+// partialMerkleTree houses the state needed to walk the partial merkle tree of
+// a merkleblock message (MsgMerkleBlock) in order to verify it.
+type partialMerkleTree struct {
+	numTx      uint32
+	hashes     []*ShaHash
+	flags      []byte
+	bitsUsed   uint32
+	hashesUsed uint32
+	matches    []ShaHash
+	indices    []uint32
+}
+
+// calcTreeWidth returns the number of nodes (width) of the merkle tree at the
+// given height, where the leaves are at height zero.
+func (t *partialMerkleTree) calcTreeWidth(height uint32) uint32 {
+	return (t.numTx + (1 << height) - 1) >> height
+}
+
+// traverseAndExtract walks the partial merkle tree depth first in the same
+// order it was built starting at the node with the given height and position
+// and returns the hash of the node.  The hashes and positions of matched
+// leaves are recorded as they are visited.
+func (t *partialMerkleTree) traverseAndExtract(height, pos uint32) (*ShaHash, error) {
+	if t.bitsUsed >= uint32(len(t.flags))*8 {
+		str := "partial merkle tree has too few flag bits"
+		return nil, messageError("VerifyMerkleBlock", str)
+	}
+	isParent := t.flags[t.bitsUsed/8]&(1<<(t.bitsUsed%8)) != 0
+	t.bitsUsed++
+
+	// The hash of leaves and of nodes which are not ancestors of a
+	// matched leaf is provided directly since their children are not
+	// included.
+	if height == 0 || !isParent {
+		if t.hashesUsed >= uint32(len(t.hashes)) {
+			str := "partial merkle tree has too few hashes"
+			return nil, messageError("VerifyMerkleBlock", str)
+		}
+		hash := t.hashes[t.hashesUsed]
+		t.hashesUsed++
+
+		if height == 0 && isParent {
+			t.matches = append(t.matches, *hash)
+			t.indices = append(t.indices, pos)
+		}
+		return hash, nil
+	}
+
+	// Otherwise, calculate the hash of the node from its children.  A
+	// node without a right child is hashed with itself.
+	left, err := t.traverseAndExtract(height-1, pos*2)
+	if err != nil {
+		return nil, err
+	}
+	right := left
+	if pos*2+1 < t.calcTreeWidth(height-1) {
+		right, err = t.traverseAndExtract(height-1, pos*2+1)
+		if err != nil {
+			return nil, err
+		}
+
+		// Identical children are only possible when a node has no
+		// right child, so they indicate the tree was mutated by
+		// duplicating transactions (CVE-2012-2459).
+		if right.IsEqual(left) {
+			str := "partial merkle tree has identical left and " +
+				"right children"
+			return nil, messageError("VerifyMerkleBlock", str)
+		}
+	}
+	return hashMerkleBranches(left, right), nil
+}
+
+// VerifyMerkleBlock walks the partial merkle tree of the passed merkleblock
+// message and ensures it rebuilds the merkle root in the block header.  The
+// hashes of the matched transactions are returned along with their positions
+// within the block.
+//
+// An error is returned when the tree is malformed, which includes a
+// transaction count that is zero or that can't possibly fit into a block,
+// more hashes than transactions, hashes or flag bytes which are not consumed
+// by the tree, leftover flag bits after the last one consumed by the tree which
+// are set, flags which describe a tree that needs more hashes or flag bits than
+// are provided, and nodes whose children are identical, which is the result of
+// the CVE-2012-2459 duplicate transaction mutation.
+func VerifyMerkleBlock(msg *MsgMerkleBlock) ([]ShaHash, []uint32, error) {
+	if msg.Transactions == 0 {
+		str := "merkle block has no transactions"
+		return nil, nil, messageError("VerifyMerkleBlock", str)
+	}
+	if msg.Transactions > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions to fit into a block "+
+			"[count %d, max %d]", msg.Transactions, maxTxPerBlock)
+		return nil, nil, messageError("VerifyMerkleBlock", str)
+	}
+	if uint32(len(msg.Hashes)) > msg.Transactions {
+		str := fmt.Sprintf("merkle block has more hashes than "+
+			"transactions [hashes %d, transactions %d]",
+			len(msg.Hashes), msg.Transactions)
+		return nil, nil, messageError("VerifyMerkleBlock", str)
+	}
+
+	tree := partialMerkleTree{
+		numTx:  msg.Transactions,
+		hashes: msg.Hashes,
+		flags:  msg.Flags,
+	}
+	height := uint32(0)
+	for tree.calcTreeWidth(height) > 1 {
+		height++
+	}
+	root, err := tree.traverseAndExtract(height, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Every hash and flag byte must have been consumed by the tree.
+	if (tree.bitsUsed+7)/8 != uint32(len(msg.Flags)) {
+		str := fmt.Sprintf("partial merkle tree has unused flag bytes "+
+			"[used %d, provided %d]", (tree.bitsUsed+7)/8,
+			len(msg.Flags))
+		return nil, nil, messageError("VerifyMerkleBlock", str)
+	}
+
+	// The leftover bits of the last flag byte are padding which must not be
+	// set, so the flags can't be malleated without changing the tree.
+	if unused := tree.bitsUsed % 8; unused != 0 &&
+		msg.Flags[len(msg.Flags)-1]>>unused != 0 {
+
+		str := fmt.Sprintf("partial merkle tree has leftover flag bits "+
+			"set [used %d, last flag byte 0x%02x]", tree.bitsUsed,
+			msg.Flags[len(msg.Flags)-1])
+		return nil, nil, messageError("VerifyMerkleBlock", str)
+	}
+	if tree.hashesUsed != uint32(len(msg.Hashes)) {
+		str := fmt.Sprintf("partial merkle tree has unused hashes "+
+			"[used %d, provided %d]", tree.hashesUsed,
+			len(msg.Hashes))
+		return nil, nil, messageError("VerifyMerkleBlock", str)
+	}
+
+	if !root.IsEqual(&msg.Header.MerkleRoot) {
+		str := fmt.Sprintf("partial merkle tree root %v does not match "+
+			"merkle root %v in block header", root,
+			msg.Header.MerkleRoot)
+		return nil, nil, messageError("VerifyMerkleBlock", str)
+	}
+
+	return tree.matches, tree.indices, nil
+}
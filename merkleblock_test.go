@@ -0,0 +1,276 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestNewMerkleBlock ensures merkleblock messages built from a block and a
+// bloom filter contain the expected partial merkle tree and matched
+// transactions.
+func TestNewMerkleBlock(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Block with a single transaction.
+	oneTxBlockStr := "0100000079cda856b143d9db2c1caff01d1aecc8630d30625d10e8b" +
+		"4b8b0000000000000b50cc069d6a3e33e3ff84a5c41d9d3febe7c770fdc" +
+		"c96b2c3ff60abe184f196367291b4d4c86041b8fa45d630101000000010" +
+		"00000000000000000000000000000000000000000000000000000000000" +
+		"0000ffffffff08044c86041b020a02ffffffff0100f2052a01000000434" +
+		"104ecd3229b0571c3be876feaac0442a9f13c5a572742927af1dc623353" +
+		"ecf8c202225f64868137a18cdd85cbbb4c74fbccfd4f49639cf1bdc94a5" +
+		"672bb15ad5d4cac00000000"
+	oneTxMerkleBlockStr := "0100000079cda856b143d9db2c1caff01d1aecc8630d30625d10e8b4" +
+		"b8b0000000000000b50cc069d6a3e33e3ff84a5c41d9d3febe7c770fdcc" +
+		"96b2c3ff60abe184f196367291b4d4c86041b8fa45d630100000001b50c" +
+		"c069d6a3e33e3ff84a5c41d9d3febe7c770fdcc96b2c3ff60abe184f196" +
+		"30101"
+
+	// Partial merkle tree of bloomTestBlock which proves the inclusion of
+	// its first and fourth transactions.
+	bloomTestMerkleBlockStr := "0100000082bb869cf3a793432a66e826e05a6fc37469f8efb7421dc8" +
+		"80670100000000007f16c5962e8bd963659c793ce370d95f093bc7e3" +
+		"67117b3c30c1f8fdd0d9728776381b4d4c86041b554b852907000000" +
+		"050b3674c6e50f36f36f7a9f485e76c7868bf4d9f5984eaa0b599665" +
+		"7876aa7c14fdacf9b3eb077412e7a968d2e4f11b9a9dee312d666187" +
+		"ed77ee7d26af16cb0b8a92a3ea10b8c728a0b7e10b39b1b1e281d648" +
+		"9d5a3716f228268e815786734841c05bdf71643267ded2cf037af210" +
+		"5a036621fcf46858bc1d48f052a01f9802cfbc39264b50034b71abba" +
+		"2d4eb0220ad66bf8ffde47d42b32b199accbdca73902af00"
+
+	// Partial merkle tree of bloomTestBlock which matches nothing and
+	// therefore only includes the merkle root.
+	bloomTestNoMatchStr := "0100000082bb869cf3a793432a66e826e05a6fc37469f8efb7421dc8" +
+		"80670100000000007f16c5962e8bd963659c793ce370d95f093bc7e3" +
+		"67117b3c30c1f8fdd0d9728776381b4d4c86041b554b852907000000" +
+		"017f16c5962e8bd963659c793ce370d95f093bc7e367117b3c30c1f8" +
+		"fdd0d972870100"
+
+	pubKey, _ := hex.DecodeString("04eaafc2314def4ca98ac970241bcab022b9c1e1" +
+		"f4ea423a20f134c876f2c01ec0f0dd5b2e86e7168cefe0d81113c3807420ce" +
+		"13ad1357231a2252247d97a46a91")
+	addr, _ := hex.DecodeString("b6efd80d99179f4f4ff6f4dd0a007d018c385d21")
+	oneTxHash, _ := rddwire.NewShaHashFromStr("63194f18be0af63f2c6bc9dc0f77" +
+		"7cbefed3d9415c4af83f3ee3a3d669c00cb5")
+
+	tests := []struct {
+		block   string   // Hex encoded block
+		data    [][]byte // Data to add to the filter
+		want    string   // Expected hex encoded merkleblock message
+		matched []int    // Indices of expected matched transactions
+	}{
+		{oneTxBlockStr, [][]byte{oneTxHash[:]}, oneTxMerkleBlockStr,
+			[]int{0}},
+		{bloomTestBlock, [][]byte{pubKey, addr}, bloomTestMerkleBlockStr,
+			[]int{0, 3}},
+		{bloomTestBlock, [][]byte{{0x01, 0x02, 0x03}}, bloomTestNoMatchStr,
+			nil},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		var block rddwire.MsgBlock
+		blockBytes, _ := hex.DecodeString(test.block)
+		err := block.Deserialize(bytes.NewReader(blockBytes))
+		if err != nil {
+			t.Errorf("Deserialize #%d error %v", i, err)
+			continue
+		}
+
+		bf := rddwire.NewBloomFilter(10, 0, 0.000001,
+			rddwire.BloomUpdateNone)
+		for _, data := range test.data {
+			bf.Add(data)
+		}
+		msg, matched := rddwire.NewMerkleBlock(&block, bf)
+
+		var buf bytes.Buffer
+		err = msg.BtcEncode(&buf, pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		want, _ := hex.DecodeString(test.want)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("NewMerkleBlock #%d\n got: %x want: %x", i,
+				buf.Bytes(), want)
+			continue
+		}
+
+		var wantMatched []*rddwire.MsgTx
+		for _, idx := range test.matched {
+			wantMatched = append(wantMatched, block.Transactions[idx])
+		}
+		if !reflect.DeepEqual(matched, wantMatched) {
+			t.Errorf("NewMerkleBlock #%d\n got: %s want: %s", i,
+				spew.Sdump(matched), spew.Sdump(wantMatched))
+			continue
+		}
+	}
+}
+
+// TestVerifyMerkleBlock ensures the partial merkle trees of merkleblock
+// messages are verified and the matched transactions are extracted properly
+// for every combination of matched transactions in a block.
+func TestVerifyMerkleBlock(t *testing.T) {
+	var block rddwire.MsgBlock
+	blockBytes, _ := hex.DecodeString(bloomTestBlock)
+	err := block.Deserialize(bytes.NewReader(blockBytes))
+	if err != nil {
+		t.Errorf("Deserialize: %v", err)
+		return
+	}
+	txHashes, _ := block.TxShas()
+
+	numTx := uint32(len(block.Transactions))
+	for combo := 0; combo < 1<<numTx; combo++ {
+		// Match the transactions selected by the combination.
+		bf := rddwire.NewBloomFilter(numTx, 0, 0.000001,
+			rddwire.BloomUpdateNone)
+		var wantHashes []rddwire.ShaHash
+		var wantIndices []uint32
+		for i := uint32(0); i < numTx; i++ {
+			if combo&(1<<i) != 0 {
+				bf.AddShaHash(&txHashes[i])
+				wantHashes = append(wantHashes, txHashes[i])
+				wantIndices = append(wantIndices, i)
+			}
+		}
+		msg, _ := rddwire.NewMerkleBlock(&block, bf)
+
+		hashes, indices, err := rddwire.VerifyMerkleBlock(msg)
+		if err != nil {
+			t.Errorf("VerifyMerkleBlock #%d error %v", combo, err)
+			continue
+		}
+		if !reflect.DeepEqual(hashes, wantHashes) {
+			t.Errorf("VerifyMerkleBlock #%d\n got: %s want: %s", combo,
+				spew.Sdump(hashes), spew.Sdump(wantHashes))
+			continue
+		}
+		if !reflect.DeepEqual(indices, wantIndices) {
+			t.Errorf("VerifyMerkleBlock #%d\n got: %v want: %v", combo,
+				indices, wantIndices)
+			continue
+		}
+	}
+}
+
+// TestVerifyMerkleBlockErrors performs negative tests against verifying the
+// partial merkle trees of merkleblock messages to ensure malformed trees are
+// rejected.
+func TestVerifyMerkleBlockErrors(t *testing.T) {
+	var block rddwire.MsgBlock
+	blockBytes, _ := hex.DecodeString(bloomTestBlock)
+	err := block.Deserialize(bytes.NewReader(blockBytes))
+	if err != nil {
+		t.Errorf("Deserialize: %v", err)
+		return
+	}
+	txHashes, _ := block.TxShas()
+
+	// Merkle block which matches the first and fourth transactions.
+	bf := rddwire.NewBloomFilter(2, 0, 0.000001, rddwire.BloomUpdateNone)
+	bf.AddShaHash(&txHashes[0])
+	bf.AddShaHash(&txHashes[3])
+	base, _ := rddwire.NewMerkleBlock(&block, bf)
+
+	// copyMsg returns a copy of the base merkle block which may be
+	// modified.
+	copyMsg := func() *rddwire.MsgMerkleBlock {
+		msg := *base
+		msg.Hashes = append([]*rddwire.ShaHash{}, base.Hashes...)
+		msg.Flags = append([]byte{}, base.Flags...)
+		return &msg
+	}
+
+	noTxns := copyMsg()
+	noTxns.Transactions = 0
+
+	tooManyTxns := copyMsg()
+	tooManyTxns.Transactions = rddwire.MaxBlockPayload
+
+	moreHashesThanTxns := copyMsg()
+	moreHashesThanTxns.Transactions = uint32(len(base.Hashes) - 1)
+
+	// Transaction count which changes the shape of the tree.
+	wrongTxns := copyMsg()
+	wrongTxns.Transactions = 4
+
+	unusedHash := copyMsg()
+	unusedHash.Hashes = append(unusedHash.Hashes, &txHashes[1])
+
+	missingHash := copyMsg()
+	missingHash.Hashes = missingHash.Hashes[:len(missingHash.Hashes)-1]
+
+	unusedFlags := copyMsg()
+	unusedFlags.Flags = append(unusedFlags.Flags, 0x00)
+
+	// Padding bit after the last flag bit used by the tree which is set.
+	leftoverFlagBits := copyMsg()
+	leftoverFlagBits.Flags[len(leftoverFlagBits.Flags)-1] |= 0x80
+
+	missingFlags := copyMsg()
+	missingFlags.Flags = missingFlags.Flags[:len(missingFlags.Flags)-1]
+
+	wrongHash := copyMsg()
+	wrongHash.Hashes[1] = &txHashes[2]
+
+	wrongRoot := copyMsg()
+	wrongRoot.Header.MerkleRoot = txHashes[0]
+
+	// Merkle block for a block which has been mutated by duplicating its
+	// last transaction, which doesn't change the merkle root, and which
+	// matches the duplicated transaction.
+	mutatedBlock := rddwire.NewMsgBlock(&block.Header)
+	for _, tx := range block.Transactions[:3] {
+		mutatedBlock.AddTransaction(tx)
+	}
+	mutatedBlock.AddTransaction(block.Transactions[2])
+	bf = rddwire.NewBloomFilter(1, 0, 0.000001, rddwire.BloomUpdateNone)
+	bf.AddShaHash(&txHashes[2])
+	mutated, _ := rddwire.NewMerkleBlock(mutatedBlock, bf)
+
+	tests := []struct {
+		msg *rddwire.MsgMerkleBlock // Merkle block to verify
+		err error                   // Expected error
+	}{
+		{noTxns, &rddwire.MessageError{}},
+		{tooManyTxns, &rddwire.MessageError{}},
+		{moreHashesThanTxns, &rddwire.MessageError{}},
+		{wrongTxns, &rddwire.MessageError{}},
+		{unusedHash, &rddwire.MessageError{}},
+		{missingHash, &rddwire.MessageError{}},
+		{unusedFlags, &rddwire.MessageError{}},
+		{leftoverFlagBits, &rddwire.MessageError{}},
+		{missingFlags, &rddwire.MessageError{}},
+		{wrongHash, &rddwire.MessageError{}},
+		{wrongRoot, &rddwire.MessageError{}},
+		{mutated, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		_, _, err := rddwire.VerifyMerkleBlock(test.msg)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("VerifyMerkleBlock #%d wrong error got: %v, "+
+				"want: %v", i, err, reflect.TypeOf(test.err))
+			continue
+		}
+	}
+
+	// Ensure the unmodified merkle block is valid.
+	_, _, err = rddwire.VerifyMerkleBlock(base)
+	if err != nil {
+		t.Errorf("VerifyMerkleBlock: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"io"
+)
+
+// maxMerkleProofBranchLen is the maximum number of hashes in the merkle branch
+// of a merkle proof.  Since the position of the transaction is a uint32, a
+// merkle tree can't be any deeper.
+const maxMerkleProofBranchLen = 32
+
+// MerkleProof defines a standalone proof that a transaction is included in a
+// block.  It consists of the header of the block, the transaction, the merkle
+// branch of the transaction and its position in the block, which together
+// rebuild the merkle root in the header without the rest of the block.
+//
+// Proofs are created from a block with NewMerkleProof and checked with Verify
+// against a trusted block of a header chain.
+type MerkleProof struct {
+	Header BlockHeader
+	Tx     *MsgTx
+	Branch []ShaHash
+	Index  uint32
+}
+
+// Deserialize decodes a merkle proof from r into the receiver.  The proof is
+// encoded as the block header, the transaction, the number of hashes in the
+// merkle branch as a variable length integer followed by the hashes, and the
+// position of the transaction.
+func (p *MerkleProof) Deserialize(r io.Reader) error {
+	err := readBlockHeader(r, 0, &p.Header)
+	if err != nil {
+		return err
+	}
+
+	var tx MsgTx
+	err = tx.Deserialize(r)
+	if err != nil {
+		return err
+	}
+	p.Tx = &tx
+
+	// Read num merkle branch hashes and limit to max.
+	count, err := readVarInt(r, 0)
+	if err != nil {
+		return err
+	}
+	if count > maxMerkleProofBranchLen {
+		str := fmt.Sprintf("too many merkle branch hashes for proof "+
+			"[count %v, max %v]", count, maxMerkleProofBranchLen)
+		return messageError("MerkleProof.Deserialize", str)
+	}
+
+	p.Branch = make([]ShaHash, count)
+	for i := range p.Branch {
+		err := readElement(r, &p.Branch[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = readElement(r, &p.Index)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Serialize encodes the merkle proof to w.  See Deserialize for the format.
+func (p *MerkleProof) Serialize(w io.Writer) error {
+	count := len(p.Branch)
+	if count > maxMerkleProofBranchLen {
+		str := fmt.Sprintf("too many merkle branch hashes for proof "+
+			"[count %v, max %v]", count, maxMerkleProofBranchLen)
+		return messageError("MerkleProof.Serialize", str)
+	}
+
+	err := writeBlockHeader(w, 0, &p.Header)
+	if err != nil {
+		return err
+	}
+
+	err = p.Tx.Serialize(w)
+	if err != nil {
+		return err
+	}
+
+	err = writeVarInt(w, 0, uint64(count))
+	if err != nil {
+		return err
+	}
+	for i := range p.Branch {
+		err = writeElement(w, &p.Branch[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeElement(w, p.Index)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SerializeSize returns the number of bytes it would take to serialize the
+// merkle proof.
+func (p *MerkleProof) SerializeSize() int {
+	// Block header + serialized tx + num branch hashes (varInt) +
+	// branch hashes + index 4 bytes.
+	return blockHeaderLen + p.Tx.SerializeSize() +
+		VarIntSerializeSize(uint64(len(p.Branch))) +
+		len(p.Branch)*HashSize + 4
+}
+
+// HeaderLookup provides the headers of known blocks along with their heights,
+// which is needed to establish that a block is an ancestor of a trusted block.
+// It is implemented by HeaderChain.
+type HeaderLookup interface {
+	// HeaderByHash returns the header of the block with the passed hash
+	// along with its height.  An error is returned if the block is not
+	// known.
+	HeaderByHash(hash *ShaHash) (*BlockHeader, int32, error)
+}
+
+// Verify ensures the merkle proof shows the transaction is included in the
+// block of the proof and that the block is part of the chain which ends at the
+// block identified by the passed trusted hash, such as the tip of a validated
+// header chain.  The proof block may be the trusted block itself or any of its
+// ancestors.  The passed lookup must know the trusted block, the proof block
+// and every block between them, which are walked back from the trusted block
+// to the height of the proof block.
+//
+// An error is returned when the trusted block or the proof block is not known
+// to the lookup, the proof block is not an ancestor of the trusted block, the
+// position of the transaction can't be expressed by the merkle branch, or the
+// merkle branch does not rebuild the merkle root in the header.
+//
+// Since a node without a sibling is hashed with a copy of itself, the merkle
+// branch of the last transaction of a level with an odd number of nodes also
+// rebuilds the merkle root for the position of the copy, where no transaction
+// exists.  The proof does not include the number of transactions in the block,
+// so such proofs are instead rejected by their merkle branch, which holds a
+// right sibling equal to the node it is hashed with.  This is never the case
+// for a valid block since the merkle tree of a block with duplicated
+// transactions is mutated (see IsMerkleTreeMutated).
+func (p *MerkleProof) Verify(lookup HeaderLookup, trustedHash *ShaHash) error {
+	blockHash, err := p.Header.BlockSha()
+	if err != nil {
+		return err
+	}
+	_, height, err := lookup.HeaderByHash(&blockHash)
+	if err != nil {
+		str := fmt.Sprintf("proof block %v is not known", blockHash)
+		return messageError("MerkleProof.Verify", str)
+	}
+	header, trustedHeight, err := lookup.HeaderByHash(trustedHash)
+	if err != nil {
+		str := fmt.Sprintf("trusted block %v is not known", trustedHash)
+		return messageError("MerkleProof.Verify", str)
+	}
+
+	// Walk back from the trusted block to the height of the proof block,
+	// which must then be reached for it to be an ancestor.
+	hash := *trustedHash
+	for h := trustedHeight; h > height; h-- {
+		hash = header.PrevBlock
+		header, _, err = lookup.HeaderByHash(&hash)
+		if err != nil {
+			str := fmt.Sprintf("ancestor %v of trusted block %v at "+
+				"height %d is not known", hash, trustedHash, h-1)
+			return messageError("MerkleProof.Verify", str)
+		}
+	}
+	if !hash.IsEqual(&blockHash) {
+		str := fmt.Sprintf("proof block %v at height %d is not an "+
+			"ancestor of trusted block %v at height %d", blockHash,
+			height, trustedHash, trustedHeight)
+		return messageError("MerkleProof.Verify", str)
+	}
+
+	if len(p.Branch) > maxMerkleProofBranchLen ||
+		uint64(p.Index)>>uint(len(p.Branch)) != 0 {
+
+		str := fmt.Sprintf("transaction index %d is out of range for "+
+			"a merkle branch of %d hashes", p.Index, len(p.Branch))
+		return messageError("MerkleProof.Verify", str)
+	}
+
+	txHash, err := p.Tx.TxSha()
+	if err != nil {
+		return err
+	}
+	root, duplicate := merkleBranchRoot(&txHash, p.Branch, p.Index)
+	if duplicate {
+		str := fmt.Sprintf("merkle branch of transaction %v at index "+
+			"%d passes through the copy of a node without a "+
+			"sibling", txHash, p.Index)
+		return messageError("MerkleProof.Verify", str)
+	}
+	if !root.IsEqual(&p.Header.MerkleRoot) {
+		str := fmt.Sprintf("merkle branch of transaction %v rebuilds "+
+			"merkle root %v, but block header indicates %v", txHash,
+			root, p.Header.MerkleRoot)
+		return messageError("MerkleProof.Verify", str)
+	}
+
+	return nil
+}
+
+// NewMerkleProof returns a merkle proof for the transaction at the passed
+// index of the passed block.
+func NewMerkleProof(block *MsgBlock, index uint32) (*MerkleProof, error) {
+	txHashes, err := block.TxShas()
+	if err != nil {
+		return nil, err
+	}
+
+	_, store := BuildMerkleTreeStore(txHashes)
+	branch, err := MerkleBranch(store, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MerkleProof{
+		Header: block.Header,
+		Tx:     block.Transactions[index],
+		Branch: branch,
+		Index:  index,
+	}, nil
+}
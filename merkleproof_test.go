@@ -0,0 +1,264 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// merkleProofTestChain returns the bloom test block modified to build on the
+// genesis block of a header chain in which it is followed by two more blocks.
+// The chain also has a side branch of three blocks from the genesis block.
+// The hashes of the genesis block, the tip and the tip of the side branch are
+// returned along with the block and the chain.
+func merkleProofTestChain(t *testing.T) (*rddwire.MsgBlock, *rddwire.HeaderChain,
+	*rddwire.ShaHash, *rddwire.ShaHash, *rddwire.ShaHash) {
+
+	genesis := headerChainTestHeader(&rddwire.ShaHash{}, 1, 0x207fffff, 0)
+	genesisHash, _ := genesis.BlockSha()
+	chain := rddwire.NewHeaderChain(headerChainTestParams(genesis, 100),
+		rddwire.NewMedianTime())
+
+	block := decodeBloomTestBlock(t)
+	block.Header = rddwire.BlockHeader{
+		Version:    1,
+		PrevBlock:  genesisHash,
+		MerkleRoot: block.Header.MerkleRoot,
+		Timestamp:  time.Unix(1400000060, 0),
+		Bits:       0x207fffff,
+	}
+	for {
+		_, err := rddwire.CheckProofOfWork(&block.Header, 1,
+			&rddwire.SimNetParams)
+		if err == nil {
+			break
+		}
+		block.Header.Nonce++
+	}
+	blockHash, _ := block.BlockSha()
+
+	headers := []*rddwire.BlockHeader{&block.Header}
+	headers = append(headers, headerChainTestBranch(&blockHash, 1,
+		0x207fffff, 2, 2)...)
+	side := headerChainTestBranch(&genesisHash, 1, 0x207fffff, 10, 3)
+	for _, header := range append(headers, side...) {
+		if err := chain.AddHeader(header); err != nil {
+			t.Fatalf("AddHeader: %v", err)
+		}
+	}
+
+	tipHash, _ := headers[2].BlockSha()
+	sideHash, _ := side[2].BlockSha()
+	return block, chain, &genesisHash, &tipHash, &sideHash
+}
+
+// TestMerkleProof tests creating, serializing and verifying merkle proofs for
+// every transaction of a block.
+func TestMerkleProof(t *testing.T) {
+	block, chain, _, tipHash, _ := merkleProofTestChain(t)
+	blockHash, _ := block.BlockSha()
+
+	t.Logf("Running %d tests", len(block.Transactions))
+	for i := range block.Transactions {
+		proof, err := rddwire.NewMerkleProof(block, uint32(i))
+		if err != nil {
+			t.Errorf("NewMerkleProof #%d: %v", i, err)
+			continue
+		}
+		if err := proof.Verify(chain, tipHash); err != nil {
+			t.Errorf("Verify #%d: %v", i, err)
+			continue
+		}
+		if err := proof.Verify(chain, &blockHash); err != nil {
+			t.Errorf("Verify #%d: proof block as trusted block %v",
+				i, err)
+			continue
+		}
+
+		var buf bytes.Buffer
+		err = proof.Serialize(&buf)
+		if err != nil {
+			t.Errorf("Serialize #%d: %v", i, err)
+			continue
+		}
+		if buf.Len() != proof.SerializeSize() {
+			t.Errorf("SerializeSize #%d: got %d, want %d", i,
+				proof.SerializeSize(), buf.Len())
+			continue
+		}
+
+		var decoded rddwire.MerkleProof
+		err = decoded.Deserialize(&buf)
+		if err != nil {
+			t.Errorf("Deserialize #%d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&decoded, proof) {
+			t.Errorf("Deserialize #%d\n got: %s want: %s", i,
+				spew.Sdump(&decoded), spew.Sdump(proof))
+			continue
+		}
+		if err := decoded.Verify(chain, tipHash); err != nil {
+			t.Errorf("Verify #%d: decoded proof %v", i, err)
+			continue
+		}
+	}
+}
+
+// TestMerkleProofVerifyErrors performs negative tests against verifying
+// merkle proofs to ensure proofs which don't show a transaction is included in
+// a block of the trusted chain are rejected.
+func TestMerkleProofVerifyErrors(t *testing.T) {
+	block, chain, genesisHash, tipHash, sideHash := merkleProofTestChain(t)
+	base, err := rddwire.NewMerkleProof(block, 3)
+	if err != nil {
+		t.Fatalf("NewMerkleProof: %v", err)
+	}
+
+	// copyProof returns a copy of the base proof which may be modified.
+	copyProof := func() *rddwire.MerkleProof {
+		proof := *base
+		proof.Branch = append([]rddwire.ShaHash{}, base.Branch...)
+		return &proof
+	}
+
+	wrongBlock := copyProof()
+	wrongBlock.Header.Nonce++
+
+	wrongTx := copyProof()
+	wrongTx.Tx = block.Transactions[4]
+
+	wrongIndex := copyProof()
+	wrongIndex.Index = 2
+
+	indexOutOfRange := copyProof()
+	indexOutOfRange.Index = 1 << uint(len(base.Branch))
+
+	wrongBranch := copyProof()
+	wrongBranch.Branch[1] = wrongBranch.Branch[0]
+
+	// The last transaction of the block is at an even index of an odd
+	// number of leaves, so its merkle branch also rebuilds the merkle root
+	// for the next index where no transaction exists.
+	lastIndex := uint32(len(block.Transactions) - 1)
+	phantomIndex, err := rddwire.NewMerkleProof(block, lastIndex)
+	if err != nil {
+		t.Fatalf("NewMerkleProof: %v", err)
+	}
+	phantomIndex.Index = lastIndex + 1
+
+	shortBranch := copyProof()
+	shortBranch.Branch = shortBranch.Branch[:len(shortBranch.Branch)-1]
+	shortBranch.Index = 1
+
+	tests := []struct {
+		name  string               // Test description
+		proof *rddwire.MerkleProof // Proof to verify
+		hash  *rddwire.ShaHash     // Trusted block hash
+	}{
+		{"unknown trusted block", base, &rddwire.ShaHash{}},
+		{"trusted block below proof block", base, genesisHash},
+		{"trusted block on other branch", base, sideHash},
+		{"unknown proof block", wrongBlock, tipHash},
+		{"wrong tx", wrongTx, tipHash},
+		{"wrong index", wrongIndex, tipHash},
+		{"index out of range", indexOutOfRange, tipHash},
+		{"wrong branch", wrongBranch, tipHash},
+		{"index past last transaction", phantomIndex, tipHash},
+		{"short branch", shortBranch, tipHash},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		err := test.proof.Verify(chain, test.hash)
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			t.Errorf("Verify %s: wrong error got: %v, want: %T",
+				test.name, err, &rddwire.MessageError{})
+			continue
+		}
+	}
+
+	// Ensure proofs for transactions which aren't in the block can't be
+	// created.
+	_, err = rddwire.NewMerkleProof(block, uint32(len(block.Transactions)))
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("NewMerkleProof: wrong error got: %v, want: %T", err,
+			&rddwire.MessageError{})
+	}
+}
+
+// TestMerkleProofSerializeErrors performs negative tests against serializing
+// and deserializing merkle proofs to confirm error paths work correctly.
+func TestMerkleProofSerializeErrors(t *testing.T) {
+	proof, err := rddwire.NewMerkleProof(&blockOne, 0)
+	if err != nil {
+		t.Fatalf("NewMerkleProof: %v", err)
+	}
+	proof.Branch = []rddwire.ShaHash{{0x01}, {0x02}}
+	var buf bytes.Buffer
+	if err := proof.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	proofBytes := buf.Bytes()
+	txLen := blockOne.Transactions[0].SerializeSize()
+
+	// Proof with a merkle branch that is forced to have too many hashes.
+	tooManyHashes := make([]rddwire.ShaHash, 33)
+	tooManyProof := *proof
+	tooManyProof.Branch = tooManyHashes
+	tooManyBytes := append([]byte{}, proofBytes[:80+txLen]...)
+	tooManyBytes = append(tooManyBytes, 0x21)
+
+	tests := []struct {
+		proof    *rddwire.MerkleProof // Proof to encode
+		buf      []byte               // Serialized data
+		max      int                  // Max size of fixed buffer to induce errors
+		writeErr error                // Expected write error
+		readErr  error                // Expected read error
+	}{
+		// Force error in header.
+		{proof, proofBytes, 0, io.ErrShortWrite, io.EOF},
+		// Force error in tx.
+		{proof, proofBytes, 80, io.ErrShortWrite, io.EOF},
+		// Force error in num branch hashes.
+		{proof, proofBytes, 80 + txLen, io.ErrShortWrite, io.EOF},
+		// Force error in branch hashes.
+		{proof, proofBytes, 81 + txLen, io.ErrShortWrite, io.EOF},
+		// Force error in index.
+		{proof, proofBytes, 145 + txLen, io.ErrShortWrite, io.EOF},
+		// Force error with too many branch hashes.
+		{&tooManyProof, tooManyBytes, len(tooManyBytes),
+			&rddwire.MessageError{}, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Serialize the merkle proof.
+		w := newFixedWriter(test.max)
+		err := test.proof.Serialize(w)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("Serialize #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// Deserialize the merkle proof.
+		var decoded rddwire.MerkleProof
+		r := newFixedReader(test.max, test.buf)
+		err = decoded.Deserialize(r)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("Deserialize #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+	}
+}
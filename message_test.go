@@ -75,6 +75,26 @@ func TestMessage(t *testing.T) {
 	bh := rddwire.NewBlockHeader(&rddwire.ShaHash{}, &rddwire.ShaHash{}, 0, 0)
 	msgMerkleBlock := rddwire.NewMsgMerkleBlock(bh)
 	msgReject := rddwire.NewMsgReject("block", rddwire.RejectDuplicate, "duplicate block")
+	msgSendHeaders := rddwire.NewMsgSendHeaders()
+	msgSendCmpct := rddwire.NewMsgSendCmpct(true, rddwire.CmpctBlockVersion)
+	msgCmpctBlock := rddwire.NewMsgCmpctBlock(&blockOne, 123123)
+	msgGetBlockTxn := rddwire.NewMsgGetBlockTxn(&rddwire.ShaHash{}, []uint32{1})
+	msgBlockTxn := rddwire.NewMsgBlockTxn(&rddwire.ShaHash{})
+	msgFeeFilter := rddwire.NewMsgFeeFilter(123456)
+	msgAddrV2 := rddwire.NewMsgAddrV2()
+	msgSendAddrV2 := rddwire.NewMsgSendAddrV2()
+	msgGetCFilters := rddwire.NewMsgGetCFilters(rddwire.GCSFilterRegular,
+		0, &rddwire.ShaHash{})
+	msgCFilter := rddwire.NewMsgCFilter(rddwire.GCSFilterRegular,
+		&rddwire.ShaHash{}, []byte("payload"))
+	msgGetCFHeaders := rddwire.NewMsgGetCFHeaders(rddwire.GCSFilterRegular,
+		0, &rddwire.ShaHash{})
+	msgCFHeaders := rddwire.NewMsgCFHeaders()
+	msgGetCFCheckpt := rddwire.NewMsgGetCFCheckpt(rddwire.GCSFilterRegular,
+		&rddwire.ShaHash{})
+	msgCFCheckpt := rddwire.NewMsgCFCheckpt(rddwire.GCSFilterRegular,
+		&rddwire.ShaHash{})
+	msgWtxidRelay := rddwire.NewMsgWtxidRelay()
 
 	tests := []struct {
 		in     rddwire.Message    // Value to encode
@@ -104,6 +124,21 @@ func TestMessage(t *testing.T) {
 		{msgFilterLoad, msgFilterLoad, pver, rddwire.MainNet, 35},
 		{msgMerkleBlock, msgMerkleBlock, pver, rddwire.MainNet, 110},
 		{msgReject, msgReject, pver, rddwire.MainNet, 79},
+		{msgSendHeaders, msgSendHeaders, pver, rddwire.MainNet, 24},
+		{msgSendCmpct, msgSendCmpct, pver, rddwire.MainNet, 33},
+		{msgCmpctBlock, msgCmpctBlock, pver, rddwire.MainNet, 249},
+		{msgGetBlockTxn, msgGetBlockTxn, pver, rddwire.MainNet, 58},
+		{msgBlockTxn, msgBlockTxn, pver, rddwire.MainNet, 57},
+		{msgFeeFilter, msgFeeFilter, pver, rddwire.MainNet, 32},
+		{msgAddrV2, msgAddrV2, pver, rddwire.MainNet, 25},
+		{msgSendAddrV2, msgSendAddrV2, pver, rddwire.MainNet, 24},
+		{msgGetCFilters, msgGetCFilters, pver, rddwire.MainNet, 61},
+		{msgCFilter, msgCFilter, pver, rddwire.MainNet, 65},
+		{msgGetCFHeaders, msgGetCFHeaders, pver, rddwire.MainNet, 61},
+		{msgCFHeaders, msgCFHeaders, pver, rddwire.MainNet, 90},
+		{msgGetCFCheckpt, msgGetCFCheckpt, pver, rddwire.MainNet, 57},
+		{msgCFCheckpt, msgCFCheckpt, pver, rddwire.MainNet, 58},
+		{msgWtxidRelay, msgWtxidRelay, pver, rddwire.MainNet, 24},
 	}
 
 	t.Logf("Running %d tests", len(tests))
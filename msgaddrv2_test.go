@@ -0,0 +1,312 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestAddrV2 tests the MsgAddrV2 API.
+func TestAddrV2(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "addrv2"
+	msg := rddwire.NewMsgAddrV2()
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgAddrV2: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	// Num addresses (varInt) + max allowed addresses.
+	wantPayload := uint32(531009)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure max payload is zero for protocol versions before the message
+	// existed.
+	oldPver := rddwire.AddrV2Version - 1
+	maxPayload = msg.MaxPayloadLength(oldPver)
+	if maxPayload != 0 {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", oldPver,
+			maxPayload, 0)
+	}
+
+	// Ensure NetAddresses are added properly.
+	na, err := rddwire.NewNetAddressV2(rddwire.AddrNetIPv4,
+		[]byte{0x7f, 0x00, 0x00, 0x01}, 45444, rddwire.SFNodeNetwork)
+	if err != nil {
+		t.Errorf("NewNetAddressV2: %v", err)
+	}
+	err = msg.AddAddress(na)
+	if err != nil {
+		t.Errorf("AddAddress: %v", err)
+	}
+	if msg.AddrList[0] != na {
+		t.Errorf("AddAddress: wrong address added - got %v, want %v",
+			spew.Sprint(msg.AddrList[0]), spew.Sprint(na))
+	}
+
+	// Ensure the address list is cleared properly.
+	msg.ClearAddresses()
+	if len(msg.AddrList) != 0 {
+		t.Errorf("ClearAddresses: address list is not empty - "+
+			"got %v [%v], want %v", len(msg.AddrList),
+			spew.Sprint(msg.AddrList[0]), 0)
+	}
+
+	// Ensure adding more than the max allowed addresses per message returns
+	// error.
+	for i := 0; i < rddwire.MaxAddrPerMsg+1; i++ {
+		err = msg.AddAddress(na)
+	}
+	if err == nil {
+		t.Errorf("AddAddress: expected error on too many addresses " +
+			"not received")
+	}
+	err = msg.AddAddresses(na)
+	if err == nil {
+		t.Errorf("AddAddresses: expected error on too many addresses " +
+			"not received")
+	}
+
+	// Ensure encode and decode fail for protocol versions before the
+	// message existed.
+	var buf bytes.Buffer
+	msg.ClearAddresses()
+	err = msg.BtcEncode(&buf, oldPver)
+	if err == nil {
+		t.Errorf("encode of MsgAddrV2 passed for old protocol "+
+			"version %v", oldPver)
+	}
+	err = msg.BtcDecode(bytes.NewReader([]byte{0x00}), oldPver)
+	if err == nil {
+		t.Errorf("decode of MsgAddrV2 passed for old protocol "+
+			"version %v", oldPver)
+	}
+}
+
+// TestAddrV2Wire tests the MsgAddrV2 wire encode and decode for various
+// numbers of addresses and networks.
+func TestAddrV2Wire(t *testing.T) {
+	// A couple of NetAddressV2s to use for testing.
+	na := &rddwire.NetAddressV2{
+		Timestamp: time.Unix(0x495fab29, 0), // 2009-01-03 12:15:05 -0600 CST
+		Services:  rddwire.SFNodeNetwork,
+		NetworkID: rddwire.AddrNetIPv4,
+		Addr:      []byte{0x7f, 0x00, 0x00, 0x01},
+		Port:      45444,
+	}
+	na2 := &rddwire.NetAddressV2{
+		Timestamp: time.Unix(0x495fab29, 0), // 2009-01-03 12:15:05 -0600 CST
+		Services:  rddwire.SFNodeNetwork,
+		NetworkID: rddwire.AddrNetTorV2,
+		Addr: []byte{
+			0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a,
+		},
+		Port: 45445,
+	}
+
+	// Empty address message.
+	noAddr := rddwire.NewMsgAddrV2()
+	noAddrEncoded := []byte{
+		0x00, // Varint for number of addresses
+	}
+
+	// Address message with multiple addresses.
+	multiAddr := rddwire.NewMsgAddrV2()
+	multiAddr.AddAddresses(na, na2)
+	multiAddrEncoded := []byte{
+		0x02,                   // Varint for number of addresses
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,                   // Varint for services
+		0x01,                   // Network ID (IPv4)
+		0x04,                   // Varint for address length
+		0x7f, 0x00, 0x00, 0x01, // IP 127.0.0.1
+		0xb1, 0x84, // Port 45444 in big-endian
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01, // Varint for services
+		0x03, // Network ID (TorV2)
+		0x0a, // Varint for address length
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0a, // Tor v2 address
+		0xb1, 0x85, // Port 45445 in big-endian
+	}
+
+	tests := []struct {
+		in   *rddwire.MsgAddrV2 // Message to encode
+		out  *rddwire.MsgAddrV2 // Expected decoded message
+		buf  []byte             // Wire encoding
+		pver uint32             // Protocol version for wire encoding
+	}{
+		// Latest protocol version with no addresses.
+		{
+			noAddr,
+			noAddr,
+			noAddrEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Latest protocol version with multiple addresses.
+		{
+			multiAddr,
+			multiAddr,
+			multiAddrEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Protocol version AddrV2Version with multiple addresses.
+		{
+			multiAddr,
+			multiAddr,
+			multiAddrEncoded,
+			rddwire.AddrV2Version,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgAddrV2
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestAddrV2WireErrors performs negative tests against wire encode and decode
+// of MsgAddrV2 to confirm error paths work correctly.
+func TestAddrV2WireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	pverNoAddrV2 := rddwire.AddrV2Version - 1
+	rddwireErr := &rddwire.MessageError{}
+
+	na := &rddwire.NetAddressV2{
+		Timestamp: time.Unix(0x495fab29, 0), // 2009-01-03 12:15:05 -0600 CST
+		Services:  rddwire.SFNodeNetwork,
+		NetworkID: rddwire.AddrNetIPv4,
+		Addr:      []byte{0x7f, 0x00, 0x00, 0x01},
+		Port:      45444,
+	}
+
+	// Address message with a single address.
+	baseAddr := rddwire.NewMsgAddrV2()
+	baseAddr.AddAddress(na)
+	baseAddrEncoded := []byte{
+		0x01,                   // Varint for number of addresses
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,                   // Varint for services
+		0x01,                   // Network ID (IPv4)
+		0x04,                   // Varint for address length
+		0x7f, 0x00, 0x00, 0x01, // IP 127.0.0.1
+		0xb1, 0x84, // Port 45444 in big-endian
+	}
+
+	// Message that forces an error by having more than the max allowed
+	// addresses.
+	maxAddr := rddwire.NewMsgAddrV2()
+	for i := 0; i < rddwire.MaxAddrPerMsg; i++ {
+		maxAddr.AddAddress(na)
+	}
+	maxAddr.AddrList = append(maxAddr.AddrList, na)
+	maxAddrEncoded := []byte{
+		0xfd, 0xe9, 0x03, // Varint for number of addresses (1001)
+	}
+
+	tests := []struct {
+		in       *rddwire.MsgAddrV2 // Value to encode
+		buf      []byte             // Wire encoding
+		pver     uint32             // Protocol version for wire encoding
+		max      int                // Max size of fixed buffer to induce errors
+		writeErr error              // Expected write error
+		readErr  error              // Expected read error
+	}{
+		// Latest protocol version with intentional read/write errors.
+		// Force error in addresses count
+		{baseAddr, baseAddrEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in address list.
+		{baseAddr, baseAddrEncoded, pver, 1, io.ErrShortWrite, io.EOF},
+		// Force error with greater than max addresses.
+		{maxAddr, maxAddrEncoded, pver, 3, rddwireErr, rddwireErr},
+		// Force error due to unsupported protocol version.
+		{baseAddr, baseAddrEncoded, pverNoAddrV2, 1, rddwireErr, rddwireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BtcEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgAddrV2
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BtcDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
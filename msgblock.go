@@ -118,7 +118,8 @@ func (msg *MsgBlock) Deserialize(r io.Reader) error {
 
 // DeserializeTxLoc decodes r in the same manner Deserialize does, but it takes
 // a byte buffer instead of a generic reader and returns a slice containing the start and length of
-// each transaction within the raw data that is being deserialized.
+// each transaction within the raw data that is being deserialized.  The
+// locations of transactions with witness data include the witness data.
 func (msg *MsgBlock) DeserializeTxLoc(r *bytes.Buffer) ([]TxLoc, error) {
 	fullLen := r.Len()
 
@@ -166,7 +167,15 @@ func (msg *MsgBlock) DeserializeTxLoc(r *bytes.Buffer) ([]TxLoc, error) {
 // This is part of the Message interface implementation.
 // See Serialize for encoding blocks to be stored to disk, such as in a
 // database, as opposed to encoding blocks for the wire.
+//
+// Transactions with witness data are encoded with their witness data.
 func (msg *MsgBlock) BtcEncode(w io.Writer, pver uint32) error {
+	return msg.btcEncode(w, pver, true)
+}
+
+// btcEncode encodes the receiver to w using the Reddcoin protocol encoding
+// either with or without the witness data of its transactions.
+func (msg *MsgBlock) btcEncode(w io.Writer, pver uint32, witness bool) error {
 	err := writeBlockHeader(w, pver, &msg.Header)
 	if err != nil {
 		return err
@@ -178,7 +187,7 @@ func (msg *MsgBlock) BtcEncode(w io.Writer, pver uint32) error {
 	}
 
 	for _, tx := range msg.Transactions {
-		err = tx.BtcEncode(w, pver)
+		err = tx.btcEncode(w, pver, witness && tx.HasWitness())
 		if err != nil {
 			return err
 		}
@@ -210,8 +219,14 @@ func (msg *MsgBlock) Serialize(w io.Writer) error {
 	return msg.BtcEncode(w, 0)
 }
 
+// SerializeNoWitness encodes the block to w in the same manner as Serialize,
+// but strips the witness data from all of its transactions.
+func (msg *MsgBlock) SerializeNoWitness(w io.Writer) error {
+	return msg.btcEncode(w, 0, false)
+}
+
 // SerializeSize returns the number of bytes it would take to serialize the
-// the block.
+// the block including the witness data of its transactions.
 func (msg *MsgBlock) SerializeSize() int {
 	// Block header bytes + Serialized varint size for the number of
 	// transactions.
@@ -228,6 +243,24 @@ func (msg *MsgBlock) SerializeSize() int {
 	return n
 }
 
+// SerializeSizeStripped returns the number of bytes it would take to serialize
+// the block without the witness data of its transactions.
+func (msg *MsgBlock) SerializeSizeStripped() int {
+	// Block header bytes + Serialized varint size for the number of
+	// transactions.
+	n := blockHeaderLen + VarIntSerializeSize(uint64(len(msg.Transactions)))
+
+	for _, tx := range msg.Transactions {
+		n += tx.SerializeSizeStripped()
+	}
+
+	if msg.Header.Version > PowBlockVersion {
+		n += VarIntSerializeSize(uint64(len(msg.Signature))) + len(msg.Signature)
+	}
+
+	return n
+}
+
 // Command returns the protocol command string for the message.  This is part
 // of the Message interface implementation.
 func (msg *MsgBlock) Command() string {
@@ -260,6 +293,78 @@ func (msg *MsgBlock) TxShas() ([]ShaHash, error) {
 	return shaList, nil
 }
 
+// IsProofOfStake returns whether the block is a PoSV block.  PoSV blocks have
+// a version greater than PowBlockVersion and a coinstake as their second
+// transaction.
+func (msg *MsgBlock) IsProofOfStake() bool {
+	return msg.Header.Version > PowBlockVersion &&
+		len(msg.Transactions) > 1 && msg.Transactions[1].IsCoinStake()
+}
+
+// IsProofOfWork returns whether the block is a proof-of-work block, which is
+// any block that is not a PoSV block.
+func (msg *MsgBlock) IsProofOfWork() bool {
+	return !msg.IsProofOfStake()
+}
+
+// CheckStructure ensures the coinbase and coinstake transactions of the block
+// are where Reddcoin requires them to be.  The first transaction must be the
+// only coinbase, and the coinstake of a PoSV block must be the second
+// transaction and the only coinstake.  The coinbase of a PoSV block must have
+// a single empty output since the reward is claimed by the coinstake.
+//
+// A RuleError whose ErrorCode identifies the violated rule is returned when
+// the block doesn't meet these requirements.
+func (msg *MsgBlock) CheckStructure() error {
+	if err := msg.checkStructure(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkStructure performs the checks of CheckStructure and returns a RuleError
+// which identifies the violated rule, so it can be shared with
+// CheckBlockSanity.
+func (msg *MsgBlock) checkStructure() *RuleError {
+	if len(msg.Transactions) == 0 {
+		str := "block does not contain any transactions"
+		return ruleError(ErrNoTransactions, str)
+	}
+
+	if !msg.Transactions[0].IsCoinBase() {
+		str := "first transaction in block is not a coinbase"
+		return ruleError(ErrFirstTxNotCoinbase, str)
+	}
+
+	isProofOfStake := msg.IsProofOfStake()
+	for i, tx := range msg.Transactions[1:] {
+		if tx.IsCoinBase() {
+			str := fmt.Sprintf("block contains second coinbase at "+
+				"index %d", i+1)
+			return ruleError(ErrMultipleCoinbases, str)
+		}
+
+		// Only the second transaction of a PoSV block may be a
+		// coinstake.
+		if tx.IsCoinStake() && (i != 0 || !isProofOfStake) {
+			str := fmt.Sprintf("block contains coinstake in wrong "+
+				"position at index %d", i+1)
+			return ruleError(ErrCoinStakeMisplaced, str)
+		}
+	}
+
+	if isProofOfStake {
+		coinbase := msg.Transactions[0]
+		if len(coinbase.TxOut) != 1 || !coinbase.TxOut[0].isEmpty() {
+			str := "coinbase output not empty for proof-of-stake " +
+				"block"
+			return ruleError(ErrCoinbaseNotEmpty, str)
+		}
+	}
+
+	return nil
+}
+
 // NewMsgBlock returns a new Reddcoin block message that conforms to the
 // Message interface.  See MsgBlock for details.
 func NewMsgBlock(blockHeader *BlockHeader) *MsgBlock {
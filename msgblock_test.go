@@ -478,6 +478,218 @@ func TestBlockSerializeSize(t *testing.T) {
 	}
 }
 
+// TestBlockWitness tests the MsgBlock encode and decode of blocks which contain
+// transactions with witness data.
+func TestBlockWitness(t *testing.T) {
+	block := rddwire.NewMsgBlock(&blockOne.Header)
+	block.AddTransaction(multiTx)
+	block.AddTransaction(witnessTx)
+
+	// Serialize the block and ensure the size matches.
+	var buf bytes.Buffer
+	err := block.Serialize(&buf)
+	if err != nil {
+		t.Errorf("Serialize: %v", err)
+		return
+	}
+	if size := block.SerializeSize(); size != buf.Len() {
+		t.Errorf("SerializeSize: got %d, want %d", size, buf.Len())
+	}
+	serialized := buf.Bytes()
+
+	// Deserialize the block and ensure the witness data was decoded along
+	// with the location of each transaction.
+	var msg rddwire.MsgBlock
+	txLocs, err := msg.DeserializeTxLoc(bytes.NewBuffer(serialized))
+	if err != nil {
+		t.Errorf("DeserializeTxLoc: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(msg.Transactions, block.Transactions) {
+		t.Errorf("DeserializeTxLoc\n got: %s want: %s",
+			spew.Sdump(msg.Transactions),
+			spew.Sdump(block.Transactions))
+	}
+	wantTxLocs := []rddwire.TxLoc{
+		{TxStart: 81, TxLen: len(multiTxEncoded)},
+		{TxStart: 81 + len(multiTxEncoded), TxLen: len(witnessTxEncoded)},
+	}
+	if !reflect.DeepEqual(txLocs, wantTxLocs) {
+		t.Errorf("DeserializeTxLoc\n got: %s want: %s",
+			spew.Sdump(txLocs), spew.Sdump(wantTxLocs))
+	}
+	witnessLoc := txLocs[1]
+	txBytes := serialized[witnessLoc.TxStart : witnessLoc.TxStart+witnessLoc.TxLen]
+	if !bytes.Equal(txBytes, witnessTxEncoded) {
+		t.Errorf("DeserializeTxLoc: wrong tx bytes\n got: %s want: %s",
+			spew.Sdump(txBytes), spew.Sdump(witnessTxEncoded))
+	}
+
+	// Serialize the block without witness data and ensure the size
+	// matches and the transaction hashes are unchanged.
+	buf.Reset()
+	err = block.SerializeNoWitness(&buf)
+	if err != nil {
+		t.Errorf("SerializeNoWitness: %v", err)
+		return
+	}
+	if size := block.SerializeSizeStripped(); size != buf.Len() {
+		t.Errorf("SerializeSizeStripped: got %d, want %d", size,
+			buf.Len())
+	}
+	wantLen := len(serialized) - len(witnessTxEncoded) +
+		len(witnessTxStripped)
+	if buf.Len() != wantLen {
+		t.Errorf("SerializeNoWitness: got %d bytes, want %d",
+			buf.Len(), wantLen)
+	}
+
+	var stripped rddwire.MsgBlock
+	err = stripped.Deserialize(&buf)
+	if err != nil {
+		t.Errorf("Deserialize: %v", err)
+		return
+	}
+	if stripped.Transactions[1].HasWitness() {
+		t.Errorf("SerializeNoWitness: witness data not stripped")
+	}
+	gotShas, _ := stripped.TxShas()
+	wantShas, _ := block.TxShas()
+	if !reflect.DeepEqual(gotShas, wantShas) {
+		t.Errorf("TxShas\n got: %s want: %s", spew.Sdump(gotShas),
+			spew.Sdump(wantShas))
+	}
+}
+
+// TestBlockProofOfStake tests the classification of blocks as PoSV and
+// proof-of-work blocks.
+func TestBlockProofOfStake(t *testing.T) {
+	posvBlock := posvTestBlock([]byte{0x51}, nil)
+
+	// PoSV block with a PoW block version.
+	powVersion := posvTestBlock([]byte{0x51}, nil)
+	powVersion.Header.Version = rddwire.PowBlockVersion
+
+	// Block with the PoSV block version but no coinstake.
+	noCoinStake := posvTestBlock([]byte{0x51}, nil)
+	noCoinStake.Transactions = noCoinStake.Transactions[:1]
+
+	tests := []struct {
+		name           string            // Test description
+		block          *rddwire.MsgBlock // Block to classify
+		isProofOfStake bool              // Expected result
+	}{
+		{"block one", &blockOne, false},
+		{"PoSV block", posvBlock, true},
+		{"PoW block version", powVersion, false},
+		{"no coinstake", noCoinStake, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		got := test.block.IsProofOfStake()
+		if got != test.isProofOfStake {
+			t.Errorf("IsProofOfStake %s: got %v, want %v", test.name,
+				got, test.isProofOfStake)
+			continue
+		}
+		if test.block.IsProofOfWork() == got {
+			t.Errorf("IsProofOfWork %s: got %v, want %v", test.name,
+				!got, got)
+			continue
+		}
+	}
+}
+
+// TestBlockCheckStructure tests that the coinbase and coinstake transactions
+// of blocks are checked to be in the required positions.
+func TestBlockCheckStructure(t *testing.T) {
+	// copyBlock returns a copy of the PoSV test block which may be
+	// modified.
+	copyBlock := func() *rddwire.MsgBlock {
+		block := posvTestBlock([]byte{0x51}, nil)
+		for i, tx := range block.Transactions {
+			block.Transactions[i] = tx.Copy()
+		}
+		return block
+	}
+	posvBlock := copyBlock()
+	coinbase := posvBlock.Transactions[0]
+	coinstake := posvBlock.Transactions[1]
+
+	noTxns := copyBlock()
+	noTxns.Transactions = nil
+
+	noCoinBase := copyBlock()
+	noCoinBase.Transactions = noCoinBase.Transactions[1:]
+
+	secondCoinBase := copyBlock()
+	secondCoinBase.AddTransaction(coinbase)
+
+	secondCoinStake := copyBlock()
+	secondCoinStake.AddTransaction(coinstake)
+
+	coinStakeFirst := copyBlock()
+	coinStakeFirst.Transactions[0] = coinstake
+	coinStakeFirst.Transactions[1] = coinbase
+
+	powCoinStake := copyBlock()
+	powCoinStake.Header.Version = rddwire.PowBlockVersion
+
+	coinbaseNotEmpty := copyBlock()
+	coinbaseNotEmpty.Transactions[0].TxOut[0].Value = 1
+
+	coinbaseTwoOutputs := copyBlock()
+	coinbaseTwoOutputs.Transactions[0].AddTxOut(&rddwire.TxOut{})
+
+	tests := []struct {
+		name  string            // Test description
+		block *rddwire.MsgBlock // Block to check
+		code  rddwire.ErrorCode // Expected error code
+		valid bool              // Whether the block is expected valid
+	}{
+		{"block one", &blockOne, 0, true},
+		{"PoSV block", posvBlock, 0, true},
+		{"no transactions", noTxns, rddwire.ErrNoTransactions, false},
+		{"no coinbase", noCoinBase, rddwire.ErrFirstTxNotCoinbase, false},
+		{"second coinbase", secondCoinBase, rddwire.ErrMultipleCoinbases,
+			false},
+		{"second coinstake", secondCoinStake,
+			rddwire.ErrCoinStakeMisplaced, false},
+		{"coinstake first", coinStakeFirst, rddwire.ErrFirstTxNotCoinbase,
+			false},
+		{"PoW block with coinstake", powCoinStake,
+			rddwire.ErrCoinStakeMisplaced, false},
+		{"coinbase not empty", coinbaseNotEmpty,
+			rddwire.ErrCoinbaseNotEmpty, false},
+		{"coinbase two outputs", coinbaseTwoOutputs,
+			rddwire.ErrCoinbaseNotEmpty, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		err := test.block.CheckStructure()
+		if test.valid {
+			if err != nil {
+				t.Errorf("CheckStructure %s: unexpected error %v",
+					test.name, err)
+			}
+			continue
+		}
+		rerr, ok := err.(*rddwire.RuleError)
+		if !ok {
+			t.Errorf("CheckStructure %s: wrong error got: %v, want: %T",
+				test.name, err, &rddwire.RuleError{})
+			continue
+		}
+		if rerr.ErrorCode != test.code {
+			t.Errorf("CheckStructure %s: wrong error code got: %v, "+
+				"want: %v", test.name, rerr.ErrorCode, test.code)
+			continue
+		}
+	}
+}
+
 var blockOne = rddwire.MsgBlock{
 	Header: rddwire.BlockHeader{
 		Version: 1,
@@ -0,0 +1,138 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"io"
+)
+
+// MsgBlockTxn implements the Message interface and represents a Reddcoin
+// blocktxn message.  It is used to deliver the transactions requested by a
+// getblocktxn message (MsgGetBlockTxn) in the same order as the requested
+// indexes.
+//
+// Use the AddTransaction function to build up the list of transactions.
+//
+// This message was not added until protocol version BIP0152Version.
+type MsgBlockTxn struct {
+	BlockHash    ShaHash
+	Transactions []*MsgTx
+}
+
+// AddTransaction adds a transaction to the message.
+func (msg *MsgBlockTxn) AddTransaction(tx *MsgTx) error {
+	if len(msg.Transactions)+1 > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message [max %v]",
+			maxTxPerBlock)
+		return messageError("MsgBlockTxn.AddTransaction", str)
+	}
+
+	msg.Transactions = append(msg.Transactions, tx)
+	return nil
+}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgBlockTxn) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("blocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgBlockTxn.BtcDecode", str)
+	}
+
+	err := readElement(r, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	// Read num transactions and limit to max.
+	count, err := readVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgBlockTxn.BtcDecode", str)
+	}
+
+	msg.Transactions = make([]*MsgTx, 0, count)
+	for i := uint64(0); i < count; i++ {
+		tx := MsgTx{}
+		err := tx.BtcDecode(r, pver)
+		if err != nil {
+			return err
+		}
+		msg.AddTransaction(&tx)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgBlockTxn) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("blocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgBlockTxn.BtcEncode", str)
+	}
+
+	// Limit to max transactions per message.
+	count := len(msg.Transactions)
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgBlockTxn.BtcEncode", str)
+	}
+
+	err := writeElement(w, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	err = writeVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range msg.Transactions {
+		err = tx.BtcEncode(w, pver)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgBlockTxn) Command() string {
+	return CmdBlockTxn
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgBlockTxn) MaxPayloadLength(pver uint32) uint32 {
+	plen := uint32(0)
+	// The blocktxn message did not exist before protocol version
+	// BIP0152Version.
+	if pver >= BIP0152Version {
+		plen = MaxBlockPayload
+	}
+
+	return plen
+}
+
+// NewMsgBlockTxn returns a new Reddcoin blocktxn message that conforms to the
+// Message interface using the passed block hash.  See MsgBlockTxn for details.
+func NewMsgBlockTxn(blockHash *ShaHash) *MsgBlockTxn {
+	return &MsgBlockTxn{
+		BlockHash:    *blockHash,
+		Transactions: make([]*MsgTx, 0, defaultTxInOutAlloc),
+	}
+}
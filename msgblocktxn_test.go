@@ -0,0 +1,223 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestBlockTxn tests the MsgBlockTxn API.
+func TestBlockTxn(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "blocktxn"
+	msg := rddwire.NewMsgBlockTxn(&mainNetGenesisHash)
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgBlockTxn: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	wantPayload := uint32(1000000)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure transactions are added properly.
+	msg.AddTransaction(multiTx)
+	if !reflect.DeepEqual(msg.Transactions, []*rddwire.MsgTx{multiTx}) {
+		t.Errorf("AddTransaction: wrong transactions - got %v, want %v",
+			spew.Sdump(msg.Transactions), spew.Sdump(multiTx))
+	}
+
+	// Ensure adding more than the max allowed transactions per message
+	// returns error.
+	var err error
+	for i := 0; i < rddwire.MaxTxPerBlock+1; i++ {
+		err = msg.AddTransaction(multiTx)
+	}
+	if err == nil {
+		t.Errorf("AddTransaction: expected error on too many " +
+			"transactions not received")
+	}
+
+	return
+}
+
+// TestBlockTxnWire tests the MsgBlockTxn wire encode and decode for various
+// protocol versions.
+func TestBlockTxnWire(t *testing.T) {
+	// Message with no transactions.
+	noTxns := rddwire.NewMsgBlockTxn(&mainNetGenesisHash)
+	noTxnsEncoded := append(mainNetGenesisHash.Bytes(),
+		0x00, // Varint for number of transactions
+	)
+
+	// Message with a transaction.
+	multiTxns := rddwire.NewMsgBlockTxn(&mainNetGenesisHash)
+	multiTxns.AddTransaction(multiTx)
+	multiTxnsEncoded := append(append(mainNetGenesisHash.Bytes(),
+		0x01, // Varint for number of transactions
+	), multiTxEncoded...)
+
+	tests := []struct {
+		in   *rddwire.MsgBlockTxn // Message to encode
+		out  *rddwire.MsgBlockTxn // Expected decoded message
+		buf  []byte               // Wire encoding
+		pver uint32               // Protocol version for wire encoding
+	}{
+		// Latest protocol version with no transactions.
+		{
+			noTxns,
+			noTxns,
+			noTxnsEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Latest protocol version with a transaction.
+		{
+			multiTxns,
+			multiTxns,
+			multiTxnsEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Protocol version BIP0152Version with a transaction.
+		{
+			multiTxns,
+			multiTxns,
+			multiTxnsEncoded,
+			rddwire.BIP0152Version,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		msg := rddwire.NewMsgBlockTxn(&rddwire.ShaHash{})
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(msg.BlockHash, test.out.BlockHash) ||
+			!reflect.DeepEqual(msg.Transactions, test.out.Transactions) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestBlockTxnWireErrors performs negative tests against wire encode and
+// decode of MsgBlockTxn to confirm error paths work correctly.
+func TestBlockTxnWireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	pverNoBlockTxn := rddwire.BIP0152Version - 1
+	rddwireErr := &rddwire.MessageError{}
+
+	baseBlockTxn := rddwire.NewMsgBlockTxn(&mainNetGenesisHash)
+	baseBlockTxn.AddTransaction(multiTx)
+	baseBlockTxnEncoded := append(append(mainNetGenesisHash.Bytes(),
+		0x01, // Varint for number of transactions
+	), multiTxEncoded...)
+
+	// Message that forces an error by having more than the max allowed
+	// transactions.
+	maxBlockTxn := rddwire.NewMsgBlockTxn(&mainNetGenesisHash)
+	for i := 0; i < rddwire.MaxTxPerBlock; i++ {
+		maxBlockTxn.AddTransaction(multiTx)
+	}
+	maxBlockTxn.Transactions = append(maxBlockTxn.Transactions, multiTx)
+	maxBlockTxnEncoded := append(mainNetGenesisHash.Bytes(),
+		0xfe, 0xff, 0xff, 0xff, 0xff, // Varint for number of transactions
+	)
+
+	tests := []struct {
+		in       *rddwire.MsgBlockTxn // Value to encode
+		buf      []byte               // Wire encoding
+		pver     uint32               // Protocol version for wire encoding
+		max      int                  // Max size of fixed buffer to induce errors
+		writeErr error                // Expected write error
+		readErr  error                // Expected read error
+	}{
+		// Force error in block hash.
+		{baseBlockTxn, baseBlockTxnEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in transaction count.
+		{baseBlockTxn, baseBlockTxnEncoded, pver, 32, io.ErrShortWrite, io.EOF},
+		// Force error in transactions.
+		{baseBlockTxn, baseBlockTxnEncoded, pver, 33, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseBlockTxn, baseBlockTxnEncoded, pverNoBlockTxn, 33, rddwireErr, rddwireErr},
+		// Force error with greater than max transactions.
+		{maxBlockTxn, maxBlockTxnEncoded, pver, 37, rddwireErr, rddwireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BtcEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgBlockTxn
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BtcDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// CFCheckptInterval is the gap (in number of blocks) between each
+	// filter header checkpoint in a cfcheckpt message (MsgCFCheckpt).
+	CFCheckptInterval = 1000
+
+	// MaxCFCheckptsPerMsg is the maximum number of filter header
+	// checkpoints that can be in a single cfcheckpt message.  BIP0157 does
+	// not define a limit since the number of checkpoints depends on the
+	// height of the chain, so this is chosen to allow for chains of up to
+	// 100 million blocks while still preventing memory exhaustion attacks.
+	MaxCFCheckptsPerMsg = 100000
+)
+
+// MsgCFCheckpt implements the Message interface and represents a Reddcoin
+// cfcheckpt message.  It is used to deliver filter header checkpoints in
+// response to a getcfcheckpt message (MsgGetCFCheckpt).  The checkpoints are
+// the filter headers of every CFCheckptInterval'th block up to the block
+// identified by StopHash.
+//
+// Use the AddCFHeader function to build up the list of filter headers.
+type MsgCFCheckpt struct {
+	FilterType    FilterType
+	StopHash      ShaHash
+	FilterHeaders []*ShaHash
+}
+
+// AddCFHeader adds a new filter header checkpoint to the message.
+func (msg *MsgCFCheckpt) AddCFHeader(header *ShaHash) error {
+	if len(msg.FilterHeaders)+1 > MaxCFCheckptsPerMsg {
+		str := fmt.Sprintf("too many filter headers in message [max %v]",
+			MaxCFCheckptsPerMsg)
+		return messageError("MsgCFCheckpt.AddCFHeader", str)
+	}
+
+	msg.FilterHeaders = append(msg.FilterHeaders, header)
+	return nil
+}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCFCheckpt) BtcDecode(r io.Reader, pver uint32) error {
+	err := readElements(r, &msg.FilterType, &msg.StopHash)
+	if err != nil {
+		return err
+	}
+
+	// Read num filter headers and limit to max.
+	count, err := readVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > MaxCFCheckptsPerMsg {
+		str := fmt.Sprintf("too many filter headers for message "+
+			"[count %v, max %v]", count, MaxCFCheckptsPerMsg)
+		return messageError("MsgCFCheckpt.BtcDecode", str)
+	}
+
+	// Create a contiguous slice of hashes to deserialize into in order to
+	// reduce the number of allocations.
+	headers := make([]ShaHash, count)
+	msg.FilterHeaders = make([]*ShaHash, 0, count)
+	for i := uint64(0); i < count; i++ {
+		header := &headers[i]
+		err := readElement(r, header)
+		if err != nil {
+			return err
+		}
+		msg.AddCFHeader(header)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCFCheckpt) BtcEncode(w io.Writer, pver uint32) error {
+	// Limit to max filter headers per message.
+	count := len(msg.FilterHeaders)
+	if count > MaxCFCheckptsPerMsg {
+		str := fmt.Sprintf("too many filter headers for message "+
+			"[count %v, max %v]", count, MaxCFCheckptsPerMsg)
+		return messageError("MsgCFCheckpt.BtcEncode", str)
+	}
+
+	err := writeElements(w, msg.FilterType, &msg.StopHash)
+	if err != nil {
+		return err
+	}
+
+	err = writeVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, header := range msg.FilterHeaders {
+		err := writeElement(w, header)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCFCheckpt) Command() string {
+	return CmdCFCheckpt
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCFCheckpt) MaxPayloadLength(pver uint32) uint32 {
+	// Filter type 1 byte + stop hash + num filter headers (varInt) + max
+	// allowed filter headers.
+	return 1 + HashSize + MaxVarIntPayload +
+		(MaxCFCheckptsPerMsg * HashSize)
+}
+
+// NewMsgCFCheckpt returns a new Reddcoin cfcheckpt message that conforms to
+// the Message interface using the passed parameters.  See MsgCFCheckpt for
+// details.
+func NewMsgCFCheckpt(filterType FilterType, stopHash *ShaHash) *MsgCFCheckpt {
+	return &MsgCFCheckpt{
+		FilterType:    filterType,
+		StopHash:      *stopHash,
+		FilterHeaders: make([]*ShaHash, 0),
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestCFCheckpt tests the MsgCFCheckpt API.
+func TestCFCheckpt(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "cfcheckpt"
+	msg := rddwire.NewMsgCFCheckpt(rddwire.GCSFilterRegular,
+		&mainNetGenesisHash)
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCFCheckpt: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	// Filter type 1 byte + stop hash 32 bytes + num filter headers
+	// (varInt) 9 bytes + max filter headers.
+	wantPayload := uint32(3200042)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure filter headers are added properly.
+	header := &mainNetGenesisMerkleRoot
+	msg.AddCFHeader(header)
+	if msg.FilterHeaders[0] != header {
+		t.Errorf("AddCFHeader: wrong header added - got %v, want %v",
+			spew.Sprint(msg.FilterHeaders[0]), spew.Sprint(header))
+	}
+
+	// Ensure adding more than the max allowed filter headers per message
+	// returns an error.
+	var err error
+	for i := 0; i < rddwire.MaxCFCheckptsPerMsg+1; i++ {
+		err = msg.AddCFHeader(header)
+	}
+	if reflect.TypeOf(err) != reflect.TypeOf(&rddwire.MessageError{}) {
+		t.Errorf("AddCFHeader: expected error on too many filter " +
+			"headers not received")
+	}
+}
+
+// TestCFCheckptWire tests the MsgCFCheckpt wire encode and decode for various
+// numbers of filter headers.
+func TestCFCheckptWire(t *testing.T) {
+	hash := &mainNetGenesisHash
+	header := &mainNetGenesisMerkleRoot
+
+	// Empty cfcheckpt message.
+	noHeaders := rddwire.NewMsgCFCheckpt(rddwire.GCSFilterRegular, hash)
+	noHeadersEncoded := []byte{0x00} // Filter type
+	noHeadersEncoded = append(noHeadersEncoded, hash.Bytes()...)
+	noHeadersEncoded = append(noHeadersEncoded, 0x00) // Varint for count
+
+	// cfcheckpt message with multiple filter headers.
+	multiHeaders := rddwire.NewMsgCFCheckpt(rddwire.GCSFilterRegular, hash)
+	multiHeaders.AddCFHeader(header)
+	multiHeaders.AddCFHeader(hash)
+	multiHeadersEncoded := []byte{0x00} // Filter type
+	multiHeadersEncoded = append(multiHeadersEncoded, hash.Bytes()...)
+	multiHeadersEncoded = append(multiHeadersEncoded, 0x02) // Varint for count
+	multiHeadersEncoded = append(multiHeadersEncoded, header.Bytes()...)
+	multiHeadersEncoded = append(multiHeadersEncoded, hash.Bytes()...)
+
+	tests := []struct {
+		in   *rddwire.MsgCFCheckpt // Message to encode
+		out  *rddwire.MsgCFCheckpt // Expected decoded message
+		buf  []byte                // Wire encoding
+		pver uint32                // Protocol version for wire encoding
+	}{
+		// Latest protocol version with no filter headers.
+		{
+			noHeaders,
+			noHeaders,
+			noHeadersEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Latest protocol version with multiple filter headers.
+		{
+			multiHeaders,
+			multiHeaders,
+			multiHeadersEncoded,
+			rddwire.ProtocolVersion,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgCFCheckpt
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestCFCheckptWireErrors performs negative tests against wire encode and
+// decode of MsgCFCheckpt to confirm error paths work correctly.
+func TestCFCheckptWireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	rddwireErr := &rddwire.MessageError{}
+
+	hash := &mainNetGenesisHash
+	header := &mainNetGenesisMerkleRoot
+
+	baseCFCheckpt := rddwire.NewMsgCFCheckpt(rddwire.GCSFilterRegular, hash)
+	baseCFCheckpt.AddCFHeader(header)
+	baseCFCheckptEncoded := []byte{0x00} // Filter type
+	baseCFCheckptEncoded = append(baseCFCheckptEncoded, hash.Bytes()...)
+	baseCFCheckptEncoded = append(baseCFCheckptEncoded, 0x01) // Varint for count
+	baseCFCheckptEncoded = append(baseCFCheckptEncoded, header.Bytes()...)
+
+	// Message that forces an error by having more than the max allowed
+	// filter headers.
+	maxCFCheckpt := rddwire.NewMsgCFCheckpt(rddwire.GCSFilterRegular, hash)
+	for i := 0; i < rddwire.MaxCFCheckptsPerMsg; i++ {
+		maxCFCheckpt.AddCFHeader(header)
+	}
+	maxCFCheckpt.FilterHeaders = append(maxCFCheckpt.FilterHeaders, header)
+	maxCFCheckptEncoded := make([]byte, 33)
+	maxCFCheckptEncoded = append(maxCFCheckptEncoded,
+		0xfe, 0xa1, 0x86, 0x01, 0x00, // Varint for count (100001)
+	)
+
+	tests := []struct {
+		in       *rddwire.MsgCFCheckpt // Value to encode
+		buf      []byte                // Wire encoding
+		pver     uint32                // Protocol version for wire encoding
+		max      int                   // Max size of fixed buffer to induce errors
+		writeErr error                 // Expected write error
+		readErr  error                 // Expected read error
+	}{
+		// Force error in filter type.
+		{baseCFCheckpt, baseCFCheckptEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in stop hash.
+		{baseCFCheckpt, baseCFCheckptEncoded, pver, 1, io.ErrShortWrite, io.EOF},
+		// Force error in filter header count.
+		{baseCFCheckpt, baseCFCheckptEncoded, pver, 33, io.ErrShortWrite, io.EOF},
+		// Force error in filter headers.
+		{baseCFCheckpt, baseCFCheckptEncoded, pver, 34, io.ErrShortWrite, io.EOF},
+		// Force error with greater than max filter headers.
+		{maxCFCheckpt, maxCFCheckptEncoded, pver, 38, rddwireErr, rddwireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BtcEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgCFCheckpt
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BtcDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
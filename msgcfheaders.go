@@ -0,0 +1,132 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"io"
+)
+
+// MaxCFHeadersPerMsg is the maximum number of committed filter hashes that can
+// be in a single Reddcoin cfheaders message (MsgCFHeaders).
+const MaxCFHeadersPerMsg = 2000
+
+// MsgCFHeaders implements the Message interface and represents a Reddcoin
+// cfheaders message.  It is used to deliver committed filter hashes in
+// response to a getcfheaders message (MsgGetCFHeaders) along with the filter
+// header preceding the requested range so the receiver can derive the filter
+// header for each block.  The maximum number of filter hashes per message is
+// currently 2000.
+//
+// Use the AddCFHash function to build up the list of filter hashes.
+type MsgCFHeaders struct {
+	FilterType       FilterType
+	StopHash         ShaHash
+	PrevFilterHeader ShaHash
+	FilterHashes     []*ShaHash
+}
+
+// AddCFHash adds a new filter hash to the message.
+func (msg *MsgCFHeaders) AddCFHash(hash *ShaHash) error {
+	if len(msg.FilterHashes)+1 > MaxCFHeadersPerMsg {
+		str := fmt.Sprintf("too many filter hashes in message [max %v]",
+			MaxCFHeadersPerMsg)
+		return messageError("MsgCFHeaders.AddCFHash", str)
+	}
+
+	msg.FilterHashes = append(msg.FilterHashes, hash)
+	return nil
+}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCFHeaders) BtcDecode(r io.Reader, pver uint32) error {
+	err := readElements(r, &msg.FilterType, &msg.StopHash,
+		&msg.PrevFilterHeader)
+	if err != nil {
+		return err
+	}
+
+	// Read num filter hashes and limit to max.
+	count, err := readVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > MaxCFHeadersPerMsg {
+		str := fmt.Sprintf("too many filter hashes for message "+
+			"[count %v, max %v]", count, MaxCFHeadersPerMsg)
+		return messageError("MsgCFHeaders.BtcDecode", str)
+	}
+
+	// Create a contiguous slice of hashes to deserialize into in order to
+	// reduce the number of allocations.
+	hashes := make([]ShaHash, count)
+	msg.FilterHashes = make([]*ShaHash, 0, count)
+	for i := uint64(0); i < count; i++ {
+		hash := &hashes[i]
+		err := readElement(r, hash)
+		if err != nil {
+			return err
+		}
+		msg.AddCFHash(hash)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCFHeaders) BtcEncode(w io.Writer, pver uint32) error {
+	// Limit to max filter hashes per message.
+	count := len(msg.FilterHashes)
+	if count > MaxCFHeadersPerMsg {
+		str := fmt.Sprintf("too many filter hashes for message "+
+			"[count %v, max %v]", count, MaxCFHeadersPerMsg)
+		return messageError("MsgCFHeaders.BtcEncode", str)
+	}
+
+	err := writeElements(w, msg.FilterType, &msg.StopHash,
+		&msg.PrevFilterHeader)
+	if err != nil {
+		return err
+	}
+
+	err = writeVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range msg.FilterHashes {
+		err := writeElement(w, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCFHeaders) Command() string {
+	return CmdCFHeaders
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCFHeaders) MaxPayloadLength(pver uint32) uint32 {
+	// Filter type 1 byte + stop hash + previous filter header + num filter
+	// hashes (varInt) + max allowed filter hashes.
+	return 1 + HashSize + HashSize + MaxVarIntPayload +
+		(MaxCFHeadersPerMsg * HashSize)
+}
+
+// NewMsgCFHeaders returns a new Reddcoin cfheaders message that conforms to
+// the Message interface.  See MsgCFHeaders for details.
+func NewMsgCFHeaders() *MsgCFHeaders {
+	return &MsgCFHeaders{
+		FilterHashes: make([]*ShaHash, 0, MaxCFHeadersPerMsg),
+	}
+}
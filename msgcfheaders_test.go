@@ -0,0 +1,236 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestCFHeaders tests the MsgCFHeaders API.
+func TestCFHeaders(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "cfheaders"
+	msg := rddwire.NewMsgCFHeaders()
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCFHeaders: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	// Filter type 1 byte + stop hash 32 bytes + previous filter header 32
+	// bytes + num filter hashes (varInt) 9 bytes + max filter hashes.
+	wantPayload := uint32(64074)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure filter hashes are added properly.
+	hash := &mainNetGenesisMerkleRoot
+	msg.AddCFHash(hash)
+	if msg.FilterHashes[0] != hash {
+		t.Errorf("AddCFHash: wrong hash added - got %v, want %v",
+			spew.Sprint(msg.FilterHashes[0]), spew.Sprint(hash))
+	}
+
+	// Ensure adding more than the max allowed filter hashes per message
+	// returns an error.
+	var err error
+	for i := 0; i < rddwire.MaxCFHeadersPerMsg+1; i++ {
+		err = msg.AddCFHash(hash)
+	}
+	if reflect.TypeOf(err) != reflect.TypeOf(&rddwire.MessageError{}) {
+		t.Errorf("AddCFHash: expected error on too many filter " +
+			"hashes not received")
+	}
+}
+
+// TestCFHeadersWire tests the MsgCFHeaders wire encode and decode for various
+// numbers of filter hashes.
+func TestCFHeadersWire(t *testing.T) {
+	prevHeader := &mainNetGenesisMerkleRoot
+	hash := &mainNetGenesisHash
+
+	// Empty cfheaders message.
+	noHashes := rddwire.NewMsgCFHeaders()
+	noHashes.StopHash = *hash
+	noHashes.PrevFilterHeader = *prevHeader
+	noHashesEncoded := []byte{0x00} // Filter type
+	noHashesEncoded = append(noHashesEncoded, hash.Bytes()...)
+	noHashesEncoded = append(noHashesEncoded, prevHeader.Bytes()...)
+	noHashesEncoded = append(noHashesEncoded, 0x00) // Varint for count
+
+	// cfheaders message with multiple filter hashes.
+	multiHashes := rddwire.NewMsgCFHeaders()
+	multiHashes.StopHash = *hash
+	multiHashes.PrevFilterHeader = *prevHeader
+	multiHashes.AddCFHash(hash)
+	multiHashes.AddCFHash(prevHeader)
+	multiHashesEncoded := []byte{0x00} // Filter type
+	multiHashesEncoded = append(multiHashesEncoded, hash.Bytes()...)
+	multiHashesEncoded = append(multiHashesEncoded, prevHeader.Bytes()...)
+	multiHashesEncoded = append(multiHashesEncoded, 0x02) // Varint for count
+	multiHashesEncoded = append(multiHashesEncoded, hash.Bytes()...)
+	multiHashesEncoded = append(multiHashesEncoded, prevHeader.Bytes()...)
+
+	tests := []struct {
+		in   *rddwire.MsgCFHeaders // Message to encode
+		out  *rddwire.MsgCFHeaders // Expected decoded message
+		buf  []byte                // Wire encoding
+		pver uint32                // Protocol version for wire encoding
+	}{
+		// Latest protocol version with no filter hashes.
+		{
+			noHashes,
+			noHashes,
+			noHashesEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Latest protocol version with multiple filter hashes.
+		{
+			multiHashes,
+			multiHashes,
+			multiHashesEncoded,
+			rddwire.ProtocolVersion,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgCFHeaders
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestCFHeadersWireErrors performs negative tests against wire encode and
+// decode of MsgCFHeaders to confirm error paths work correctly.
+func TestCFHeadersWireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	rddwireErr := &rddwire.MessageError{}
+
+	prevHeader := &mainNetGenesisMerkleRoot
+	hash := &mainNetGenesisHash
+
+	baseCFHeaders := rddwire.NewMsgCFHeaders()
+	baseCFHeaders.StopHash = *hash
+	baseCFHeaders.PrevFilterHeader = *prevHeader
+	baseCFHeaders.AddCFHash(hash)
+	baseCFHeadersEncoded := []byte{0x00} // Filter type
+	baseCFHeadersEncoded = append(baseCFHeadersEncoded, hash.Bytes()...)
+	baseCFHeadersEncoded = append(baseCFHeadersEncoded, prevHeader.Bytes()...)
+	baseCFHeadersEncoded = append(baseCFHeadersEncoded, 0x01) // Varint for count
+	baseCFHeadersEncoded = append(baseCFHeadersEncoded, hash.Bytes()...)
+
+	// Message that forces an error by having more than the max allowed
+	// filter hashes.
+	maxCFHeaders := rddwire.NewMsgCFHeaders()
+	for i := 0; i < rddwire.MaxCFHeadersPerMsg; i++ {
+		maxCFHeaders.AddCFHash(hash)
+	}
+	maxCFHeaders.FilterHashes = append(maxCFHeaders.FilterHashes, hash)
+	maxCFHeadersEncoded := make([]byte, 65)
+	maxCFHeadersEncoded = append(maxCFHeadersEncoded,
+		0xfd, 0xd1, 0x07, // Varint for count (2001)
+	)
+
+	tests := []struct {
+		in       *rddwire.MsgCFHeaders // Value to encode
+		buf      []byte                // Wire encoding
+		pver     uint32                // Protocol version for wire encoding
+		max      int                   // Max size of fixed buffer to induce errors
+		writeErr error                 // Expected write error
+		readErr  error                 // Expected read error
+	}{
+		// Force error in filter type.
+		{baseCFHeaders, baseCFHeadersEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in stop hash.
+		{baseCFHeaders, baseCFHeadersEncoded, pver, 1, io.ErrShortWrite, io.EOF},
+		// Force error in previous filter header.
+		{baseCFHeaders, baseCFHeadersEncoded, pver, 33, io.ErrShortWrite, io.EOF},
+		// Force error in filter hash count.
+		{baseCFHeaders, baseCFHeadersEncoded, pver, 65, io.ErrShortWrite, io.EOF},
+		// Force error in filter hashes.
+		{baseCFHeaders, baseCFHeadersEncoded, pver, 66, io.ErrShortWrite, io.EOF},
+		// Force error with greater than max filter hashes.
+		{maxCFHeaders, maxCFHeadersEncoded, pver, 68, rddwireErr, rddwireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BtcEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgCFHeaders
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BtcDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
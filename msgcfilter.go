@@ -0,0 +1,114 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"io"
+)
+
+// FilterType identifies the type of a committed block filter as defined by
+// BIP0157 and BIP0158.
+type FilterType uint8
+
+const (
+	// GCSFilterRegular is the regular (basic) filter type which commits to
+	// the output scripts created and spent by a block.
+	GCSFilterRegular FilterType = iota
+)
+
+// Map of filter types back to their constant names for pretty printing.
+var ftStrings = map[FilterType]string{
+	GCSFilterRegular: "GCSFilterRegular",
+}
+
+// String returns the FilterType in human-readable form.
+func (t FilterType) String() string {
+	if s, ok := ftStrings[t]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("Unknown FilterType (%d)", uint8(t))
+}
+
+// MaxCFilterDataSize is the maximum byte size of a committed filter.  The
+// maximum size is currently defined as 256KiB.
+const MaxCFilterDataSize = 256 * 1024
+
+// MsgCFilter implements the Message interface and represents a Reddcoin
+// cfilter message.  It is used to deliver a committed filter in response to a
+// getcfilters message (MsgGetCFilters).
+type MsgCFilter struct {
+	FilterType FilterType
+	BlockHash  ShaHash
+	Data       []byte
+}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCFilter) BtcDecode(r io.Reader, pver uint32) error {
+	err := readElements(r, &msg.FilterType, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	msg.Data, err = readVarBytes(r, pver, MaxCFilterDataSize,
+		"cfilter data")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCFilter) BtcEncode(w io.Writer, pver uint32) error {
+	size := len(msg.Data)
+	if size > MaxCFilterDataSize {
+		str := fmt.Sprintf("cfilter size too large for message "+
+			"[size %v, max %v]", size, MaxCFilterDataSize)
+		return messageError("MsgCFilter.BtcEncode", str)
+	}
+
+	err := writeElements(w, msg.FilterType, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	err = writeVarBytes(w, pver, msg.Data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCFilter) Command() string {
+	return CmdCFilter
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCFilter) MaxPayloadLength(pver uint32) uint32 {
+	// Filter type 1 byte + block hash + filter size (varInt) + max filter
+	// size.
+	return 1 + HashSize + uint32(VarIntSerializeSize(MaxCFilterDataSize)) +
+		MaxCFilterDataSize
+}
+
+// NewMsgCFilter returns a new Reddcoin cfilter message that conforms to the
+// Message interface.  See MsgCFilter for details.
+func NewMsgCFilter(filterType FilterType, blockHash *ShaHash,
+	data []byte) *MsgCFilter {
+
+	return &MsgCFilter{
+		FilterType: filterType,
+		BlockHash:  *blockHash,
+		Data:       data,
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestCFilter tests the MsgCFilter API.
+func TestCFilter(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	data := []byte{0x01, 0x02}
+	msg := rddwire.NewMsgCFilter(rddwire.GCSFilterRegular,
+		&mainNetGenesisHash, data)
+
+	// Ensure the command is expected value.
+	wantCmd := "cfilter"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCFilter: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	// Filter type 1 byte + block hash 32 bytes + filter size 5 bytes + max
+	// filter size.
+	wantPayload := uint32(262182)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure the constructor stored the passed fields.
+	if msg.FilterType != rddwire.GCSFilterRegular ||
+		!msg.BlockHash.IsEqual(&mainNetGenesisHash) ||
+		!bytes.Equal(msg.Data, data) {
+
+		t.Errorf("NewMsgCFilter: wrong fields - got %v",
+			spew.Sdump(msg))
+	}
+
+	// Ensure filter data larger than the max allowed fails to encode.
+	msg.Data = make([]byte, rddwire.MaxCFilterDataSize+1)
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, pver)
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("BtcEncode: expected error for oversized filter - "+
+			"got %v", err)
+	}
+}
+
+// TestFilterTypeStringer tests the stringized output for FilterType types.
+func TestFilterTypeStringer(t *testing.T) {
+	tests := []struct {
+		in   rddwire.FilterType
+		want string
+	}{
+		{rddwire.GCSFilterRegular, "GCSFilterRegular"},
+		{0xff, "Unknown FilterType (255)"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := test.in.String()
+		if result != test.want {
+			t.Errorf("String #%d\n got: %s want: %s", i, result,
+				test.want)
+			continue
+		}
+	}
+}
+
+// TestCFilterWire tests the MsgCFilter wire encode and decode for various
+// filter sizes.
+func TestCFilterWire(t *testing.T) {
+	// Empty filter.
+	emptyFilter := rddwire.NewMsgCFilter(rddwire.GCSFilterRegular,
+		&mainNetGenesisHash, []byte{})
+	emptyFilterEncoded := append([]byte{
+		0x00, // Filter type
+	}, append(mainNetGenesisHash.Bytes(),
+		0x00, // Varint for filter size
+	)...)
+
+	// Filter with data.
+	dataFilter := rddwire.NewMsgCFilter(rddwire.GCSFilterRegular,
+		&mainNetGenesisHash, []byte{0x01, 0x1c, 0x51, 0x80})
+	dataFilterEncoded := append([]byte{
+		0x00, // Filter type
+	}, append(mainNetGenesisHash.Bytes(),
+		0x04,                   // Varint for filter size
+		0x01, 0x1c, 0x51, 0x80, // Filter data
+	)...)
+
+	tests := []struct {
+		in   *rddwire.MsgCFilter // Message to encode
+		out  *rddwire.MsgCFilter // Expected decoded message
+		buf  []byte              // Wire encoding
+		pver uint32              // Protocol version for wire encoding
+	}{
+		// Latest protocol version with empty filter.
+		{
+			emptyFilter,
+			emptyFilter,
+			emptyFilterEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Latest protocol version with filter data.
+		{
+			dataFilter,
+			dataFilter,
+			dataFilterEncoded,
+			rddwire.ProtocolVersion,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgCFilter
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestCFilterWireErrors performs negative tests against wire encode and decode
+// of MsgCFilter to confirm error paths work correctly.
+func TestCFilterWireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	rddwireErr := &rddwire.MessageError{}
+
+	baseFilter := rddwire.NewMsgCFilter(rddwire.GCSFilterRegular,
+		&mainNetGenesisHash, []byte{0x01, 0x1c, 0x51, 0x80})
+	baseFilterEncoded := append([]byte{
+		0x00, // Filter type
+	}, append(mainNetGenesisHash.Bytes(),
+		0x04,                   // Varint for filter size
+		0x01, 0x1c, 0x51, 0x80, // Filter data
+	)...)
+
+	// Message that forces an error by having a filter larger than the max
+	// allowed size.
+	maxFilter := rddwire.NewMsgCFilter(rddwire.GCSFilterRegular,
+		&mainNetGenesisHash, make([]byte, rddwire.MaxCFilterDataSize+1))
+	maxFilterEncoded := append([]byte{
+		0x00, // Filter type
+	}, append(mainNetGenesisHash.Bytes(),
+		0xfe, 0x01, 0x00, 0x04, 0x00, // Varint for filter size
+	)...)
+
+	tests := []struct {
+		in       *rddwire.MsgCFilter // Value to encode
+		buf      []byte              // Wire encoding
+		pver     uint32              // Protocol version for wire encoding
+		max      int                 // Max size of fixed buffer to induce errors
+		writeErr error               // Expected write error
+		readErr  error               // Expected read error
+	}{
+		// Force error in filter type.
+		{baseFilter, baseFilterEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in block hash.
+		{baseFilter, baseFilterEncoded, pver, 1, io.ErrShortWrite, io.EOF},
+		// Force error in filter size.
+		{baseFilter, baseFilterEncoded, pver, 33, io.ErrShortWrite, io.EOF},
+		// Force error in filter data.
+		{baseFilter, baseFilterEncoded, pver, 34, io.ErrShortWrite, io.EOF},
+		// Force error with greater than max filter size.
+		{maxFilter, maxFilterEncoded, pver, 38, rddwireErr, rddwireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BtcEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgCFilter
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BtcDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
@@ -0,0 +1,471 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/conformal/fastsha256"
+	"github.com/dchest/siphash"
+)
+
+const (
+	// ShortTxIDSize is the number of bytes used to encode a short
+	// transaction ID on the wire.
+	ShortTxIDSize = 6
+
+	// shortTxIDMask is the mask applied to the SipHash output to truncate
+	// it to ShortTxIDSize bytes.
+	shortTxIDMask = (1 << (ShortTxIDSize * 8)) - 1
+
+	// maxCmpctBlockTxIndex is the maximum transaction index which may be
+	// referenced by a compact block or block transactions request.  The
+	// differential encoding used on the wire is only defined for indexes
+	// which fit into a uint16.
+	maxCmpctBlockTxIndex = math.MaxUint16
+)
+
+// PrefilledTx defines a transaction which is included in full in a compact
+// block (MsgCmpctBlock) along with its index in the block.
+type PrefilledTx struct {
+	Index uint32
+	Tx    *MsgTx
+}
+
+// MsgCmpctBlock implements the Message interface and represents a Reddcoin
+// cmpctblock message.  It is used to relay a block using short transaction
+// IDs in place of the full transactions the receiver is expected to already
+// have in its memory pool.  Transactions the receiver is unlikely to have,
+// such as the coinbase, are included in full as prefilled transactions.
+//
+// Unlike Bitcoin, blocks with a version greater than PowBlockVersion carry a
+// PoSV block signature, so the Signature field is encoded after the
+// prefilled transactions for those blocks in the same way as MsgBlock.
+//
+// Use the Reconstruct function to rebuild the full block (MsgBlock) from the
+// compact block and a set of candidate transactions.
+//
+// Only compact block relay version 1 (CmpctBlockVersion) is supported, so short
+// transaction IDs are always calculated from the transaction hash (txid).  The
+// version 2 short IDs of BIP0152, which are calculated from the witness
+// transaction hash (wtxid), are not supported.
+//
+// This message was not added until protocol version BIP0152Version.
+type MsgCmpctBlock struct {
+	Header       BlockHeader
+	Nonce        uint64
+	ShortIDs     []uint64
+	PrefilledTxs []*PrefilledTx
+	Signature    []byte
+}
+
+// AddShortID adds a new short transaction ID to the message.
+func (msg *MsgCmpctBlock) AddShortID(id uint64) error {
+	if len(msg.ShortIDs)+len(msg.PrefilledTxs)+1 > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message [max %v]",
+			maxTxPerBlock)
+		return messageError("MsgCmpctBlock.AddShortID", str)
+	}
+
+	msg.ShortIDs = append(msg.ShortIDs, id&shortTxIDMask)
+	return nil
+}
+
+// AddPrefilledTx adds a new prefilled transaction at the provided block index
+// to the message.  Prefilled transactions must be added in increasing index
+// order.
+func (msg *MsgCmpctBlock) AddPrefilledTx(index uint32, tx *MsgTx) error {
+	if len(msg.ShortIDs)+len(msg.PrefilledTxs)+1 > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message [max %v]",
+			maxTxPerBlock)
+		return messageError("MsgCmpctBlock.AddPrefilledTx", str)
+	}
+
+	msg.PrefilledTxs = append(msg.PrefilledTxs, &PrefilledTx{
+		Index: index,
+		Tx:    tx,
+	})
+	return nil
+}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	err := readBlockHeader(r, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+
+	err = readElement(r, &msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	// Read num short IDs and limit to max.
+	count, err := readVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many short transaction IDs for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	msg.ShortIDs = make([]uint64, 0, count)
+	var idBuf [8]byte
+	for i := uint64(0); i < count; i++ {
+		_, err := io.ReadFull(r, idBuf[:ShortTxIDSize])
+		if err != nil {
+			return err
+		}
+		msg.ShortIDs = append(msg.ShortIDs,
+			binary.LittleEndian.Uint64(idBuf[:]))
+	}
+
+	// Read num prefilled transactions and limit to max.
+	count, err = readVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count+uint64(len(msg.ShortIDs)) > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[count %v, max %v]", count+uint64(len(msg.ShortIDs)),
+			maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	// The prefilled transaction indexes are differentially encoded such
+	// that each index is the offset from the previous index plus one.
+	msg.PrefilledTxs = make([]*PrefilledTx, 0, count)
+	offset := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		diff, err := readVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+		index := offset + diff
+		if diff > maxCmpctBlockTxIndex || index > maxCmpctBlockTxIndex {
+			str := fmt.Sprintf("prefilled transaction index is too "+
+				"high [index %v, max %v]", index,
+				maxCmpctBlockTxIndex)
+			return messageError("MsgCmpctBlock.BtcDecode", str)
+		}
+		offset = index + 1
+
+		tx := MsgTx{}
+		err = tx.BtcDecode(r, pver)
+		if err != nil {
+			return err
+		}
+		msg.PrefilledTxs = append(msg.PrefilledTxs, &PrefilledTx{
+			Index: uint32(index),
+			Tx:    &tx,
+		})
+	}
+
+	if msg.Header.Version > PowBlockVersion {
+		msg.Signature, err = readVarBytes(r, pver, MaxMessagePayload,
+			"compact block serialized signature")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcEncode", str)
+	}
+
+	// Limit to max transactions per block.
+	count := len(msg.ShortIDs) + len(msg.PrefilledTxs)
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcEncode", str)
+	}
+
+	err := writeBlockHeader(w, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+
+	err = writeElement(w, msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	err = writeVarInt(w, pver, uint64(len(msg.ShortIDs)))
+	if err != nil {
+		return err
+	}
+
+	var idBuf [8]byte
+	for _, id := range msg.ShortIDs {
+		binary.LittleEndian.PutUint64(idBuf[:], id)
+		_, err := w.Write(idBuf[:ShortTxIDSize])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeVarInt(w, pver, uint64(len(msg.PrefilledTxs)))
+	if err != nil {
+		return err
+	}
+
+	// The prefilled transaction indexes are differentially encoded such
+	// that each index is the offset from the previous index plus one.
+	offset := uint64(0)
+	for _, ptx := range msg.PrefilledTxs {
+		index := uint64(ptx.Index)
+		if index < offset || index > maxCmpctBlockTxIndex {
+			str := fmt.Sprintf("prefilled transaction index %v is "+
+				"out of order or too high [min %v, max %v]",
+				index, offset, maxCmpctBlockTxIndex)
+			return messageError("MsgCmpctBlock.BtcEncode", str)
+		}
+
+		err = writeVarInt(w, pver, index-offset)
+		if err != nil {
+			return err
+		}
+		offset = index + 1
+
+		err = ptx.Tx.BtcEncode(w, pver)
+		if err != nil {
+			return err
+		}
+	}
+
+	if msg.Header.Version > PowBlockVersion {
+		err = writeVarBytes(w, pver, msg.Signature)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCmpctBlock) Command() string {
+	return CmdCmpctBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint32 {
+	plen := uint32(0)
+	// The cmpctblock message did not exist before protocol version
+	// BIP0152Version.
+	if pver >= BIP0152Version {
+		// A compact block can never be larger than the block it
+		// represents.
+		plen = MaxBlockPayload
+	}
+
+	return plen
+}
+
+// sipHashKeys returns the SipHash keys used to calculate the short
+// transaction IDs for the compact block.  The keys are the first two little
+// endian 64-bit integers of the single sha256 of the block header followed
+// by the nonce.
+func (msg *MsgCmpctBlock) sipHashKeys() (uint64, uint64) {
+	// Ignore the error returns since the only way the encode could fail
+	// is being out of memory which would cause a run-time panic.
+	var buf bytes.Buffer
+	_ = writeBlockHeader(&buf, 0, &msg.Header)
+	_ = writeElement(&buf, msg.Nonce)
+
+	hasher := fastsha256.New()
+	hasher.Write(buf.Bytes())
+	sum := hasher.Sum(nil)
+
+	return binary.LittleEndian.Uint64(sum[0:8]),
+		binary.LittleEndian.Uint64(sum[8:16])
+}
+
+// shortTxID returns the short transaction ID for the provided transaction
+// hash using the passed SipHash keys.
+func shortTxID(k0, k1 uint64, hash *ShaHash) uint64 {
+	return siphash.Hash(k0, k1, hash[:]) & shortTxIDMask
+}
+
+// ShortTxID returns the short transaction ID of the passed transaction for
+// the compact block.  The ID depends on both the block header and the nonce,
+// so they must be set before calling this function.  The ID is calculated
+// from the transaction hash (txid) as defined by compact block relay version
+// 1, even when the transaction has witness data.
+func (msg *MsgCmpctBlock) ShortTxID(tx *MsgTx) uint64 {
+	k0, k1 := msg.sipHashKeys()
+
+	// Ignore the error since TxSha can't fail in the current
+	// implementation except due to run-time panics.
+	sha, _ := tx.TxSha()
+	return shortTxID(k0, k1, &sha)
+}
+
+// Reconstruct attempts to rebuild the full block from the compact block using
+// the prefilled transactions and the passed candidate transactions, which
+// are typically the contents of the memory pool.
+//
+// When every transaction in the block could be determined, the rebuilt block
+// is returned.  Otherwise, the returned block is nil and the indexes of the
+// transactions which could not be determined are returned instead.  Those
+// indexes can be requested via a getblocktxn message (MsgGetBlockTxn) and the
+// transactions in the resulting blocktxn message (MsgBlockTxn) appended to
+// the candidates before calling this function again.
+//
+// Candidates whose short transaction ID collides with another candidate for
+// the same index are ignored and the index is reported as missing.  Callers
+// should verify the merkle root of the rebuilt block since short ID
+// collisions with transactions which are not in the block are not detectable
+// here.
+func (msg *MsgCmpctBlock) Reconstruct(candidates []*MsgTx) (*MsgBlock, []uint32, error) {
+	txCount := len(msg.ShortIDs) + len(msg.PrefilledTxs)
+	if txCount > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for block "+
+			"[count %v, max %v]", txCount, maxTxPerBlock)
+		return nil, nil, messageError("MsgCmpctBlock.Reconstruct", str)
+	}
+
+	// Put the prefilled transactions in place.
+	txns := make([]*MsgTx, txCount)
+	for _, ptx := range msg.PrefilledTxs {
+		if ptx.Index >= uint32(txCount) || ptx.Tx == nil {
+			str := fmt.Sprintf("invalid prefilled transaction "+
+				"[index %v, count %v]", ptx.Index, txCount)
+			return nil, nil, messageError("MsgCmpctBlock.Reconstruct",
+				str)
+		}
+		if txns[ptx.Index] != nil {
+			str := fmt.Sprintf("duplicate prefilled transaction "+
+				"index %v", ptx.Index)
+			return nil, nil, messageError("MsgCmpctBlock.Reconstruct",
+				str)
+		}
+		txns[ptx.Index] = ptx.Tx
+	}
+
+	// Assign the short IDs to the remaining slots in order.
+	idIndex := make(map[uint64]int, len(msg.ShortIDs))
+	slot := 0
+	for _, id := range msg.ShortIDs {
+		for txns[slot] != nil {
+			slot++
+		}
+		if _, exists := idIndex[id]; exists {
+			str := fmt.Sprintf("duplicate short transaction ID %x",
+				id)
+			return nil, nil, messageError("MsgCmpctBlock.Reconstruct",
+				str)
+		}
+		idIndex[id] = slot
+		slot++
+	}
+
+	// Fill in the slots from the candidates while discarding any which
+	// are ambiguous due to colliding short IDs.
+	k0, k1 := msg.sipHashKeys()
+	filled := make(map[int]ShaHash)
+	collided := make(map[int]struct{})
+	for _, tx := range candidates {
+		sha, _ := tx.TxSha()
+		index, ok := idIndex[shortTxID(k0, k1, &sha)]
+		if !ok {
+			continue
+		}
+		if _, ok := collided[index]; ok {
+			continue
+		}
+		if existing, ok := filled[index]; ok {
+			if !existing.IsEqual(&sha) {
+				txns[index] = nil
+				delete(filled, index)
+				collided[index] = struct{}{}
+			}
+			continue
+		}
+		txns[index] = tx
+		filled[index] = sha
+	}
+
+	var missing []uint32
+	for i, tx := range txns {
+		if tx == nil {
+			missing = append(missing, uint32(i))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, missing, nil
+	}
+
+	block := MsgBlock{
+		Header:       msg.Header,
+		Transactions: txns,
+		Signature:    msg.Signature,
+	}
+	return &block, nil, nil
+}
+
+// NewMsgCmpctBlock returns a new Reddcoin cmpctblock message that conforms to
+// the Message interface and represents the passed block using the provided
+// nonce for the short transaction IDs.  The coinbase is always prefilled.
+// For blocks with a version greater than PowBlockVersion, the coinstake which
+// follows the coinbase is prefilled as well since it is never relayed on its
+// own.  See MsgCmpctBlock for details.
+func NewMsgCmpctBlock(block *MsgBlock, nonce uint64) *MsgCmpctBlock {
+	numPrefilled := 1
+	if block.Header.Version > PowBlockVersion {
+		numPrefilled = 2
+	}
+	if numPrefilled > len(block.Transactions) {
+		numPrefilled = len(block.Transactions)
+	}
+
+	msg := MsgCmpctBlock{
+		Header:       block.Header,
+		Nonce:        nonce,
+		ShortIDs:     make([]uint64, 0, len(block.Transactions)-numPrefilled),
+		PrefilledTxs: make([]*PrefilledTx, 0, numPrefilled),
+		Signature:    block.Signature,
+	}
+
+	k0, k1 := msg.sipHashKeys()
+	for i, tx := range block.Transactions {
+		if i < numPrefilled {
+			msg.PrefilledTxs = append(msg.PrefilledTxs, &PrefilledTx{
+				Index: uint32(i),
+				Tx:    tx,
+			})
+			continue
+		}
+
+		sha, _ := tx.TxSha()
+		msg.ShortIDs = append(msg.ShortIDs, shortTxID(k0, k1, &sha))
+	}
+
+	return &msg
+}
@@ -0,0 +1,481 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestCmpctBlock tests the MsgCmpctBlock API.
+func TestCmpctBlock(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "cmpctblock"
+	msg := rddwire.NewMsgCmpctBlock(&blockOne, 0x0102030405060708)
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCmpctBlock: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	wantPayload := uint32(1000000)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure max payload is zero for protocol versions before the message
+	// existed.
+	wantPayload = uint32(0)
+	maxPayload = msg.MaxPayloadLength(rddwire.BIP0152Version - 1)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v",
+			rddwire.BIP0152Version-1, maxPayload, wantPayload)
+	}
+
+	// Ensure we get the same block header data back out.
+	if !reflect.DeepEqual(&msg.Header, &blockOne.Header) {
+		t.Errorf("NewMsgCmpctBlock: wrong block header - got %v, want %v",
+			spew.Sdump(&msg.Header), spew.Sdump(&blockOne.Header))
+	}
+
+	// Ensure the coinbase is prefilled and there are no short IDs.
+	if len(msg.PrefilledTxs) != 1 || len(msg.ShortIDs) != 0 {
+		t.Errorf("NewMsgCmpctBlock: wrong number of transactions - "+
+			"got %d prefilled and %d short IDs, want 1 and 0",
+			len(msg.PrefilledTxs), len(msg.ShortIDs))
+	}
+
+	// Ensure the short transaction ID is calculated from the block header,
+	// nonce, and transaction hash.
+	wantID := uint64(0x56623a742b65)
+	id := msg.ShortTxID(blockOne.Transactions[0])
+	if id != wantID {
+		t.Errorf("ShortTxID: wrong short ID - got %x, want %x", id,
+			wantID)
+	}
+
+	// Ensure short IDs are truncated to the wire size.
+	msg.AddShortID(0xffffffffffffffff)
+	if msg.ShortIDs[0] != 0xffffffffffff {
+		t.Errorf("AddShortID: wrong short ID - got %x, want %x",
+			msg.ShortIDs[0], 0xffffffffffff)
+	}
+
+	// Ensure adding more than the max allowed transactions per block
+	// returns an error.
+	var err error
+	for i := 0; i < rddwire.MaxTxPerBlock+1; i++ {
+		err = msg.AddShortID(uint64(i))
+	}
+	if err == nil {
+		t.Errorf("AddShortID: expected error on too many transactions " +
+			"not received")
+	}
+	err = msg.AddPrefilledTx(0, blockOne.Transactions[0])
+	if err == nil {
+		t.Errorf("AddPrefilledTx: expected error on too many " +
+			"transactions not received")
+	}
+
+	return
+}
+
+// TestCmpctBlockReconstruct tests rebuilding blocks from compact blocks.
+func TestCmpctBlockReconstruct(t *testing.T) {
+	cmpct := rddwire.NewMsgCmpctBlock(&posvBlock, 0x1122334455667788)
+
+	// Ensure the coinbase and coinstake are prefilled for PoSV blocks.
+	if len(cmpct.PrefilledTxs) != 2 || len(cmpct.ShortIDs) != 2 {
+		t.Errorf("NewMsgCmpctBlock: wrong number of transactions - "+
+			"got %d prefilled and %d short IDs, want 2 and 2",
+			len(cmpct.PrefilledTxs), len(cmpct.ShortIDs))
+	}
+
+	// Ensure the missing indexes are reported when no candidates are
+	// available.
+	block, missing, err := cmpct.Reconstruct(nil)
+	if err != nil {
+		t.Errorf("Reconstruct: unexpected error %v", err)
+	}
+	if block != nil || !reflect.DeepEqual(missing, []uint32{2, 3}) {
+		t.Errorf("Reconstruct: wrong missing indexes - got %v, "+
+			"want %v", missing, []uint32{2, 3})
+	}
+
+	// Ensure a partial set of candidates, including unrelated ones, only
+	// reports the remaining index as missing.
+	candidates := []*rddwire.MsgTx{multiTx, posvBlock.Transactions[3]}
+	block, missing, err = cmpct.Reconstruct(candidates)
+	if err != nil {
+		t.Errorf("Reconstruct: unexpected error %v", err)
+	}
+	if block != nil || !reflect.DeepEqual(missing, []uint32{2}) {
+		t.Errorf("Reconstruct: wrong missing indexes - got %v, "+
+			"want %v", missing, []uint32{2})
+	}
+
+	// Ensure the full block, including the signature, is rebuilt once
+	// the missing transactions are provided via a blocktxn message.
+	blockHash, _ := posvBlock.BlockSha()
+	blockTxn := rddwire.NewMsgBlockTxn(&blockHash)
+	blockTxn.AddTransaction(posvBlock.Transactions[2])
+	candidates = append(candidates, blockTxn.Transactions...)
+	block, missing, err = cmpct.Reconstruct(candidates)
+	if err != nil {
+		t.Errorf("Reconstruct: unexpected error %v", err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("Reconstruct: unexpected missing indexes %v", missing)
+	}
+	if !reflect.DeepEqual(block, &posvBlock) {
+		t.Errorf("Reconstruct: wrong block - got %v, want %v",
+			spew.Sdump(block), spew.Sdump(&posvBlock))
+	}
+
+	// Ensure duplicate short IDs are rejected.
+	dupCmpct := *cmpct
+	dupCmpct.ShortIDs = []uint64{cmpct.ShortIDs[0], cmpct.ShortIDs[0]}
+	_, _, err = dupCmpct.Reconstruct(candidates)
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("Reconstruct: wrong error for duplicate short IDs - "+
+			"got %v, want %v", err, &rddwire.MessageError{})
+	}
+
+	// Ensure prefilled transactions outside of the block are rejected.
+	badCmpct := *cmpct
+	badCmpct.PrefilledTxs = []*rddwire.PrefilledTx{
+		{Index: 4, Tx: posvBlock.Transactions[0]},
+	}
+	_, _, err = badCmpct.Reconstruct(candidates)
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("Reconstruct: wrong error for invalid prefilled "+
+			"index - got %v, want %v", err, &rddwire.MessageError{})
+	}
+}
+
+// TestCmpctBlockWire tests the MsgCmpctBlock wire encode and decode for
+// various protocol versions and block versions.
+func TestCmpctBlockWire(t *testing.T) {
+	tests := []struct {
+		in   *rddwire.MsgCmpctBlock // Message to encode
+		out  *rddwire.MsgCmpctBlock // Expected decoded message
+		buf  []byte                 // Wire encoding
+		pver uint32                 // Protocol version for wire encoding
+	}{
+		// Latest protocol version with a PoW block.
+		{
+			cmpctBlockOne,
+			cmpctBlockOne,
+			cmpctBlockOneBytes,
+			rddwire.ProtocolVersion,
+		},
+
+		// Protocol version BIP0152Version with a PoW block.
+		{
+			cmpctBlockOne,
+			cmpctBlockOne,
+			cmpctBlockOneBytes,
+			rddwire.BIP0152Version,
+		},
+
+		// Latest protocol version with a PoSV block.
+		{
+			cmpctBlockPoSV,
+			cmpctBlockPoSV,
+			cmpctBlockPoSVBytes,
+			rddwire.ProtocolVersion,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgCmpctBlock
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(&msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestCmpctBlockWireErrors performs negative tests against wire encode and
+// decode of MsgCmpctBlock to confirm error paths work correctly.
+func TestCmpctBlockWireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	pverNoCmpctBlock := rddwire.BIP0152Version - 1
+	rddwireErr := &rddwire.MessageError{}
+
+	tests := []struct {
+		in       *rddwire.MsgCmpctBlock // Value to encode
+		buf      []byte                 // Wire encoding
+		pver     uint32                 // Protocol version for wire encoding
+		max      int                    // Max size of fixed buffer to induce errors
+		writeErr error                  // Expected write error
+		readErr  error                  // Expected read error
+	}{
+		// Force error in header.
+		{cmpctBlockPoSV, cmpctBlockPoSVBytes, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in nonce.
+		{cmpctBlockPoSV, cmpctBlockPoSVBytes, pver, 80, io.ErrShortWrite, io.EOF},
+		// Force error in short ID count.
+		{cmpctBlockPoSV, cmpctBlockPoSVBytes, pver, 88, io.ErrShortWrite, io.EOF},
+		// Force error in short IDs.
+		{cmpctBlockPoSV, cmpctBlockPoSVBytes, pver, 89, io.ErrShortWrite, io.EOF},
+		// Force error in prefilled transaction count.
+		{cmpctBlockPoSV, cmpctBlockPoSVBytes, pver, 101, io.ErrShortWrite, io.EOF},
+		// Force error in prefilled transaction index.
+		{cmpctBlockPoSV, cmpctBlockPoSVBytes, pver, 102, io.ErrShortWrite, io.EOF},
+		// Force error in prefilled transaction.
+		{cmpctBlockPoSV, cmpctBlockPoSVBytes, pver, 103, io.ErrShortWrite, io.EOF},
+		// Force error in signature.
+		{cmpctBlockPoSV, cmpctBlockPoSVBytes, pver, len(cmpctBlockPoSVBytes) - 4, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{cmpctBlockPoSV, cmpctBlockPoSVBytes, pverNoCmpctBlock, len(cmpctBlockPoSVBytes), rddwireErr, rddwireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BtcEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgCmpctBlock
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BtcDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
+
+// TestCmpctBlockOverflowErrors performs tests to ensure encoding and decoding
+// compact blocks that are intentionally crafted to use large values for the
+// number of transactions and indexes are handled properly.
+func TestCmpctBlockOverflowErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	header := cmpctBlockOneBytes[:88]
+
+	tests := []struct {
+		buf  []byte // Wire encoding
+		pver uint32 // Protocol version for wire encoding
+		err  error  // Expected error
+	}{
+		// Short ID count that claims to have more than the max allowed.
+		{
+			append(append([]byte{}, header...),
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff),
+			pver, &rddwire.MessageError{},
+		},
+
+		// Prefilled transaction count that claims to have more than the
+		// max allowed.
+		{
+			append(append([]byte{}, header...),
+				0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0xff),
+			pver, &rddwire.MessageError{},
+		},
+
+		// Prefilled transaction index higher than allowed.
+		{
+			append(append([]byte{}, header...),
+				0x00, 0x01, 0xfe, 0x00, 0x00, 0x01, 0x00),
+			pver, &rddwire.MessageError{},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Decode from wire format.
+		var msg rddwire.MsgCmpctBlock
+		r := bytes.NewReader(test.buf)
+		err := msg.BtcDecode(r, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, reflect.TypeOf(test.err))
+			continue
+		}
+	}
+
+	// Ensure out of order prefilled transactions are not encoded.
+	msg := *cmpctBlockPoSV
+	msg.PrefilledTxs = []*rddwire.PrefilledTx{
+		msg.PrefilledTxs[1], msg.PrefilledTxs[0],
+	}
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, pver)
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("BtcEncode: wrong error for out of order prefilled "+
+			"transactions - got %v, want %v", err,
+			&rddwire.MessageError{})
+	}
+}
+
+// posvTx returns a transaction spending the passed outpoint index which is
+// used to build the PoSV block used in various tests.
+func posvTx(index uint32, value int64) *rddwire.MsgTx {
+	return &rddwire.MsgTx{
+		Version: 2,
+		TxIn: []*rddwire.TxIn{
+			{
+				PreviousOutPoint: rddwire.OutPoint{
+					Hash:  mainNetGenesisMerkleRoot,
+					Index: index,
+				},
+				SignatureScript: []byte{0x51},
+				Sequence:        0xffffffff,
+			},
+		},
+		TxOut: []*rddwire.TxOut{
+			{
+				Value:    value,
+				PkScript: []byte{0x51},
+			},
+		},
+		LockTime:  0,
+		Timestamp: time.Unix(0x53cec6bf, 0),
+	}
+}
+
+// posvBlock is a version 3 block with a coinbase, coinstake, and two regular
+// transactions and is used in various tests.
+var posvBlock = rddwire.MsgBlock{
+	Header: rddwire.BlockHeader{
+		Version:    3,
+		PrevBlock:  mainNetGenesisHash,
+		MerkleRoot: mainNetGenesisMerkleRoot,
+		Timestamp:  time.Unix(0x53cec6bf, 0),
+		Bits:       0x1e0fffff,
+		Nonce:      0,
+	},
+	Transactions: []*rddwire.MsgTx{
+		multiTx,
+		posvTx(0, 0),
+		posvTx(1, 1000),
+		posvTx(2, 2000),
+	},
+	Signature: []byte{0x30, 0x01, 0x02},
+}
+
+// cmpctBlockOne is the compact block for block one using a nonce of
+// 0x0102030405060708.
+var cmpctBlockOne = &rddwire.MsgCmpctBlock{
+	Header:   blockOne.Header,
+	Nonce:    0x0102030405060708,
+	ShortIDs: []uint64{},
+	PrefilledTxs: []*rddwire.PrefilledTx{
+		{Index: 0, Tx: blockOne.Transactions[0]},
+	},
+}
+
+// cmpctBlockOneBytes is the wire encoded bytes for cmpctBlockOne.
+var cmpctBlockOneBytes = func() []byte {
+	var buf bytes.Buffer
+	buf.Write(blockOneBytes[:80]) // Header
+	buf.Write([]byte{
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // Nonce
+		0x00, // Varint for number of short IDs
+		0x01, // Varint for number of prefilled transactions
+		0x00, // Differential index of prefilled transaction
+	})
+	buf.Write(blockOneBytes[81:]) // Coinbase transaction
+	return buf.Bytes()
+}()
+
+// cmpctBlockPoSV is a compact block for a PoSV block with two short IDs and
+// two prefilled transactions.
+var cmpctBlockPoSV = &rddwire.MsgCmpctBlock{
+	Header:   posvBlock.Header,
+	Nonce:    0x0102030405060708,
+	ShortIDs: []uint64{0x0000aabbccddeeff, 0x0000112233445566},
+	PrefilledTxs: []*rddwire.PrefilledTx{
+		{Index: 0, Tx: multiTx},
+		{Index: 2, Tx: multiTx},
+	},
+	Signature: []byte{0x30, 0x01, 0x02},
+}
+
+// cmpctBlockPoSVBytes is the wire encoded bytes for cmpctBlockPoSV.
+var cmpctBlockPoSVBytes = func() []byte {
+	var buf bytes.Buffer
+	rddwire.TstWriteBlockHeader(&buf, 0, &posvBlock.Header)
+	buf.Write([]byte{
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // Nonce
+		0x02,                               // Varint for number of short IDs
+		0xff, 0xee, 0xdd, 0xcc, 0xbb, 0xaa, // Short ID
+		0x66, 0x55, 0x44, 0x33, 0x22, 0x11, // Short ID
+		0x02, // Varint for number of prefilled transactions
+		0x00, // Differential index of first prefilled transaction
+	})
+	buf.Write(multiTxEncoded)
+	buf.Write([]byte{
+		0x01, // Differential index of second prefilled transaction
+	})
+	buf.Write(multiTxEncoded)
+	buf.Write([]byte{
+		0x03, 0x30, 0x01, 0x02, // Signature
+	})
+	return buf.Bytes()
+}()
@@ -0,0 +1,224 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestFeeFilterLatest tests the MsgFeeFilter API against the latest protocol
+// version.
+func TestFeeFilterLatest(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	minFee := int64(100000)
+	msg := rddwire.NewMsgFeeFilter(minFee)
+	if msg.MinFee != minFee {
+		t.Errorf("NewMsgFeeFilter: wrong minfee - got %v, want %v",
+			msg.MinFee, minFee)
+	}
+
+	// Ensure the command is expected value.
+	wantCmd := "feefilter"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgFeeFilter: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	wantPayload := uint32(8)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure max payload is zero for protocol versions before the message
+	// existed.
+	oldPver := rddwire.FeeFilterVersion - 1
+	wantPayload = uint32(0)
+	maxPayload = msg.MaxPayloadLength(oldPver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", oldPver,
+			maxPayload, wantPayload)
+	}
+
+	// Test encode with latest protocol version.
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, pver)
+	if err != nil {
+		t.Errorf("encode of MsgFeeFilter failed %v err <%v>", msg, err)
+	}
+
+	// Test decode with latest protocol version.
+	readmsg := rddwire.NewMsgFeeFilter(0)
+	err = readmsg.BtcDecode(&buf, pver)
+	if err != nil {
+		t.Errorf("decode of MsgFeeFilter failed [%v] err <%v>", buf, err)
+	}
+
+	// Ensure minfee is the same.
+	if msg.MinFee != readmsg.MinFee {
+		t.Errorf("Should get same minfee for protocol version %d", pver)
+	}
+
+	return
+}
+
+// TestFeeFilterCrossProtocol tests the MsgFeeFilter API when encoding with
+// the latest protocol version and decoding with SendHeadersVersion.
+func TestFeeFilterCrossProtocol(t *testing.T) {
+	msg := rddwire.NewMsgFeeFilter(1000)
+
+	// Encode with latest protocol version.
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, rddwire.ProtocolVersion)
+	if err != nil {
+		t.Errorf("encode of MsgFeeFilter failed %v err <%v>", msg, err)
+	}
+
+	// Decode with old protocol version.
+	var readmsg rddwire.MsgFeeFilter
+	err = readmsg.BtcDecode(&buf, rddwire.SendHeadersVersion)
+	if err == nil {
+		t.Errorf("decode of MsgFeeFilter succeeded when it "+
+			"shouldn't have %v", msg)
+	}
+}
+
+// TestFeeFilterWire tests the MsgFeeFilter wire encode and decode for various
+// protocol versions.
+func TestFeeFilterWire(t *testing.T) {
+	tests := []struct {
+		in   rddwire.MsgFeeFilter // Message to encode
+		out  rddwire.MsgFeeFilter // Expected decoded message
+		buf  []byte               // Wire encoding
+		pver uint32               // Protocol version for wire encoding
+	}{
+		// Latest protocol version.
+		{
+			rddwire.MsgFeeFilter{MinFee: 123123}, // 0x1e0f3
+			rddwire.MsgFeeFilter{MinFee: 123123}, // 0x1e0f3
+			[]byte{0xf3, 0xe0, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+			rddwire.ProtocolVersion,
+		},
+
+		// Protocol version FeeFilterVersion.
+		{
+			rddwire.MsgFeeFilter{MinFee: 456456}, // 0x6f708
+			rddwire.MsgFeeFilter{MinFee: 456456}, // 0x6f708
+			[]byte{0x08, 0xf7, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00},
+			rddwire.FeeFilterVersion,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgFeeFilter
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestFeeFilterWireErrors performs negative tests against wire encode and
+// decode of MsgFeeFilter to confirm error paths work correctly.
+func TestFeeFilterWireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	pverNoFeeFilter := rddwire.FeeFilterVersion - 1
+	rddwireErr := &rddwire.MessageError{}
+
+	baseFeeFilter := rddwire.NewMsgFeeFilter(123123) // 0x1e0f3
+	baseFeeFilterEncoded := []byte{
+		0xf3, 0xe0, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	tests := []struct {
+		in       *rddwire.MsgFeeFilter // Value to encode
+		buf      []byte                // Wire encoding
+		pver     uint32                // Protocol version for wire encoding
+		max      int                   // Max size of fixed buffer to induce errors
+		writeErr error                 // Expected write error
+		readErr  error                 // Expected read error
+	}{
+		// Latest protocol version with intentional read/write errors.
+		// Force error in minfee.
+		{baseFeeFilter, baseFeeFilterEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseFeeFilter, baseFeeFilterEncoded, pverNoFeeFilter, 4, rddwireErr, rddwireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BtcEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgFeeFilter
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BtcDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
@@ -28,6 +28,9 @@ const MaxBlockLocatorsPerMsg = 500
 // most recent 10 block hashes, then double the step each loop iteration to
 // exponentially decrease the number of hashes the further away from head and
 // closer to the genesis block you get.
+//
+// NewBlockLocator builds the block locator hashes this way, and LocateBlocks
+// resolves them into the response.
 type MsgGetBlocks struct {
 	ProtocolVersion    uint32
 	BlockLocatorHashes []*ShaHash
@@ -0,0 +1,162 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"io"
+)
+
+// MsgGetBlockTxn implements the Message interface and represents a Reddcoin
+// getblocktxn message.  It is used to request the transactions at the given
+// indexes of a block which could not be determined while reconstructing it
+// from a compact block (MsgCmpctBlock).  The transactions are returned via a
+// blocktxn message (MsgBlockTxn).
+//
+// Use the AddIndex function to build up the list of requested indexes.  The
+// indexes must be added in increasing order.
+//
+// This message was not added until protocol version BIP0152Version.
+type MsgGetBlockTxn struct {
+	BlockHash ShaHash
+	Indexes   []uint32
+}
+
+// AddIndex adds a new transaction index to the message.
+func (msg *MsgGetBlockTxn) AddIndex(index uint32) error {
+	if len(msg.Indexes)+1 > maxTxPerBlock {
+		str := fmt.Sprintf("too many transaction indexes for message "+
+			"[max %v]", maxTxPerBlock)
+		return messageError("MsgGetBlockTxn.AddIndex", str)
+	}
+
+	msg.Indexes = append(msg.Indexes, index)
+	return nil
+}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcDecode", str)
+	}
+
+	err := readElement(r, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	// Read num indexes and limit to max.
+	count, err := readVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many transaction indexes for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgGetBlockTxn.BtcDecode", str)
+	}
+
+	// The indexes are differentially encoded such that each index is the
+	// offset from the previous index plus one.
+	msg.Indexes = make([]uint32, 0, count)
+	offset := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		diff, err := readVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+		index := offset + diff
+		if diff > maxCmpctBlockTxIndex || index > maxCmpctBlockTxIndex {
+			str := fmt.Sprintf("transaction index is too high "+
+				"[index %v, max %v]", index, maxCmpctBlockTxIndex)
+			return messageError("MsgGetBlockTxn.BtcDecode", str)
+		}
+		offset = index + 1
+		msg.AddIndex(uint32(index))
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcEncode", str)
+	}
+
+	// Limit to max indexes per message.
+	count := len(msg.Indexes)
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many transaction indexes for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgGetBlockTxn.BtcEncode", str)
+	}
+
+	err := writeElement(w, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	err = writeVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	offset := uint64(0)
+	for _, idx := range msg.Indexes {
+		index := uint64(idx)
+		if index < offset || index > maxCmpctBlockTxIndex {
+			str := fmt.Sprintf("transaction index %v is out of "+
+				"order or too high [min %v, max %v]", index,
+				offset, maxCmpctBlockTxIndex)
+			return messageError("MsgGetBlockTxn.BtcEncode", str)
+		}
+
+		err = writeVarInt(w, pver, index-offset)
+		if err != nil {
+			return err
+		}
+		offset = index + 1
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetBlockTxn) Command() string {
+	return CmdGetBlockTxn
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) MaxPayloadLength(pver uint32) uint32 {
+	plen := uint32(0)
+	// The getblocktxn message did not exist before protocol version
+	// BIP0152Version.
+	if pver >= BIP0152Version {
+		// Block hash + num indexes (varInt) + max allowed indexes,
+		// each of which fits into a 3 byte varInt.
+		plen = HashSize + MaxVarIntPayload + (maxTxPerBlock * 3)
+	}
+
+	return plen
+}
+
+// NewMsgGetBlockTxn returns a new Reddcoin getblocktxn message that conforms to
+// the Message interface using the passed block hash and transaction indexes.
+// See MsgGetBlockTxn for details.
+func NewMsgGetBlockTxn(blockHash *ShaHash, indexes []uint32) *MsgGetBlockTxn {
+	return &MsgGetBlockTxn{
+		BlockHash: *blockHash,
+		Indexes:   indexes,
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetBlockTxn tests the MsgGetBlockTxn API.
+func TestGetBlockTxn(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "getblocktxn"
+	msg := rddwire.NewMsgGetBlockTxn(&mainNetGenesisHash, nil)
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgGetBlockTxn: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	// Block hash + num indexes (varInt) + max allowed indexes.
+	wantPayload := uint32(32 + 9 + (rddwire.MaxTxPerBlock * 3))
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure we get the same block hash back out.
+	if !msg.BlockHash.IsEqual(&mainNetGenesisHash) {
+		t.Errorf("NewMsgGetBlockTxn: wrong block hash - got %v, want %v",
+			msg.BlockHash, mainNetGenesisHash)
+	}
+
+	// Ensure indexes are added properly.
+	msg.AddIndex(5)
+	if !reflect.DeepEqual(msg.Indexes, []uint32{5}) {
+		t.Errorf("AddIndex: wrong indexes - got %v, want %v",
+			msg.Indexes, []uint32{5})
+	}
+
+	// Ensure adding more than the max allowed indexes per message returns
+	// error.
+	var err error
+	for i := 0; i < rddwire.MaxTxPerBlock+1; i++ {
+		err = msg.AddIndex(uint32(i))
+	}
+	if err == nil {
+		t.Errorf("AddIndex: expected error on too many indexes " +
+			"not received")
+	}
+
+	return
+}
+
+// TestGetBlockTxnWire tests the MsgGetBlockTxn wire encode and decode for
+// various protocol versions.
+func TestGetBlockTxnWire(t *testing.T) {
+	// Message with no indexes.
+	noIndexes := rddwire.NewMsgGetBlockTxn(&mainNetGenesisHash, []uint32{})
+	noIndexesEncoded := append(mainNetGenesisHash.Bytes(),
+		0x00, // Varint for number of indexes
+	)
+
+	// Message with multiple differentially encoded indexes.
+	multiIndexes := rddwire.NewMsgGetBlockTxn(&mainNetGenesisHash,
+		[]uint32{1, 2, 5, 300, 65535})
+	multiIndexesEncoded := append(mainNetGenesisHash.Bytes(),
+		0x05,             // Varint for number of indexes
+		0x01,             // Index 1
+		0x00,             // Index 2
+		0x02,             // Index 5
+		0xfd, 0x26, 0x01, // Index 300
+		0xfd, 0xd2, 0xfe, // Index 65535
+	)
+
+	tests := []struct {
+		in   *rddwire.MsgGetBlockTxn // Message to encode
+		out  *rddwire.MsgGetBlockTxn // Expected decoded message
+		buf  []byte                  // Wire encoding
+		pver uint32                  // Protocol version for wire encoding
+	}{
+		// Latest protocol version with no indexes.
+		{
+			noIndexes,
+			noIndexes,
+			noIndexesEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Latest protocol version with multiple indexes.
+		{
+			multiIndexes,
+			multiIndexes,
+			multiIndexesEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Protocol version BIP0152Version with multiple indexes.
+		{
+			multiIndexes,
+			multiIndexes,
+			multiIndexesEncoded,
+			rddwire.BIP0152Version,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgGetBlockTxn
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(&msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestGetBlockTxnWireErrors performs negative tests against wire encode and
+// decode of MsgGetBlockTxn to confirm error paths work correctly.
+func TestGetBlockTxnWireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	pverNoGetBlockTxn := rddwire.BIP0152Version - 1
+	rddwireErr := &rddwire.MessageError{}
+
+	baseGetBlockTxn := rddwire.NewMsgGetBlockTxn(&mainNetGenesisHash,
+		[]uint32{1, 2})
+	baseGetBlockTxnEncoded := append(mainNetGenesisHash.Bytes(),
+		0x02, // Varint for number of indexes
+		0x01, // Index 1
+		0x00, // Index 2
+	)
+
+	// Message that forces an error by having out of order indexes.
+	unorderedGetBlockTxn := rddwire.NewMsgGetBlockTxn(&mainNetGenesisHash,
+		[]uint32{2, 1})
+
+	// Message that forces an error by having an index which is too high.
+	highGetBlockTxn := rddwire.NewMsgGetBlockTxn(&mainNetGenesisHash,
+		[]uint32{65536})
+	highGetBlockTxnEncoded := append(mainNetGenesisHash.Bytes(),
+		0x01,                         // Varint for number of indexes
+		0xfe, 0x00, 0x00, 0x01, 0x00, // Index 65536
+	)
+
+	// Message that forces an error by having more than the max allowed
+	// indexes.
+	maxGetBlockTxn := rddwire.NewMsgGetBlockTxn(&mainNetGenesisHash,
+		make([]uint32, rddwire.MaxTxPerBlock+1))
+	maxGetBlockTxnEncoded := append(mainNetGenesisHash.Bytes(),
+		0xfe, 0xff, 0xff, 0xff, 0xff, // Varint for number of indexes
+	)
+
+	tests := []struct {
+		in       *rddwire.MsgGetBlockTxn // Value to encode
+		buf      []byte                  // Wire encoding
+		pver     uint32                  // Protocol version for wire encoding
+		max      int                     // Max size of fixed buffer to induce errors
+		writeErr error                   // Expected write error
+		readErr  error                   // Expected read error
+	}{
+		// Force error in block hash.
+		{baseGetBlockTxn, baseGetBlockTxnEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in index count.
+		{baseGetBlockTxn, baseGetBlockTxnEncoded, pver, 32, io.ErrShortWrite, io.EOF},
+		// Force error in indexes.
+		{baseGetBlockTxn, baseGetBlockTxnEncoded, pver, 33, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseGetBlockTxn, baseGetBlockTxnEncoded, pverNoGetBlockTxn, 35, rddwireErr, rddwireErr},
+		// Force error with out of order indexes.  The decode doesn't
+		// fail since the encoded bytes are valid.
+		{unorderedGetBlockTxn, baseGetBlockTxnEncoded, pver, 35, rddwireErr, nil},
+		// Force error with index which is too high.
+		{highGetBlockTxn, highGetBlockTxnEncoded, pver, 38, rddwireErr, rddwireErr},
+		// Force error with greater than max indexes.
+		{maxGetBlockTxn, maxGetBlockTxnEncoded, pver, 37, rddwireErr, rddwireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BtcEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgGetBlockTxn
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BtcDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
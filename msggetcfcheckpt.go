@@ -0,0 +1,63 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"io"
+)
+
+// MsgGetCFCheckpt implements the Message interface and represents a Reddcoin
+// getcfcheckpt message.  It is used to request the filter headers at evenly
+// spaced intervals of CFCheckptInterval blocks up to the block identified by
+// StopHash.  The headers are returned via a cfcheckpt message (MsgCFCheckpt).
+type MsgGetCFCheckpt struct {
+	FilterType FilterType
+	StopHash   ShaHash
+}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCFCheckpt) BtcDecode(r io.Reader, pver uint32) error {
+	err := readElements(r, &msg.FilterType, &msg.StopHash)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCFCheckpt) BtcEncode(w io.Writer, pver uint32) error {
+	err := writeElements(w, msg.FilterType, &msg.StopHash)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetCFCheckpt) Command() string {
+	return CmdGetCFCheckpt
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetCFCheckpt) MaxPayloadLength(pver uint32) uint32 {
+	// Filter type 1 byte + stop hash.
+	return 1 + HashSize
+}
+
+// NewMsgGetCFCheckpt returns a new Reddcoin getcfcheckpt message that conforms
+// to the Message interface using the passed parameters.  See MsgGetCFCheckpt
+// for details.
+func NewMsgGetCFCheckpt(filterType FilterType, stopHash *ShaHash) *MsgGetCFCheckpt {
+	return &MsgGetCFCheckpt{
+		FilterType: filterType,
+		StopHash:   *stopHash,
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetCFCheckpt tests the MsgGetCFCheckpt API.
+func TestGetCFCheckpt(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	msg := rddwire.NewMsgGetCFCheckpt(rddwire.GCSFilterRegular,
+		&mainNetGenesisHash)
+
+	// Ensure the command is expected value.
+	wantCmd := "getcfcheckpt"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgGetCFCheckpt: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	// Filter type 1 byte + stop hash 32 bytes.
+	wantPayload := uint32(33)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure the constructor stored the passed fields.
+	if msg.FilterType != rddwire.GCSFilterRegular ||
+		!msg.StopHash.IsEqual(&mainNetGenesisHash) {
+
+		t.Errorf("NewMsgGetCFCheckpt: wrong fields - got %v",
+			spew.Sdump(msg))
+	}
+}
+
+// TestGetCFCheckptWire tests the MsgGetCFCheckpt wire encode and decode.
+func TestGetCFCheckptWire(t *testing.T) {
+	baseGetCFCheckpt := rddwire.NewMsgGetCFCheckpt(rddwire.GCSFilterRegular,
+		&mainNetGenesisHash)
+	baseGetCFCheckptEncoded := append([]byte{
+		0x00, // Filter type
+	}, mainNetGenesisHash.Bytes()...)
+
+	tests := []struct {
+		in   *rddwire.MsgGetCFCheckpt // Message to encode
+		out  *rddwire.MsgGetCFCheckpt // Expected decoded message
+		buf  []byte                   // Wire encoding
+		pver uint32                   // Protocol version for wire encoding
+	}{
+		// Latest protocol version.
+		{
+			baseGetCFCheckpt,
+			baseGetCFCheckpt,
+			baseGetCFCheckptEncoded,
+			rddwire.ProtocolVersion,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgGetCFCheckpt
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestGetCFCheckptWireErrors performs negative tests against wire encode and
+// decode of MsgGetCFCheckpt to confirm error paths work correctly.
+func TestGetCFCheckptWireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	baseGetCFCheckpt := rddwire.NewMsgGetCFCheckpt(rddwire.GCSFilterRegular,
+		&mainNetGenesisHash)
+	baseGetCFCheckptEncoded := append([]byte{
+		0x00, // Filter type
+	}, mainNetGenesisHash.Bytes()...)
+
+	tests := []struct {
+		in       *rddwire.MsgGetCFCheckpt // Value to encode
+		buf      []byte                   // Wire encoding
+		pver     uint32                   // Protocol version for wire encoding
+		max      int                      // Max size of fixed buffer to induce errors
+		writeErr error                    // Expected write error
+		readErr  error                    // Expected read error
+	}{
+		// Force error in filter type.
+		{baseGetCFCheckpt, baseGetCFCheckptEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in stop hash.
+		{baseGetCFCheckpt, baseGetCFCheckptEncoded, pver, 1, io.ErrShortWrite, io.EOF},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if err != test.writeErr {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgGetCFCheckpt
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if err != test.readErr {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"io"
+)
+
+// MaxGetCFiltersReqRange is the maximum number of filters that may be
+// requested in a single getcfilters message (MsgGetCFilters).
+const MaxGetCFiltersReqRange = 1000
+
+// MsgGetCFilters implements the Message interface and represents a Reddcoin
+// getcfilters message.  It is used to request committed filters for a range
+// of blocks starting at StartHeight and ending at the block identified by
+// StopHash.  Each filter is returned via a cfilter message (MsgCFilter).  The
+// range is limited to MaxGetCFiltersReqRange blocks, however, since the
+// height of StopHash is not known to the message, it is up to the receiver
+// to enforce that limit.
+type MsgGetCFilters struct {
+	FilterType  FilterType
+	StartHeight uint32
+	StopHash    ShaHash
+}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCFilters) BtcDecode(r io.Reader, pver uint32) error {
+	err := readElements(r, &msg.FilterType, &msg.StartHeight, &msg.StopHash)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCFilters) BtcEncode(w io.Writer, pver uint32) error {
+	err := writeElements(w, msg.FilterType, msg.StartHeight, &msg.StopHash)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetCFilters) Command() string {
+	return CmdGetCFilters
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetCFilters) MaxPayloadLength(pver uint32) uint32 {
+	// Filter type 1 byte + start height 4 bytes + stop hash.
+	return 1 + 4 + HashSize
+}
+
+// NewMsgGetCFilters returns a new Reddcoin getcfilters message that conforms
+// to the Message interface using the passed parameters and defaults for the
+// remaining fields.  See MsgGetCFilters for details.
+func NewMsgGetCFilters(filterType FilterType, startHeight uint32,
+	stopHash *ShaHash) *MsgGetCFilters {
+
+	return &MsgGetCFilters{
+		FilterType:  filterType,
+		StartHeight: startHeight,
+		StopHash:    *stopHash,
+	}
+}
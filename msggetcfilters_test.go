@@ -0,0 +1,155 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetCFilters tests the MsgGetCFilters API.
+func TestGetCFilters(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	msg := rddwire.NewMsgGetCFilters(rddwire.GCSFilterRegular, 1,
+		&mainNetGenesisHash)
+
+	// Ensure the command is expected value.
+	wantCmd := "getcfilters"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgGetCFilters: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	// Filter type 1 byte + start height 4 bytes + stop hash 32 bytes.
+	wantPayload := uint32(37)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure the constructor stored the passed fields.
+	if msg.FilterType != rddwire.GCSFilterRegular ||
+		msg.StartHeight != 1 ||
+		!msg.StopHash.IsEqual(&mainNetGenesisHash) {
+
+		t.Errorf("NewMsgGetCFilters: wrong fields - got %v",
+			spew.Sdump(msg))
+	}
+}
+
+// TestGetCFiltersWire tests the MsgGetCFilters wire encode and decode.
+func TestGetCFiltersWire(t *testing.T) {
+	baseGetCFilters := rddwire.NewMsgGetCFilters(rddwire.GCSFilterRegular,
+		0x010203, &mainNetGenesisHash)
+	baseGetCFiltersEncoded := append([]byte{
+		0x00,                   // Filter type
+		0x03, 0x02, 0x01, 0x00, // Start height
+	}, mainNetGenesisHash.Bytes()...)
+
+	tests := []struct {
+		in   *rddwire.MsgGetCFilters // Message to encode
+		out  *rddwire.MsgGetCFilters // Expected decoded message
+		buf  []byte                  // Wire encoding
+		pver uint32                  // Protocol version for wire encoding
+	}{
+		// Latest protocol version.
+		{
+			baseGetCFilters,
+			baseGetCFilters,
+			baseGetCFiltersEncoded,
+			rddwire.ProtocolVersion,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgGetCFilters
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestGetCFiltersWireErrors performs negative tests against wire encode and
+// decode of MsgGetCFilters to confirm error paths work correctly.
+func TestGetCFiltersWireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	baseGetCFilters := rddwire.NewMsgGetCFilters(rddwire.GCSFilterRegular,
+		0x010203, &mainNetGenesisHash)
+	baseGetCFiltersEncoded := append([]byte{
+		0x00,                   // Filter type
+		0x03, 0x02, 0x01, 0x00, // Start height
+	}, mainNetGenesisHash.Bytes()...)
+
+	tests := []struct {
+		in       *rddwire.MsgGetCFilters // Value to encode
+		buf      []byte                  // Wire encoding
+		pver     uint32                  // Protocol version for wire encoding
+		max      int                     // Max size of fixed buffer to induce errors
+		writeErr error                   // Expected write error
+		readErr  error                   // Expected read error
+	}{
+		// Force error in filter type.
+		{baseGetCFilters, baseGetCFiltersEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in start height.
+		{baseGetCFilters, baseGetCFiltersEncoded, pver, 1, io.ErrShortWrite, io.EOF},
+		// Force error in stop hash.
+		{baseGetCFilters, baseGetCFiltersEncoded, pver, 5, io.ErrShortWrite, io.EOF},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver)
+		if err != test.writeErr {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// Decode from wire format.
+		var msg rddwire.MsgGetCFilters
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver)
+		if err != test.readErr {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+	}
+}
@@ -25,6 +25,9 @@ import (
 // most recent 10 block hashes, then double the step each loop iteration to
 // exponentially decrease the number of hashes the further away from head and
 // closer to the genesis block you get.
+//
+// NewBlockLocator builds the block locator hashes this way, and LocateHeaders
+// resolves them into the response.
 type MsgGetHeaders struct {
 	ProtocolVersion    uint32
 	BlockLocatorHashes []*ShaHash
@@ -0,0 +1,91 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"io"
+)
+
+// CmpctBlockVersion is the current latest supported compact block relay
+// version.  It is the version carried by the sendcmpct message (MsgSendCmpct)
+// and determines how short transaction IDs are calculated.
+const CmpctBlockVersion = 1
+
+// MsgSendCmpct implements the Message interface and represents a Reddcoin
+// sendcmpct message.  It is used to negotiate compact block relay with a peer.
+// When Announce is true, the peer is requested to announce new blocks by
+// sending a cmpctblock message (MsgCmpctBlock) directly rather than an inv or
+// headers message.  Version is the compact block relay version the sender
+// supports.
+//
+// This message was not added until protocol version BIP0152Version.
+type MsgSendCmpct struct {
+	Announce bool
+	Version  uint64
+}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgSendCmpct) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("sendcmpct message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgSendCmpct.BtcDecode", str)
+	}
+
+	err := readElements(r, &msg.Announce, &msg.Version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgSendCmpct) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("sendcmpct message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgSendCmpct.BtcEncode", str)
+	}
+
+	err := writeElements(w, msg.Announce, msg.Version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgSendCmpct) Command() string {
+	return CmdSendCmpct
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgSendCmpct) MaxPayloadLength(pver uint32) uint32 {
+	plen := uint32(0)
+	// The sendcmpct message did not exist before protocol version
+	// BIP0152Version.
+	if pver >= BIP0152Version {
+		// Announce 1 byte + Version 8 bytes.
+		plen = 9
+	}
+
+	return plen
+}
+
+// NewMsgSendCmpct returns a new Reddcoin sendcmpct message that conforms to the
+// Message interface.  See MsgSendCmpct for details.
+func NewMsgSendCmpct(announce bool, version uint64) *MsgSendCmpct {
+	return &MsgSendCmpct{
+		Announce: announce,
+		Version:  version,
+	}
+}
@@ -61,6 +61,37 @@ const (
 	// number of transaction outputs 1 byte + LockTime 4 bytes + min input
 	// payload + min output payload.
 	minTxPayload = 10
+
+	// maxWitnessItemsPerInput is the maximum number of witness items to
+	// be read for the witness data for a single transaction input.  This
+	// number is derived using a possible lower bound for the encoding of a
+	// witness item: 1 byte for the length and 1 byte for the item itself,
+	// or two bytes.  MaxBlockPayload is then divided by that lower bound.
+	maxWitnessItemsPerInput = 500000
+
+	// witnessItemsAllocHint is the maximum number of witness items which
+	// are allocated up front when reading the witness data for a single
+	// transaction input.  Larger witness stacks grow as their items are
+	// read, so a small claimed count can't force a large allocation.
+	witnessItemsAllocHint = 64
+
+	// maxWitnessItemSize is the maximum allowed size for an item within
+	// an input's witness data.  This number is derived from the fact that
+	// for script validation, each pushed item onto the stack must be less
+	// than 10k bytes.
+	maxWitnessItemSize = 11000
+)
+
+const (
+	// TxWitnessMarker is the marker byte which follows the version of a
+	// transaction encoded with witness data.  It takes the place of the
+	// number of transaction inputs, which otherwise can't be zero, to
+	// signal the witness encoding.
+	TxWitnessMarker = 0x00
+
+	// TxWitnessFlag is the flag byte which follows the marker of a
+	// transaction encoded with witness data.
+	TxWitnessFlag = 0x01
 )
 
 // OutPoint defines a Reddcoin data type that is used to track previous
@@ -79,15 +110,43 @@ func NewOutPoint(hash *ShaHash, index uint32) *OutPoint {
 	}
 }
 
+// isNull returns whether the outpoint is the null outpoint which is referenced
+// by the input of a coinbase transaction.  Its hash is all zeros and its index
+// is the maximum value.
+func (o *OutPoint) isNull() bool {
+	return o.Index == ^uint32(0) && o.Hash.IsEqual(&ShaHash{})
+}
+
+// TxWitness defines the witness stack for a transaction input.  The witness
+// is encoded after all of the transaction outputs when the transaction is
+// serialized with witness data.
+type TxWitness [][]byte
+
+// SerializeSize returns the number of bytes it would take to serialize the
+// the witness stack.
+func (t TxWitness) SerializeSize() int {
+	// Serialized varint size for the number of items on the stack.
+	n := VarIntSerializeSize(uint64(len(t)))
+
+	// Serialized varint size for the length of each item + item bytes.
+	for _, item := range t {
+		n += VarIntSerializeSize(uint64(len(item))) + len(item)
+	}
+
+	return n
+}
+
 // TxIn defines a Reddcoin transaction input.
 type TxIn struct {
 	PreviousOutPoint OutPoint
 	SignatureScript  []byte
+	Witness          TxWitness
 	Sequence         uint32
 }
 
 // SerializeSize returns the number of bytes it would take to serialize the
-// the transaction input.
+// the transaction input.  The witness stack is not included since it is
+// serialized separately from the input.
 func (t *TxIn) SerializeSize() int {
 	// Outpoint Hash 32 bytes + Outpoint Index 4 bytes + Sequence 4 bytes +
 	// serialized varint size for the length of SignatureScript +
@@ -121,6 +180,12 @@ func (t *TxOut) SerializeSize() int {
 	return 8 + VarIntSerializeSize(uint64(len(t.PkScript))) + len(t.PkScript)
 }
 
+// isEmpty returns whether the output has no value and an empty public key
+// script, which is how the first output of a coinstake transaction is marked.
+func (t *TxOut) isEmpty() bool {
+	return t.Value == 0 && len(t.PkScript) == 0
+}
+
 // NewTxOut returns a new Reddcoin transaction output with the provided
 // transaction value and public key script.
 func NewTxOut(value int64, pkScript []byte) *TxOut {
@@ -157,7 +222,42 @@ func (msg *MsgTx) AddTxOut(to *TxOut) {
 	msg.TxOut = append(msg.TxOut, to)
 }
 
-// TxSha generates the ShaHash name for the transaction.
+// HasWitness returns whether any of the transaction inputs have witness data.
+// Transactions with witness data are encoded with the witness marker and flag.
+func (msg *MsgTx) HasWitness() bool {
+	for _, txIn := range msg.TxIn {
+		if len(txIn.Witness) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsCoinBase determines whether or not the transaction is a coinbase.  A
+// coinbase is a special transaction created by miners and stakers that has no
+// inputs.  This is represented by a single input which references the null
+// outpoint.
+func (msg *MsgTx) IsCoinBase() bool {
+	return len(msg.TxIn) == 1 && msg.TxIn[0].PreviousOutPoint.isNull()
+}
+
+// IsCoinStake determines whether or not the transaction is a coinstake.  A
+// coinstake is the special transaction of a PoSV block which spends the coins
+// staked to create the block.  It has at least one input, the first of which
+// does not reference the null outpoint, and at least two outputs, the first of
+// which is empty to mark the transaction as a coinstake.
+func (msg *MsgTx) IsCoinStake() bool {
+	if len(msg.TxIn) == 0 || msg.TxIn[0].PreviousOutPoint.isNull() {
+		return false
+	}
+
+	return len(msg.TxOut) >= 2 && msg.TxOut[0].isEmpty()
+}
+
+// TxSha generates the ShaHash name for the transaction.  The hash is always
+// calculated over the transaction serialized without witness data, so it is
+// not affected by changes to the witnesses.
 func (msg *MsgTx) TxSha() (ShaHash, error) {
 	// Encode the transaction and calculate double sha256 on the result.
 	// Ignore the error returns since the only way the encode could fail
@@ -165,6 +265,27 @@ func (msg *MsgTx) TxSha() (ShaHash, error) {
 	// cause a run-time panic.  Also, SetBytes can't fail here due to the
 	// fact DoubleSha256 always returns a []byte of the right size
 	// regardless of input.
+	buf := bytes.NewBuffer(make([]byte, 0, msg.SerializeSizeStripped()))
+	_ = msg.SerializeNoWitness(buf)
+	var sha ShaHash
+	_ = sha.SetBytes(DoubleSha256(buf.Bytes()))
+
+	// Even though this function can't currently fail, it still returns
+	// a potential error to help future proof the API should a failure
+	// become possible.
+	return sha, nil
+}
+
+// WitnessHash generates the witness transaction id (wtxid) for the
+// transaction, which is the hash of the transaction serialized with its
+// witness data.  The result is the same as TxSha for transactions without
+// witness data.
+func (msg *MsgTx) WitnessHash() (ShaHash, error) {
+	if !msg.HasWitness() {
+		return msg.TxSha()
+	}
+
+	// See TxSha for why the errors are ignored.
 	buf := bytes.NewBuffer(make([]byte, 0, msg.SerializeSize()))
 	_ = msg.Serialize(buf)
 	var sha ShaHash
@@ -206,11 +327,23 @@ func (msg *MsgTx) Copy() *MsgTx {
 			copy(newScript, oldScript[:oldScriptLen])
 		}
 
+		// Deep copy the old witness stack.
+		var newWitness TxWitness
+		if len(oldTxIn.Witness) > 0 {
+			newWitness = make(TxWitness, len(oldTxIn.Witness))
+			for i, oldItem := range oldTxIn.Witness {
+				newItem := make([]byte, len(oldItem))
+				copy(newItem, oldItem)
+				newWitness[i] = newItem
+			}
+		}
+
 		// Create new txIn with the deep copied data and append it to
 		// new Tx.
 		newTxIn := TxIn{
 			PreviousOutPoint: newOutPoint,
 			SignatureScript:  newScript,
+			Witness:          newWitness,
 			Sequence:         oldTxIn.Sequence,
 		}
 		newTx.TxIn = append(newTx.TxIn, &newTxIn)
@@ -243,6 +376,12 @@ func (msg *MsgTx) Copy() *MsgTx {
 // This is part of the Message interface implementation.
 // See Deserialize for decoding transactions stored to disk, such as in a
 // database, as opposed to decoding transactions from the wire.
+//
+// Both the legacy encoding and the encoding with witness data are accepted.
+// The witness encoding is detected by the witness marker in place of the
+// number of transaction inputs followed by the witness flag.  Note that this
+// means a legacy transaction with no inputs and exactly one output can't be
+// decoded since it is indistinguishable from the witness encoding.
 func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32) error {
 	var buf [4]byte
 	_, err := io.ReadFull(r, buf[:])
@@ -256,6 +395,29 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32) error {
 		return err
 	}
 
+	// A zero input count may be the witness marker, in which case it is
+	// followed by the witness flag and then the real input count.
+	// Otherwise, the byte that was read is the start of the output count
+	// of a legacy transaction with no inputs, so put it back.
+	var witness bool
+	if count == TxWitnessMarker {
+		var flag [1]byte
+		_, err = io.ReadFull(r, flag[:])
+		if err != nil {
+			return err
+		}
+
+		if flag[0] == TxWitnessFlag {
+			witness = true
+			count, err = readVarInt(r, pver)
+			if err != nil {
+				return err
+			}
+		} else {
+			r = io.MultiReader(bytes.NewReader(flag[:]), r)
+		}
+	}
+
 	// Prevent more input transactions than could possibly fit into a
 	// message.  It would be possible to cause memory exhaustion and panics
 	// without a sane upper bound on this count.
@@ -301,6 +463,25 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32) error {
 		msg.TxOut[i] = &to
 	}
 
+	// The witness stack for each input follows the outputs when the
+	// transaction is encoded with witness data.
+	if witness {
+		for _, ti := range msg.TxIn {
+			ti.Witness, err = readTxWitness(r, pver)
+			if err != nil {
+				return err
+			}
+		}
+
+		// A transaction flagged as having witness data must actually
+		// have some since it would otherwise be encoded differently.
+		if !msg.HasWitness() {
+			str := "witness flag set for transaction with no " +
+				"witness data"
+			return messageError("MsgTx.BtcDecode", str)
+		}
+	}
+
 	_, err = io.ReadFull(r, buf[:])
 	if err != nil {
 		return err
@@ -342,7 +523,16 @@ func (msg *MsgTx) Deserialize(r io.Reader) error {
 // This is part of the Message interface implementation.
 // See Serialize for encoding transactions to be stored to disk, such as in a
 // database, as opposed to encoding transactions for the wire.
+//
+// Transactions with witness data are encoded with the witness marker, flag,
+// and witness stacks while all other transactions use the legacy encoding.
 func (msg *MsgTx) BtcEncode(w io.Writer, pver uint32) error {
+	return msg.btcEncode(w, pver, msg.HasWitness())
+}
+
+// btcEncode encodes the receiver to w using the Reddcoin protocol encoding
+// either with or without witness data.
+func (msg *MsgTx) btcEncode(w io.Writer, pver uint32, witness bool) error {
 	var buf [4]byte
 	binary.LittleEndian.PutUint32(buf[:], uint32(msg.Version))
 	_, err := w.Write(buf[:])
@@ -350,6 +540,13 @@ func (msg *MsgTx) BtcEncode(w io.Writer, pver uint32) error {
 		return err
 	}
 
+	if witness {
+		_, err = w.Write([]byte{TxWitnessMarker, TxWitnessFlag})
+		if err != nil {
+			return err
+		}
+	}
+
 	count := uint64(len(msg.TxIn))
 	err = writeVarInt(w, pver, count)
 	if err != nil {
@@ -376,6 +573,15 @@ func (msg *MsgTx) BtcEncode(w io.Writer, pver uint32) error {
 		}
 	}
 
+	if witness {
+		for _, ti := range msg.TxIn {
+			err = writeTxWitness(w, pver, ti.Witness)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	binary.LittleEndian.PutUint32(buf[:], msg.LockTime)
 	_, err = w.Write(buf[:])
 	if err != nil {
@@ -412,9 +618,33 @@ func (msg *MsgTx) Serialize(w io.Writer) error {
 
 }
 
+// SerializeNoWitness encodes the transaction to w in the same manner as
+// Serialize, but always uses the legacy encoding without any witness data.
+// This is the serialization used to calculate the transaction hash.
+func (msg *MsgTx) SerializeNoWitness(w io.Writer) error {
+	return msg.btcEncode(w, 0, false)
+}
+
 // SerializeSize returns the number of bytes it would take to serialize the
-// the transaction.
+// the transaction including any witness data.
 func (msg *MsgTx) SerializeSize() int {
+	n := msg.SerializeSizeStripped()
+
+	if msg.HasWitness() {
+		// Witness marker 1 byte + witness flag 1 byte + witness stack
+		// of every input.
+		n += 2
+		for _, txIn := range msg.TxIn {
+			n += txIn.Witness.SerializeSize()
+		}
+	}
+
+	return n
+}
+
+// SerializeSizeStripped returns the number of bytes it would take to serialize
+// the transaction without any witness data.
+func (msg *MsgTx) SerializeSizeStripped() int {
 	// Version 4 bytes + LockTime 4 bytes + Serialized varint size for the
 	// number of transaction inputs and outputs.
 	n := 8 + VarIntSerializeSize(uint64(len(msg.TxIn))) +
@@ -544,6 +774,61 @@ func writeTxIn(w io.Writer, pver uint32, version int32, ti *TxIn) error {
 	return nil
 }
 
+// readTxWitness reads the next sequence of bytes from r as the witness stack
+// of a transaction input (TxWitness).
+func readTxWitness(r io.Reader, pver uint32) (TxWitness, error) {
+	count, err := readVarInt(r, pver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prevent more witness items than could possibly fit into a block.
+	// It would be possible to cause memory exhaustion and panics without
+	// a sane upper bound on this count.
+	if count > maxWitnessItemsPerInput {
+		str := fmt.Sprintf("too many witness items to fit into max "+
+			"message size [count %d, max %d]", count,
+			maxWitnessItemsPerInput)
+		return nil, messageError("readTxWitness", str)
+	}
+
+	// Only allocate room for a small number of items up front since the
+	// count has not been backed by any data yet.
+	allocCount := count
+	if allocCount > witnessItemsAllocHint {
+		allocCount = witnessItemsAllocHint
+	}
+	witness := make(TxWitness, 0, allocCount)
+	for i := uint64(0); i < count; i++ {
+		item, err := readVarBytes(r, pver, maxWitnessItemSize,
+			"transaction input witness item")
+		if err != nil {
+			return nil, err
+		}
+		witness = append(witness, item)
+	}
+
+	return witness, nil
+}
+
+// writeTxWitness encodes the witness stack of a transaction input (TxWitness)
+// to w.
+func writeTxWitness(w io.Writer, pver uint32, witness TxWitness) error {
+	err := writeVarInt(w, pver, uint64(len(witness)))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range witness {
+		err = writeVarBytes(w, pver, item)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // readTxOut reads the next sequence of bytes from r as a transaction output
 // (TxOut).
 func readTxOut(r io.Reader, pver uint32, version int32, to *TxOut) error {
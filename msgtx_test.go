@@ -621,6 +621,300 @@ func TestTxSerializeSize(t *testing.T) {
 	}
 }
 
+// TestTxWitness tests the MsgTx wire encode and decode of transactions with
+// witness data along with the hashes and sizes derived from them.
+func TestTxWitness(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Ensure the transaction is detected as having witness data.
+	if !witnessTx.HasWitness() {
+		t.Errorf("HasWitness: witness not detected")
+	}
+	if multiTx.HasWitness() {
+		t.Errorf("HasWitness: unexpected witness detected")
+	}
+
+	// Encode the message to wire format.
+	var buf bytes.Buffer
+	err := witnessTx.BtcEncode(&buf, pver)
+	if err != nil {
+		t.Errorf("BtcEncode: %v", err)
+		return
+	}
+	if !bytes.Equal(buf.Bytes(), witnessTxEncoded) {
+		t.Errorf("BtcEncode\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(witnessTxEncoded))
+	}
+
+	// Decode the message from wire format.
+	var msg rddwire.MsgTx
+	err = msg.BtcDecode(bytes.NewReader(witnessTxEncoded), pver)
+	if err != nil {
+		t.Errorf("BtcDecode: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(&msg, witnessTx) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(&msg),
+			spew.Sdump(witnessTx))
+	}
+
+	// Ensure the stripped encoding omits the marker, flag, and witness.
+	buf.Reset()
+	err = witnessTx.SerializeNoWitness(&buf)
+	if err != nil {
+		t.Errorf("SerializeNoWitness: %v", err)
+		return
+	}
+	if !bytes.Equal(buf.Bytes(), witnessTxStripped) {
+		t.Errorf("SerializeNoWitness\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(witnessTxStripped))
+	}
+
+	// Ensure the sizes match the encodings.
+	if size := witnessTx.SerializeSize(); size != len(witnessTxEncoded) {
+		t.Errorf("SerializeSize: got %d, want %d", size,
+			len(witnessTxEncoded))
+	}
+	size := witnessTx.SerializeSizeStripped()
+	if size != len(witnessTxStripped) {
+		t.Errorf("SerializeSizeStripped: got %d, want %d", size,
+			len(witnessTxStripped))
+	}
+	if size := multiTx.SerializeSizeStripped(); size != multiTx.SerializeSize() {
+		t.Errorf("SerializeSizeStripped: got %d, want %d", size,
+			multiTx.SerializeSize())
+	}
+
+	// Ensure the transaction hash commits to the stripped encoding while
+	// the witness hash commits to the full encoding.
+	var wantTxHash, wantWitnessHash rddwire.ShaHash
+	wantTxHash.SetBytes(rddwire.DoubleSha256(witnessTxStripped))
+	wantWitnessHash.SetBytes(rddwire.DoubleSha256(witnessTxEncoded))
+	txHash, err := witnessTx.TxSha()
+	if err != nil {
+		t.Errorf("TxSha: %v", err)
+	}
+	if !txHash.IsEqual(&wantTxHash) {
+		t.Errorf("TxSha: wrong hash - got %v, want %v", txHash,
+			wantTxHash)
+	}
+	witnessHash, err := witnessTx.WitnessHash()
+	if err != nil {
+		t.Errorf("WitnessHash: %v", err)
+	}
+	if !witnessHash.IsEqual(&wantWitnessHash) {
+		t.Errorf("WitnessHash: wrong hash - got %v, want %v",
+			witnessHash, wantWitnessHash)
+	}
+
+	// Ensure the witness hash of a transaction without witness data is
+	// its transaction hash.
+	txHash, _ = multiTx.TxSha()
+	witnessHash, _ = multiTx.WitnessHash()
+	if !witnessHash.IsEqual(&txHash) {
+		t.Errorf("WitnessHash: wrong hash - got %v, want %v",
+			witnessHash, txHash)
+	}
+
+	// Ensure the copy deep copies the witness.
+	txCopy := witnessTx.Copy()
+	if !reflect.DeepEqual(txCopy.TxIn[0].Witness, witnessTx.TxIn[0].Witness) {
+		t.Errorf("Copy\n got: %s want: %s",
+			spew.Sdump(txCopy.TxIn[0].Witness),
+			spew.Sdump(witnessTx.TxIn[0].Witness))
+	}
+	txCopy.TxIn[0].Witness[0][0] ^= 0xff
+	if witnessTx.TxIn[0].Witness[0][0] == txCopy.TxIn[0].Witness[0][0] {
+		t.Errorf("Copy: witness item not deep copied")
+	}
+}
+
+// TestTxWitnessErrors performs negative tests against decoding transactions
+// with witness data to ensure malformed encodings are rejected.
+func TestTxWitnessErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Witness encoding where the witness stack of the only input is empty.
+	noWitness := make([]byte, len(witnessTxEncoded))
+	copy(noWitness, witnessTxEncoded)
+	noWitness = append(noWitness[:len(noWitness)-witnessLen-8],
+		append([]byte{0x00}, noWitness[len(noWitness)-8:]...)...)
+
+	// Witness encoding where the input claims to have more witness items
+	// than could possibly fit into a block.
+	tooManyItems := make([]byte, len(witnessTxEncoded))
+	copy(tooManyItems, witnessTxEncoded)
+	tooManyItems = append(tooManyItems[:len(tooManyItems)-witnessLen-8],
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+
+	// Witness encoding where the input claims to have one more witness
+	// item than the max allowed.
+	overMaxItems := make([]byte, len(witnessTxEncoded))
+	copy(overMaxItems, witnessTxEncoded)
+	overMaxItems = append(overMaxItems[:len(overMaxItems)-witnessLen-8],
+		0xfe, 0x21, 0xa1, 0x07, 0x00)
+
+	// Witness encoding where the input claims to have the max allowed
+	// witness items, but the data ends before the first one.
+	maxItemsTruncated := make([]byte, len(witnessTxEncoded))
+	copy(maxItemsTruncated, witnessTxEncoded)
+	maxItemsTruncated = append(
+		maxItemsTruncated[:len(maxItemsTruncated)-witnessLen-8],
+		0xfe, 0x20, 0xa1, 0x07, 0x00)
+
+	// Witness encoding where the input has a witness item which claims to
+	// be larger than the max allowed.
+	itemTooLarge := make([]byte, len(witnessTxEncoded))
+	copy(itemTooLarge, witnessTxEncoded)
+	itemTooLarge = append(itemTooLarge[:len(itemTooLarge)-witnessLen-8],
+		0x01, 0xfe, 0xff, 0xff, 0xff, 0xff)
+
+	tests := []struct {
+		buf []byte // Wire encoding
+		err error  // Expected error
+	}{
+		{noWitness, &rddwire.MessageError{}},
+		{tooManyItems, &rddwire.MessageError{}},
+		{overMaxItems, &rddwire.MessageError{}},
+		{maxItemsTruncated, io.EOF},
+		{itemTooLarge, &rddwire.MessageError{}},
+		{witnessTxEncoded[:len(witnessTxEncoded)-9], io.ErrUnexpectedEOF},
+		{witnessTxEncoded[:5], io.EOF},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		var msg rddwire.MsgTx
+		err := msg.BtcDecode(bytes.NewReader(test.buf), pver)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, reflect.TypeOf(test.err))
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.err {
+				t.Errorf("BtcDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.err)
+				continue
+			}
+		}
+	}
+}
+
+// TestTxNoInputs ensures legacy transactions with no inputs, whose encoding
+// begins the same way as the witness marker, still decode properly.
+func TestTxNoInputs(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	noInputsTx := rddwire.NewMsgTx()
+	noInputsTx.Version = 1
+	noInputsTx.Timestamp = time.Unix(0, 0)
+	noInputsTx.TxIn = []*rddwire.TxIn{}
+	noInputsTx.AddTxOut(rddwire.NewTxOut(1, []byte{0x51}))
+	noInputsTx.AddTxOut(rddwire.NewTxOut(2, []byte{0x52}))
+	noInputsTxEncoded := []byte{
+		0x01, 0x00, 0x00, 0x00, // Version
+		0x00,                                           // Varint for number of input transactions
+		0x02,                                           // Varint for number of output transactions
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Transaction amount
+		0x01, 0x51, // Pk script
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Transaction amount
+		0x01, 0x52, // Pk script
+		0x00, 0x00, 0x00, 0x00, // Lock time
+	}
+
+	var buf bytes.Buffer
+	err := noInputsTx.BtcEncode(&buf, pver)
+	if err != nil {
+		t.Errorf("BtcEncode: %v", err)
+		return
+	}
+	if !bytes.Equal(buf.Bytes(), noInputsTxEncoded) {
+		t.Errorf("BtcEncode\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(noInputsTxEncoded))
+	}
+
+	var msg rddwire.MsgTx
+	err = msg.BtcDecode(bytes.NewReader(noInputsTxEncoded), pver)
+	if err != nil {
+		t.Errorf("BtcDecode: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(&msg, noInputsTx) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(&msg),
+			spew.Sdump(noInputsTx))
+	}
+}
+
+// TestTxCoinBaseCoinStake tests the classification of transactions as
+// coinbase and coinstake transactions.
+func TestTxCoinBaseCoinStake(t *testing.T) {
+	nullPrevOut := rddwire.OutPoint{Hash: rddwire.ShaHash{}, Index: 0xffffffff}
+	prevOut := rddwire.OutPoint{Hash: rddwire.ShaHash{0x01}, Index: 0}
+	emptyOut := &rddwire.TxOut{Value: 0, PkScript: nil}
+	payOut := &rddwire.TxOut{Value: 1000, PkScript: []byte{0x51}}
+
+	// newTx returns a transaction with the passed inputs and outputs.
+	newTx := func(prevOuts []rddwire.OutPoint, txOuts ...*rddwire.TxOut) *rddwire.MsgTx {
+		tx := rddwire.NewMsgTx()
+		for i := range prevOuts {
+			tx.AddTxIn(rddwire.NewTxIn(&prevOuts[i], nil))
+		}
+		for _, txOut := range txOuts {
+			tx.AddTxOut(txOut)
+		}
+		return tx
+	}
+
+	// Coinbase whose null outpoint has a non-zero hash.
+	notNullHash := rddwire.OutPoint{Hash: rddwire.ShaHash{0x01},
+		Index: 0xffffffff}
+
+	tests := []struct {
+		name        string         // Test description
+		tx          *rddwire.MsgTx // Transaction to classify
+		isCoinBase  bool           // Expected coinbase result
+		isCoinStake bool           // Expected coinstake result
+	}{
+		{"coinbase", multiTx, true, false},
+		{"PoSV coinbase", newTx([]rddwire.OutPoint{nullPrevOut},
+			emptyOut), true, false},
+		{"coinbase with two inputs", newTx([]rddwire.OutPoint{
+			nullPrevOut, prevOut}, payOut), false, false},
+		{"not null hash", newTx([]rddwire.OutPoint{notNullHash},
+			payOut), false, false},
+		{"coinstake", newTx([]rddwire.OutPoint{prevOut}, emptyOut,
+			payOut), false, true},
+		{"coinstake with two inputs", newTx([]rddwire.OutPoint{prevOut,
+			prevOut}, emptyOut, payOut, payOut), false, true},
+		{"coinstake spending null outpoint", newTx(
+			[]rddwire.OutPoint{nullPrevOut}, emptyOut, payOut), true,
+			false},
+		{"no inputs", newTx(nil, emptyOut, payOut), false, false},
+		{"one output", newTx([]rddwire.OutPoint{prevOut}, emptyOut),
+			false, false},
+		{"first output not empty", newTx([]rddwire.OutPoint{prevOut},
+			payOut, payOut), false, false},
+		{"first output has value", newTx([]rddwire.OutPoint{prevOut},
+			&rddwire.TxOut{Value: 1}, payOut), false, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		if got := test.tx.IsCoinBase(); got != test.isCoinBase {
+			t.Errorf("IsCoinBase %s: got %v, want %v", test.name,
+				got, test.isCoinBase)
+		}
+		if got := test.tx.IsCoinStake(); got != test.isCoinStake {
+			t.Errorf("IsCoinStake %s: got %v, want %v", test.name,
+				got, test.isCoinStake)
+		}
+	}
+}
+
 // multiTx is a MsgTx with an input and output and used in various tests.
 var multiTx = &rddwire.MsgTx{
 	Version: 2,
@@ -688,3 +982,99 @@ var multiTxEncoded = []byte{
 	0x00, 0x00, 0x00, 0x00, // Lock time
 	0xbf, 0xc6, 0xce, 0x53, // Timestamp
 }
+
+// witnessTx is a MsgTx with an input that has witness data and is used in
+// various tests.
+var witnessTx = &rddwire.MsgTx{
+	Version: 2,
+	TxIn: []*rddwire.TxIn{
+		{
+			PreviousOutPoint: rddwire.OutPoint{
+				Hash: rddwire.ShaHash{
+					0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+					0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+					0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+					0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+				},
+				Index: 1,
+			},
+			SignatureScript: []byte{},
+			Witness: rddwire.TxWitness{
+				{0x30, 0x02, 0x01, 0x01, 0x01},
+				{0x02, 0xaa, 0xbb},
+			},
+			Sequence: 0xffffffff,
+		},
+	},
+	TxOut: []*rddwire.TxOut{
+		{
+			Value: 0x5f5e100,
+			PkScript: []byte{
+				0x00, // OP_0
+				0x14, // OP_DATA_20
+				0xc1, 0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7, 0xc8,
+				0xc9, 0xca, 0xcb, 0xcc, 0xcd, 0xce, 0xcf, 0xd0,
+				0xd1, 0xd2, 0xd3, 0xd4,
+			},
+		},
+	},
+	LockTime:  0,
+	Timestamp: time.Unix(1406060223, 0),
+}
+
+// witnessLen is the number of bytes the witness of the only input of witnessTx
+// takes in its encoding.
+const witnessLen = 11
+
+// witnessTxEncoded is the wire encoded bytes for witnessTx including the
+// witness data.
+var witnessTxEncoded = []byte{
+	0x02, 0x00, 0x00, 0x00, // Version
+	0x00, // Witness marker
+	0x01, // Witness flag
+	0x01, // Varint for number of input transactions
+	0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+	0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+	0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+	0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20, // Previous output hash
+	0x01, 0x00, 0x00, 0x00, // Previous output index
+	0x00,                   // Varint for length of signature script
+	0xff, 0xff, 0xff, 0xff, // Sequence
+	0x01,                                           // Varint for number of output transactions
+	0x00, 0xe1, 0xf5, 0x05, 0x00, 0x00, 0x00, 0x00, // Transaction amount
+	0x16, // Varint for length of pk script
+	0x00, // OP_0
+	0x14, // OP_DATA_20
+	0xc1, 0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7, 0xc8,
+	0xc9, 0xca, 0xcb, 0xcc, 0xcd, 0xce, 0xcf, 0xd0,
+	0xd1, 0xd2, 0xd3, 0xd4,
+	0x02,                               // Varint for number of witness items
+	0x05, 0x30, 0x02, 0x01, 0x01, 0x01, // Witness item
+	0x03, 0x02, 0xaa, 0xbb, // Witness item
+	0x00, 0x00, 0x00, 0x00, // Lock time
+	0xbf, 0xc6, 0xce, 0x53, // Timestamp
+}
+
+// witnessTxStripped is the wire encoded bytes for witnessTx without the
+// witness data.
+var witnessTxStripped = []byte{
+	0x02, 0x00, 0x00, 0x00, // Version
+	0x01, // Varint for number of input transactions
+	0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+	0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+	0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+	0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20, // Previous output hash
+	0x01, 0x00, 0x00, 0x00, // Previous output index
+	0x00,                   // Varint for length of signature script
+	0xff, 0xff, 0xff, 0xff, // Sequence
+	0x01,                                           // Varint for number of output transactions
+	0x00, 0xe1, 0xf5, 0x05, 0x00, 0x00, 0x00, 0x00, // Transaction amount
+	0x16, // Varint for length of pk script
+	0x00, // OP_0
+	0x14, // OP_DATA_20
+	0xc1, 0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7, 0xc8,
+	0xc9, 0xca, 0xcb, 0xcc, 0xcd, 0xce, 0xcf, 0xd0,
+	0xd1, 0xd2, 0xd3, 0xd4,
+	0x00, 0x00, 0x00, 0x00, // Lock time
+	0xbf, 0xc6, 0xce, 0x53, // Timestamp
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+	"io"
+)
+
+// MsgWtxidRelay implements the Message interface and represents a Reddcoin
+// wtxidrelay message as defined by BIP0339.  It is used to signal the peer
+// that transactions should be announced and requested by their witness
+// transaction id (wtxid) using InvTypeWtx inventory vectors rather than by
+// their transaction id.  It must be sent before the verack message.
+//
+// This message has no payload and was not added until protocol versions
+// starting with WtxidRelayVersion.
+type MsgWtxidRelay struct{}
+
+// BtcDecode decodes r using the Reddcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgWtxidRelay) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < WtxidRelayVersion {
+		str := fmt.Sprintf("wtxidrelay message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgWtxidRelay.BtcDecode", str)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Reddcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgWtxidRelay) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < WtxidRelayVersion {
+		str := fmt.Sprintf("wtxidrelay message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgWtxidRelay.BtcEncode", str)
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgWtxidRelay) Command() string {
+	return CmdWtxidRelay
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgWtxidRelay) MaxPayloadLength(pver uint32) uint32 {
+	return 0
+}
+
+// NewMsgWtxidRelay returns a new Reddcoin wtxidrelay message that conforms to
+// the Message interface.  See MsgWtxidRelay for details.
+func NewMsgWtxidRelay() *MsgWtxidRelay {
+	return &MsgWtxidRelay{}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestWtxidRelay tests the MsgWtxidRelay API against the latest protocol
+// version.
+func TestWtxidRelay(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "wtxidrelay"
+	msg := rddwire.NewMsgWtxidRelay()
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgWtxidRelay: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value.
+	wantPayload := uint32(0)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Test encode with latest protocol version.
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, pver)
+	if err != nil {
+		t.Errorf("encode of MsgWtxidRelay failed %v err <%v>", msg, err)
+	}
+
+	// Older protocol versions should fail encode since message didn't
+	// exist yet.
+	oldPver := rddwire.WtxidRelayVersion - 1
+	err = msg.BtcEncode(&buf, oldPver)
+	if err == nil {
+		s := "encode of MsgWtxidRelay passed for old protocol version %v err <%v>"
+		t.Errorf(s, msg, err)
+	}
+
+	// Test decode with latest protocol version.
+	readmsg := rddwire.NewMsgWtxidRelay()
+	err = readmsg.BtcDecode(&buf, pver)
+	if err != nil {
+		t.Errorf("decode of MsgWtxidRelay failed [%v] err <%v>", buf, err)
+	}
+
+	// Older protocol versions should fail decode since message didn't
+	// exist yet.
+	err = readmsg.BtcDecode(&buf, oldPver)
+	if err == nil {
+		s := "decode of MsgWtxidRelay passed for old protocol version %v err <%v>"
+		t.Errorf(s, msg, err)
+	}
+
+	return
+}
+
+// TestWtxidRelayCrossProtocol tests the MsgWtxidRelay API when encoding with
+// the latest protocol version and decoding with RejectVersion.
+func TestWtxidRelayCrossProtocol(t *testing.T) {
+	msg := rddwire.NewMsgWtxidRelay()
+
+	// Encode with latest protocol version.
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, rddwire.ProtocolVersion)
+	if err != nil {
+		t.Errorf("encode of MsgWtxidRelay failed %v err <%v>", msg, err)
+	}
+
+	// Decode with old protocol version.
+	var readmsg rddwire.MsgWtxidRelay
+	err = readmsg.BtcDecode(&buf, rddwire.RejectVersion)
+	if err == nil {
+		t.Errorf("decode of MsgWtxidRelay succeeded when it "+
+			"shouldn't have %v", msg)
+	}
+}
+
+// TestWtxidRelayWire tests the MsgWtxidRelay wire encode and decode for
+// various protocol versions.
+func TestWtxidRelayWire(t *testing.T) {
+	msgWtxidRelay := rddwire.NewMsgWtxidRelay()
+	msgWtxidRelayEncoded := []byte{}
+
+	tests := []struct {
+		in   *rddwire.MsgWtxidRelay // Message to encode
+		out  *rddwire.MsgWtxidRelay // Expected decoded message
+		buf  []byte                 // Wire encoding
+		pver uint32                 // Protocol version for wire encoding
+	}{
+		// Latest protocol version.
+		{
+			msgWtxidRelay,
+			msgWtxidRelay,
+			msgWtxidRelayEncoded,
+			rddwire.ProtocolVersion,
+		},
+
+		// Protocol version WtxidRelayVersion.
+		{
+			msgWtxidRelay,
+			msgWtxidRelay,
+			msgWtxidRelayEncoded,
+			rddwire.WtxidRelayVersion,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, test.pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg rddwire.MsgWtxidRelay
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BtcDecode(rbuf, test.pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// MaxAddrV2Size is the maximum number of bytes an address of any network may
+// have when encoded in a NetAddressV2 as defined by BIP0155.
+const MaxAddrV2Size = 512
+
+// ErrAddrV2NotIP describes an error that indicates a NetAddressV2 can't be
+// converted to a NetAddress since it is for a network which has no IPv6
+// representation such as Tor v3 or I2P.
+var ErrAddrV2NotIP = errors.New("address network has no NetAddress " +
+	"representation")
+
+// onionCatPrefix is the IPv6 prefix used by OnionCat to map Tor v2 hidden
+// service addresses into the fd87:d87e:eb43::/48 range.
+var onionCatPrefix = []byte{0xfd, 0x87, 0xd8, 0x7e, 0xeb, 0x43}
+
+// AddrNetworkID identifies the network an address in a NetAddressV2 belongs to
+// as defined by BIP0155.
+type AddrNetworkID uint8
+
+// Constants for the network IDs defined by BIP0155.
+const (
+	// AddrNetIPv4 identifies an IPv4 address (4 bytes).
+	AddrNetIPv4 AddrNetworkID = 0x01
+
+	// AddrNetIPv6 identifies an IPv6 address (16 bytes).
+	AddrNetIPv6 AddrNetworkID = 0x02
+
+	// AddrNetTorV2 identifies a Tor v2 hidden service address (10 bytes).
+	AddrNetTorV2 AddrNetworkID = 0x03
+
+	// AddrNetTorV3 identifies a Tor v3 hidden service public key
+	// (32 bytes).
+	AddrNetTorV3 AddrNetworkID = 0x04
+
+	// AddrNetI2P identifies the SHA256 hash of an I2P destination
+	// (32 bytes).
+	AddrNetI2P AddrNetworkID = 0x05
+
+	// AddrNetCJDNS identifies a CJDNS address (16 bytes).
+	AddrNetCJDNS AddrNetworkID = 0x06
+)
+
+// addrNetSizes maps each known network ID to the required length of its
+// addresses.
+var addrNetSizes = map[AddrNetworkID]int{
+	AddrNetIPv4:  4,
+	AddrNetIPv6:  16,
+	AddrNetTorV2: 10,
+	AddrNetTorV3: 32,
+	AddrNetI2P:   32,
+	AddrNetCJDNS: 16,
+}
+
+// Map of network IDs back to their constant names for pretty printing.
+var anStrings = map[AddrNetworkID]string{
+	AddrNetIPv4:  "AddrNetIPv4",
+	AddrNetIPv6:  "AddrNetIPv6",
+	AddrNetTorV2: "AddrNetTorV2",
+	AddrNetTorV3: "AddrNetTorV3",
+	AddrNetI2P:   "AddrNetI2P",
+	AddrNetCJDNS: "AddrNetCJDNS",
+}
+
+// String returns the AddrNetworkID in human-readable form.
+func (id AddrNetworkID) String() string {
+	if s, ok := anStrings[id]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("Unknown AddrNetworkID (%d)", uint8(id))
+}
+
+// IsKnown returns whether the network ID is one of the networks defined by
+// BIP0155.
+func (id AddrNetworkID) IsKnown() bool {
+	_, ok := addrNetSizes[id]
+	return ok
+}
+
+// maxNetAddressV2Payload returns the max payload size for a Reddcoin
+// NetAddressV2.
+func maxNetAddressV2Payload() uint32 {
+	// Timestamp 4 bytes + services (varInt) + network id 1 byte +
+	// address length (varInt) + address + port 2 bytes.
+	return 4 + MaxVarIntPayload + 1 + uint32(VarIntSerializeSize(MaxAddrV2Size)) +
+		MaxAddrV2Size + 2
+}
+
+// NetAddressV2 defines information about a peer on the network as relayed by
+// the addrv2 message (MsgAddrV2).  Unlike NetAddress, the address is tagged
+// with the network it belongs to which allows addresses that have no IPv6
+// representation, such as Tor v3 and I2P, to be described.
+type NetAddressV2 struct {
+	// Last time the address was seen.  This is encoded as a uint32 on the
+	// wire and therefore is limited to 2106.
+	Timestamp time.Time
+
+	// Bitfield which identifies the services supported by the address.
+	// This is encoded as a variable length integer on the wire.
+	Services ServiceFlag
+
+	// Network the address belongs to.
+	NetworkID AddrNetworkID
+
+	// Raw address bytes.  The length depends on NetworkID.
+	Addr []byte
+
+	// Port the peer is using.  This is encoded in big endian on the wire.
+	Port uint16
+}
+
+// HasService returns whether the specified service is supported by the address.
+func (na *NetAddressV2) HasService(service ServiceFlag) bool {
+	if na.Services&service == service {
+		return true
+	}
+	return false
+}
+
+// AddService adds service as a supported service by the peer generating the
+// message.
+func (na *NetAddressV2) AddService(service ServiceFlag) {
+	na.Services |= service
+}
+
+// ToNetAddress converts the address to a NetAddress.  IPv4, IPv6, and CJDNS
+// addresses are converted directly while Tor v2 addresses are mapped into the
+// OnionCat IPv6 range.  ErrAddrV2NotIP is returned for any other networks
+// since they can't be represented by a NetAddress.
+func (na *NetAddressV2) ToNetAddress() (*NetAddress, error) {
+	if size, ok := addrNetSizes[na.NetworkID]; ok && len(na.Addr) != size {
+		str := fmt.Sprintf("%v address has wrong length [len %v, "+
+			"want %v]", na.NetworkID, len(na.Addr), size)
+		return nil, messageError("NetAddressV2.ToNetAddress", str)
+	}
+
+	var ip net.IP
+	switch na.NetworkID {
+	case AddrNetIPv4, AddrNetIPv6, AddrNetCJDNS:
+		ip = make(net.IP, len(na.Addr))
+		copy(ip, na.Addr)
+
+	case AddrNetTorV2:
+		ip = make(net.IP, net.IPv6len)
+		copy(ip, onionCatPrefix)
+		copy(ip[len(onionCatPrefix):], na.Addr)
+
+	default:
+		return nil, ErrAddrV2NotIP
+	}
+
+	return &NetAddress{
+		Timestamp: na.Timestamp,
+		Services:  na.Services,
+		IP:        ip,
+		Port:      na.Port,
+	}, nil
+}
+
+// NewNetAddressV2 returns a new NetAddressV2 using the provided network ID,
+// raw address, port, and supported services with defaults for the remaining
+// fields.  An error is returned if the length of the address is not valid for
+// a known network ID.
+func NewNetAddressV2(networkID AddrNetworkID, addr []byte, port uint16,
+	services ServiceFlag) (*NetAddressV2, error) {
+
+	if len(addr) > MaxAddrV2Size {
+		str := fmt.Sprintf("address is too long [len %v, max %v]",
+			len(addr), MaxAddrV2Size)
+		return nil, messageError("NewNetAddressV2", str)
+	}
+	if size, ok := addrNetSizes[networkID]; ok && len(addr) != size {
+		str := fmt.Sprintf("%v address has wrong length [len %v, "+
+			"want %v]", networkID, len(addr), size)
+		return nil, messageError("NewNetAddressV2", str)
+	}
+
+	// Limit the timestamp to one second precision since the protocol
+	// doesn't support better.
+	na := NetAddressV2{
+		Timestamp: time.Unix(time.Now().Unix(), 0),
+		Services:  services,
+		NetworkID: networkID,
+		Addr:      addr,
+		Port:      port,
+	}
+	return &na, nil
+}
+
+// NewNetAddressV2FromNetAddress returns a new NetAddressV2 which describes the
+// same peer as the provided NetAddress.  IPv4 and IPv4-mapped IPv6 addresses
+// use the IPv4 network, addresses in the OnionCat range use the Tor v2 network,
+// and all other addresses use the IPv6 network.
+func NewNetAddressV2FromNetAddress(na *NetAddress) *NetAddressV2 {
+	var networkID AddrNetworkID
+	var addr []byte
+	ip := na.IP.To16()
+	switch {
+	case na.IP.To4() != nil:
+		networkID = AddrNetIPv4
+		addr = make([]byte, net.IPv4len)
+		copy(addr, na.IP.To4())
+
+	case ip != nil && bytes.HasPrefix(ip, onionCatPrefix):
+		networkID = AddrNetTorV2
+		addr = make([]byte, net.IPv6len-len(onionCatPrefix))
+		copy(addr, ip[len(onionCatPrefix):])
+
+	default:
+		// Ensure to always use 16 bytes even if the ip is nil.
+		networkID = AddrNetIPv6
+		addr = make([]byte, net.IPv6len)
+		copy(addr, ip)
+	}
+
+	return &NetAddressV2{
+		Timestamp: na.Timestamp,
+		Services:  na.Services,
+		NetworkID: networkID,
+		Addr:      addr,
+		Port:      na.Port,
+	}
+}
+
+// readNetAddressV2 reads an encoded NetAddressV2 from r.  An error is returned
+// if the address exceeds MaxAddrV2Size or does not have the required length
+// for a known network ID.  Addresses for unknown network IDs are decoded
+// as-is.
+func readNetAddressV2(r io.Reader, pver uint32, na *NetAddressV2) error {
+	var stamp uint32
+	err := readElement(r, &stamp)
+	if err != nil {
+		return err
+	}
+
+	services, err := readVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+
+	var networkID [1]byte
+	_, err = io.ReadFull(r, networkID[:])
+	if err != nil {
+		return err
+	}
+
+	addr, err := readVarBytes(r, pver, MaxAddrV2Size, "NetAddressV2.Addr")
+	if err != nil {
+		return err
+	}
+	id := AddrNetworkID(networkID[0])
+	if size, ok := addrNetSizes[id]; ok && len(addr) != size {
+		str := fmt.Sprintf("%v address has wrong length [len %v, "+
+			"want %v]", id, len(addr), size)
+		return messageError("readNetAddressV2", str)
+	}
+
+	// Sigh.  Reddcoin protocol mixes little and big endian.
+	var port uint16
+	err = binary.Read(r, binary.BigEndian, &port)
+	if err != nil {
+		return err
+	}
+
+	na.Timestamp = time.Unix(int64(stamp), 0)
+	na.Services = ServiceFlag(services)
+	na.NetworkID = id
+	na.Addr = addr
+	na.Port = port
+	return nil
+}
+
+// writeNetAddressV2 serializes a NetAddressV2 to w.
+func writeNetAddressV2(w io.Writer, pver uint32, na *NetAddressV2) error {
+	if len(na.Addr) > MaxAddrV2Size {
+		str := fmt.Sprintf("address is too long [len %v, max %v]",
+			len(na.Addr), MaxAddrV2Size)
+		return messageError("writeNetAddressV2", str)
+	}
+	if size, ok := addrNetSizes[na.NetworkID]; ok && len(na.Addr) != size {
+		str := fmt.Sprintf("%v address has wrong length [len %v, "+
+			"want %v]", na.NetworkID, len(na.Addr), size)
+		return messageError("writeNetAddressV2", str)
+	}
+
+	// NOTE: The Reddcoin protocol uses a uint32 for the timestamp so it will
+	// stop working somewhere around 2106.
+	err := writeElement(w, uint32(na.Timestamp.Unix()))
+	if err != nil {
+		return err
+	}
+
+	err = writeVarInt(w, pver, uint64(na.Services))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte{byte(na.NetworkID)})
+	if err != nil {
+		return err
+	}
+
+	err = writeVarBytes(w, pver, na.Addr)
+	if err != nil {
+		return err
+	}
+
+	// Sigh.  Reddcoin protocol mixes little and big endian.
+	err = binary.Write(w, binary.BigEndian, na.Port)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
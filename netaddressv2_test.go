@@ -0,0 +1,449 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestNetAddressV2 tests the NetAddressV2 API.
+func TestNetAddressV2(t *testing.T) {
+	addr := []byte{0x7f, 0x00, 0x00, 0x01}
+	port := uint16(45444)
+
+	// Test NewNetAddressV2.
+	na, err := rddwire.NewNetAddressV2(rddwire.AddrNetIPv4, addr, port, 0)
+	if err != nil {
+		t.Errorf("NewNetAddressV2: %v", err)
+	}
+
+	// Ensure we get the same network, address, port, and services back out.
+	if na.NetworkID != rddwire.AddrNetIPv4 {
+		t.Errorf("NewNetAddressV2: wrong network - got %v, want %v",
+			na.NetworkID, rddwire.AddrNetIPv4)
+	}
+	if !bytes.Equal(na.Addr, addr) {
+		t.Errorf("NewNetAddressV2: wrong addr - got %x, want %x",
+			na.Addr, addr)
+	}
+	if na.Port != port {
+		t.Errorf("NewNetAddressV2: wrong port - got %v, want %v",
+			na.Port, port)
+	}
+	if na.Services != 0 {
+		t.Errorf("NewNetAddressV2: wrong services - got %v, want %v",
+			na.Services, 0)
+	}
+	if na.HasService(rddwire.SFNodeNetwork) {
+		t.Errorf("HasService: SFNodeNetwork service is set")
+	}
+
+	// Ensure adding the full service node flag works.
+	na.AddService(rddwire.SFNodeNetwork)
+	if na.Services != rddwire.SFNodeNetwork {
+		t.Errorf("AddService: wrong services - got %v, want %v",
+			na.Services, rddwire.SFNodeNetwork)
+	}
+	if !na.HasService(rddwire.SFNodeNetwork) {
+		t.Errorf("HasService: SFNodeNetwork service not set")
+	}
+
+	// Ensure addresses with the wrong length for a known network and
+	// addresses which are too long are rejected.
+	_, err = rddwire.NewNetAddressV2(rddwire.AddrNetTorV3, addr, port, 0)
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("NewNetAddressV2: expected error for short Tor v3 "+
+			"address - got %v", err)
+	}
+	_, err = rddwire.NewNetAddressV2(rddwire.AddrNetworkID(0xff),
+		make([]byte, rddwire.MaxAddrV2Size+1), port, 0)
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("NewNetAddressV2: expected error for oversized "+
+			"address - got %v", err)
+	}
+
+	// Ensure unknown networks are allowed with any valid length.
+	_, err = rddwire.NewNetAddressV2(rddwire.AddrNetworkID(0xff),
+		make([]byte, 7), port, 0)
+	if err != nil {
+		t.Errorf("NewNetAddressV2: unexpected error for unknown "+
+			"network - got %v", err)
+	}
+}
+
+// TestAddrNetworkIDStringer tests the stringized output for AddrNetworkID
+// types.
+func TestAddrNetworkIDStringer(t *testing.T) {
+	tests := []struct {
+		in    rddwire.AddrNetworkID
+		want  string
+		known bool
+	}{
+		{rddwire.AddrNetIPv4, "AddrNetIPv4", true},
+		{rddwire.AddrNetIPv6, "AddrNetIPv6", true},
+		{rddwire.AddrNetTorV2, "AddrNetTorV2", true},
+		{rddwire.AddrNetTorV3, "AddrNetTorV3", true},
+		{rddwire.AddrNetI2P, "AddrNetI2P", true},
+		{rddwire.AddrNetCJDNS, "AddrNetCJDNS", true},
+		{0xff, "Unknown AddrNetworkID (255)", false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := test.in.String()
+		if result != test.want {
+			t.Errorf("String #%d\n got: %s want: %s", i, result,
+				test.want)
+			continue
+		}
+		if test.in.IsKnown() != test.known {
+			t.Errorf("IsKnown #%d\n got: %v want: %v", i,
+				test.in.IsKnown(), test.known)
+			continue
+		}
+	}
+}
+
+// TestNetAddressV2Conversion tests conversion between NetAddressV2 and
+// NetAddress.
+func TestNetAddressV2Conversion(t *testing.T) {
+	ts := time.Unix(0x495fab29, 0) // 2009-01-03 12:15:05 -0600 CST
+	torV2 := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a}
+
+	tests := []struct {
+		ip        net.IP                // NetAddress IP
+		networkID rddwire.AddrNetworkID // Expected network ID
+		addr      []byte                // Expected raw address
+	}{
+		// IPv4 and IPv4-mapped IPv6.
+		{
+			net.IPv4(192, 168, 0, 1).To4(),
+			rddwire.AddrNetIPv4,
+			[]byte{0xc0, 0xa8, 0x00, 0x01},
+		},
+		{
+			net.ParseIP("192.168.0.1"),
+			rddwire.AddrNetIPv4,
+			[]byte{0xc0, 0xa8, 0x00, 0x01},
+		},
+
+		// IPv6.
+		{
+			net.ParseIP("2001:db8::1"),
+			rddwire.AddrNetIPv6,
+			[]byte{
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+			},
+		},
+
+		// Tor v2 via OnionCat.
+		{
+			net.ParseIP("fd87:d87e:eb43:102:304:506:708:90a"),
+			rddwire.AddrNetTorV2,
+			torV2,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		na := &rddwire.NetAddress{
+			Timestamp: ts,
+			Services:  rddwire.SFNodeNetwork,
+			IP:        test.ip,
+			Port:      45444,
+		}
+
+		// Convert to NetAddressV2.
+		nav2 := rddwire.NewNetAddressV2FromNetAddress(na)
+		if nav2.NetworkID != test.networkID {
+			t.Errorf("NewNetAddressV2FromNetAddress #%d wrong "+
+				"network - got %v, want %v", i, nav2.NetworkID,
+				test.networkID)
+			continue
+		}
+		if !bytes.Equal(nav2.Addr, test.addr) {
+			t.Errorf("NewNetAddressV2FromNetAddress #%d wrong "+
+				"addr - got %x, want %x", i, nav2.Addr, test.addr)
+			continue
+		}
+		if !nav2.Timestamp.Equal(ts) || nav2.Port != na.Port ||
+			nav2.Services != na.Services {
+			t.Errorf("NewNetAddressV2FromNetAddress #%d wrong "+
+				"fields - got %v", i, spew.Sdump(nav2))
+			continue
+		}
+
+		// Convert back to NetAddress.
+		na2, err := nav2.ToNetAddress()
+		if err != nil {
+			t.Errorf("ToNetAddress #%d error %v", i, err)
+			continue
+		}
+		if !na2.IP.Equal(na.IP) {
+			t.Errorf("ToNetAddress #%d wrong ip - got %v, want %v",
+				i, na2.IP, na.IP)
+			continue
+		}
+		if !na2.Timestamp.Equal(ts) || na2.Port != na.Port ||
+			na2.Services != na.Services {
+			t.Errorf("ToNetAddress #%d wrong fields - got %v", i,
+				spew.Sdump(na2))
+			continue
+		}
+	}
+
+	// Ensure CJDNS addresses convert to their IPv6 form.
+	cjdns := net.ParseIP("fc00::1")
+	nav2 := &rddwire.NetAddressV2{NetworkID: rddwire.AddrNetCJDNS, Addr: cjdns}
+	na, err := nav2.ToNetAddress()
+	if err != nil {
+		t.Errorf("ToNetAddress: unexpected error for CJDNS - %v", err)
+	} else if !na.IP.Equal(cjdns) {
+		t.Errorf("ToNetAddress: wrong CJDNS ip - got %v, want %v",
+			na.IP, cjdns)
+	}
+
+	// Ensure networks without an IPv6 representation are rejected.
+	for _, id := range []rddwire.AddrNetworkID{rddwire.AddrNetTorV3,
+		rddwire.AddrNetI2P} {
+
+		nav2 := &rddwire.NetAddressV2{NetworkID: id, Addr: make([]byte, 32)}
+		_, err := nav2.ToNetAddress()
+		if err != rddwire.ErrAddrV2NotIP {
+			t.Errorf("ToNetAddress: wrong error for %v - got %v, "+
+				"want %v", id, err, rddwire.ErrAddrV2NotIP)
+		}
+	}
+
+	// Ensure addresses with the wrong length are rejected.
+	nav2 = &rddwire.NetAddressV2{NetworkID: rddwire.AddrNetIPv4, Addr: cjdns}
+	_, err = nav2.ToNetAddress()
+	if _, ok := err.(*rddwire.MessageError); !ok {
+		t.Errorf("ToNetAddress: expected error for bad IPv4 length - "+
+			"got %v", err)
+	}
+}
+
+// TestNetAddressV2Wire tests the NetAddressV2 wire encode and decode for
+// various networks.
+func TestNetAddressV2Wire(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+
+	// ipv4NetAddr is a NetAddressV2 for an IPv4 address.
+	ipv4NetAddr := rddwire.NetAddressV2{
+		Timestamp: time.Unix(0x495fab29, 0), // 2009-01-03 12:15:05 -0600 CST
+		Services:  rddwire.SFNodeNetwork,
+		NetworkID: rddwire.AddrNetIPv4,
+		Addr:      []byte{0x7f, 0x00, 0x00, 0x01},
+		Port:      45444,
+	}
+	ipv4NetAddrEncoded := []byte{
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,                   // Varint for services
+		0x01,                   // Network ID (IPv4)
+		0x04,                   // Varint for address length
+		0x7f, 0x00, 0x00, 0x01, // IP 127.0.0.1
+		0xb1, 0x84, // Port 45444 in big-endian
+	}
+
+	// torV3NetAddr is a NetAddressV2 for a Tor v3 hidden service.
+	torV3Key := bytes.Repeat([]byte{0xab}, 32)
+	torV3NetAddr := rddwire.NetAddressV2{
+		Timestamp: time.Unix(0x495fab29, 0), // 2009-01-03 12:15:05 -0600 CST
+		Services:  rddwire.ServiceFlag(0x0409),
+		NetworkID: rddwire.AddrNetTorV3,
+		Addr:      torV3Key,
+		Port:      9050,
+	}
+	torV3NetAddrEncoded := append([]byte{
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0xfd, 0x09, 0x04, // Varint for services
+		0x04, // Network ID (TorV3)
+		0x20, // Varint for address length
+	}, append(torV3Key,
+		0x23, 0x5a, // Port 9050 in big-endian
+	)...)
+
+	// unknownNetAddr is a NetAddressV2 for an unknown network.
+	unknownNetAddr := rddwire.NetAddressV2{
+		Timestamp: time.Unix(0x495fab29, 0), // 2009-01-03 12:15:05 -0600 CST
+		Services:  0,
+		NetworkID: rddwire.AddrNetworkID(0x2a),
+		Addr:      []byte{0x01, 0x02, 0x03},
+		Port:      1,
+	}
+	unknownNetAddrEncoded := []byte{
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x00,             // Varint for services
+		0x2a,             // Network ID (unknown)
+		0x03,             // Varint for address length
+		0x01, 0x02, 0x03, // Address
+		0x00, 0x01, // Port 1 in big-endian
+	}
+
+	tests := []struct {
+		in  rddwire.NetAddressV2 // NetAddressV2 to encode
+		out rddwire.NetAddressV2 // Expected decoded NetAddressV2
+		buf []byte               // Wire encoding
+	}{
+		{ipv4NetAddr, ipv4NetAddr, ipv4NetAddrEncoded},
+		{torV3NetAddr, torV3NetAddr, torV3NetAddrEncoded},
+		{unknownNetAddr, unknownNetAddr, unknownNetAddrEncoded},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		var buf bytes.Buffer
+		err := rddwire.TstWriteNetAddressV2(&buf, pver, &test.in)
+		if err != nil {
+			t.Errorf("writeNetAddressV2 #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("writeNetAddressV2 #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var na rddwire.NetAddressV2
+		rbuf := bytes.NewReader(test.buf)
+		err = rddwire.TstReadNetAddressV2(rbuf, pver, &na)
+		if err != nil {
+			t.Errorf("readNetAddressV2 #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(na, test.out) {
+			t.Errorf("readNetAddressV2 #%d\n got: %s want: %s", i,
+				spew.Sdump(na), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestNetAddressV2WireErrors performs negative tests against wire encode and
+// decode NetAddressV2 to confirm error paths work correctly.
+func TestNetAddressV2WireErrors(t *testing.T) {
+	pver := rddwire.ProtocolVersion
+	rddwireErr := &rddwire.MessageError{}
+
+	// baseNetAddr is used in the various tests as a baseline NetAddressV2.
+	baseNetAddr := rddwire.NetAddressV2{
+		Timestamp: time.Unix(0x495fab29, 0), // 2009-01-03 12:15:05 -0600 CST
+		Services:  rddwire.SFNodeNetwork,
+		NetworkID: rddwire.AddrNetIPv4,
+		Addr:      []byte{0x7f, 0x00, 0x00, 0x01},
+		Port:      45444,
+	}
+	baseNetAddrEncoded := []byte{
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,                   // Varint for services
+		0x01,                   // Network ID (IPv4)
+		0x04,                   // Varint for address length
+		0x7f, 0x00, 0x00, 0x01, // IP 127.0.0.1
+		0xb1, 0x84, // Port 45444 in big-endian
+	}
+
+	// badLenNetAddr is an IPv4 NetAddressV2 with an IPv6 length address.
+	badLenNetAddr := baseNetAddr
+	badLenNetAddr.Addr = net.ParseIP("::1")
+	badLenNetAddrEncoded := []byte{
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01, // Varint for services
+		0x01, // Network ID (IPv4)
+		0x10, // Varint for address length
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // IP ::1
+		0xb1, 0x84, // Port 45444 in big-endian
+	}
+
+	// oversizedNetAddr is a NetAddressV2 with an address longer than
+	// allowed.
+	oversizedNetAddr := baseNetAddr
+	oversizedNetAddr.NetworkID = rddwire.AddrNetworkID(0x2a)
+	oversizedNetAddr.Addr = make([]byte, rddwire.MaxAddrV2Size+1)
+	oversizedNetAddrEncoded := []byte{
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,             // Varint for services
+		0x2a,             // Network ID (unknown)
+		0xfd, 0x01, 0x02, // Varint for address length (513)
+	}
+
+	tests := []struct {
+		in       *rddwire.NetAddressV2 // Value to encode
+		buf      []byte                // Wire encoding
+		pver     uint32                // Protocol version for wire encoding
+		max      int                   // Max size of fixed buffer to induce errors
+		writeErr error                 // Expected write error
+		readErr  error                 // Expected read error
+	}{
+		// Force errors on timestamp.
+		{&baseNetAddr, baseNetAddrEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force errors on services.
+		{&baseNetAddr, baseNetAddrEncoded, pver, 4, io.ErrShortWrite, io.EOF},
+		// Force errors on network id.
+		{&baseNetAddr, baseNetAddrEncoded, pver, 5, io.ErrShortWrite, io.EOF},
+		// Force errors on address.
+		{&baseNetAddr, baseNetAddrEncoded, pver, 6, io.ErrShortWrite, io.EOF},
+		// Force errors on port.
+		{&baseNetAddr, baseNetAddrEncoded, pver, 11, io.ErrShortWrite, io.EOF},
+		// Force error with wrong address length for network.
+		{&badLenNetAddr, badLenNetAddrEncoded, pver, 100, rddwireErr, rddwireErr},
+		// Force error with address greater than max allowed size.
+		{&oversizedNetAddr, oversizedNetAddrEncoded, pver, 100, rddwireErr, rddwireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := rddwire.TstWriteNetAddressV2(w, test.pver, test.in)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("writeNetAddressV2 #%d wrong error got: %v, "+
+				"want: %v", i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("writeNetAddressV2 #%d wrong error "+
+					"got: %v, want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var na rddwire.NetAddressV2
+		r := newFixedReader(test.max, test.buf)
+		err = rddwire.TstReadNetAddressV2(r, test.pver, &na)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("readNetAddressV2 #%d wrong error got: %v, "+
+				"want: %v", i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type rddwire.MessageError, check
+		// them for equality.
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("readNetAddressV2 #%d wrong error "+
+					"got: %v, want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
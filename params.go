@@ -0,0 +1,294 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+)
+
+var (
+	// ErrDuplicateNet describes an error where the parameters for a
+	// Reddcoin network could not be registered due to the network already
+	// being registered.
+	ErrDuplicateNet = errors.New("duplicate Reddcoin network")
+
+	// ErrUnknownNet describes an error where no parameters are registered
+	// for a Reddcoin network.
+	ErrUnknownNet = errors.New("unknown Reddcoin network")
+
+	// ErrUnknownPoSVRules describes an error where the version of the PoSV
+	// consensus rules which apply to a block can't be determined from the
+	// parameters of its network.
+	ErrUnknownPoSVRules = errors.New("unknown PoSV rules")
+)
+
+// Checkpoint identifies a known good point in the block chain.  Using
+// checkpoints allows a few optimizations for old blocks during initial download
+// and also prevents forks from old blocks.
+type Checkpoint struct {
+	Height int32
+	Hash   *ShaHash
+}
+
+// Params defines a Reddcoin network by its parameters.  These parameters may be
+// used by Reddcoin applications to differentiate networks as well as addresses
+// and keys for one network from those intended for use on another network.
+type Params struct {
+	// Name is the human-readable name of the network, which is also
+	// returned by the String method of Net once the network is registered.
+	Name string
+
+	// Net is the magic number which identifies messages of the network.
+	Net ReddcoinNet
+
+	// DefaultPort is the default peer-to-peer port of the network.
+	DefaultPort string
+
+	// DNSSeeds is a list of DNS seeds which return the addresses of peers
+	// of the network.
+	DNSSeeds []string
+
+	// GenesisBlock and GenesisHash are the first block of the block chain
+	// of the network and its hash.
+	GenesisBlock *MsgBlock
+	GenesisHash  *ShaHash
+
+	// PowLimit is the highest proof of work value a block of the network
+	// can have and PowLimitBits is its compact representation.
+	PowLimit     *big.Int
+	PowLimitBits uint32
+
+	// PoSVHeight is the height of the first block of the network that is
+	// secured by proof of stake velocity rather than proof of work.
+	PoSVHeight int32
+
+	// PoSVv2Height is the height of the first block of the network that is
+	// validated with the PoSV v2 rules.  A negative height means the
+	// activation height is not known, so the rules of the PoSV blocks of
+	// the network can't be determined.
+	PoSVv2Height int32
+
+	// Checkpoints are the known good blocks of the network ordered from
+	// oldest to newest.
+	Checkpoints []Checkpoint
+
+	// Address encoding magics.
+	PubKeyHashAddrID byte // First byte of a P2PKH address
+	ScriptHashAddrID byte // First byte of a P2SH address
+	PrivateKeyID     byte // First byte of a WIF private key
+}
+
+// IsPoSV returns whether the block at the passed height is a PoSV block.
+// Blocks below PoSVHeight are secured by proof of work.
+func (p *Params) IsPoSV(height int32) bool {
+	return height >= p.PoSVHeight
+}
+
+// PoSVRules returns the version of the PoSV consensus rules which apply to the
+// PoSV block at the passed height.  ErrUnknownPoSVRules is returned when the
+// block is not a PoSV block, or when the activation height of the PoSV v2
+// rules is not known for the network.
+func (p *Params) PoSVRules(height int32) (PoSVRules, error) {
+	if !p.IsPoSV(height) || p.PoSVv2Height < 0 {
+		return 0, ErrUnknownPoSVRules
+	}
+	if height >= p.PoSVv2Height {
+		return PoSVv2, nil
+	}
+	return PoSV, nil
+}
+
+// MainNetParams defines the network parameters for the main Reddcoin network.
+var MainNetParams = Params{
+	Name:        "MainNet",
+	Net:         MainNet,
+	DefaultPort: "45444",
+	DNSSeeds: []string{
+		"dnsseed01.redd.ink",
+		"dnsseed02.redd.ink",
+		"dnsseed03.redd.ink",
+	},
+
+	// Chain parameters
+	GenesisBlock: &mainNetGenesisBlock,
+	GenesisHash:  &mainNetGenesisHash,
+	PowLimit:     MainNetPowLimit,
+	PowLimitBits: 0x1e0fffff,
+	PoSVHeight:   260800,
+
+	// The activation height of the PoSV v2 rules on the main network is
+	// not part of these parameters yet, so PoSVRules reports the rules of
+	// its PoSV blocks as unknown rather than as PoSV v1.
+	PoSVv2Height: -1,
+
+	// Checkpoints ordered from oldest to newest.
+	Checkpoints: []Checkpoint{
+		{0, &mainNetGenesisHash},
+	},
+
+	// Address encoding magics
+	PubKeyHashAddrID: 0x3d, // starts with R
+	ScriptHashAddrID: 0x05, // starts with 3
+	PrivateKeyID:     0xbd, // starts with 7 or U/V (uncompressed/compressed)
+}
+
+// TestNetParams defines the network parameters for the regression test
+// Reddcoin network.  Not to be confused with the test network (version 3).
+//
+// No parameters are provided for the test network (version 3) since its
+// genesis block and PoSV activation heights are not part of this package.
+// Applications which connect to it must register its parameters themselves.
+var TestNetParams = Params{
+	Name:        "TestNet",
+	Net:         TestNet,
+	DefaultPort: "55444",
+	DNSSeeds:    []string{},
+
+	// Chain parameters
+	GenesisBlock: &testNetGenesisBlock,
+	GenesisHash:  &testNetGenesisHash,
+	PowLimit:     TestNetPowLimit,
+	PowLimitBits: 0x207fffff,
+	PoSVHeight:   100,
+	PoSVv2Height: 200,
+
+	// Checkpoints ordered from oldest to newest.
+	Checkpoints: nil,
+
+	// Address encoding magics
+	PubKeyHashAddrID: 0x6f, // starts with m or n
+	ScriptHashAddrID: 0xc4, // starts with 2
+	PrivateKeyID:     0xef, // starts with 9 or c (uncompressed/compressed)
+}
+
+// SimNetParams defines the network parameters for the simulation test Reddcoin
+// network.  This network is similar to the normal test network except it is
+// intended for private use within a group of individuals doing simulation
+// testing.  The functionality is intended to differ in that the only nodes
+// which are specifically specified are used to create the network rather than
+// following normal discovery rules.  This is important as otherwise it would
+// just turn into another public testnet.
+//
+// Its default port and address magics differ from those of the btcd simulation
+// test network, so simulation test nodes and addresses of both can't be
+// confused.
+var SimNetParams = Params{
+	Name:        "SimNet",
+	Net:         SimNet,
+	DefaultPort: "65444",
+	DNSSeeds:    []string{}, // NOTE: There must NOT be any seeds.
+
+	// Chain parameters
+	GenesisBlock: &simNetGenesisBlock,
+	GenesisHash:  &simNetGenesisHash,
+	PowLimit:     SimNetPowLimit,
+	PowLimitBits: 0x207fffff,
+	PoSVHeight:   100,
+	PoSVv2Height: 200,
+
+	// Checkpoints ordered from oldest to newest.
+	Checkpoints: nil,
+
+	// Address encoding magics
+	PubKeyHashAddrID: 0x7d, // starts with s
+	ScriptHashAddrID: 0x7f, // starts with t
+	PrivateKeyID:     0xfd, // starts with 9 or e (uncompressed/compressed)
+}
+
+var (
+	// registerMtx protects the registered networks, including the names of
+	// the networks in bnStrings, from concurrent access.
+	registerMtx sync.RWMutex
+
+	registeredNets    = make(map[ReddcoinNet]*Params)
+	pubKeyHashAddrIDs = make(map[byte]struct{})
+	scriptHashAddrIDs = make(map[byte]struct{})
+)
+
+// Register registers the network parameters for a Reddcoin network.  This may
+// error with ErrDuplicateNet if the network is already registered (either due
+// to a previous Register call, or the network being one of the default
+// networks).
+//
+// Once registered, the String method of the network returns the Name of the
+// parameters, ParamsForNet returns the parameters, and the address magics are
+// recognized by IsPubKeyHashAddrID and IsScriptHashAddrID.
+//
+// Network parameters should be registered into this package by a main package
+// as early as possible, such as from an init function.  Register is safe for
+// concurrent access, but networks which are registered late may already have
+// been reported as unknown.
+func Register(params *Params) error {
+	registerMtx.Lock()
+	defer registerMtx.Unlock()
+
+	if _, ok := registeredNets[params.Net]; ok {
+		return ErrDuplicateNet
+	}
+	registeredNets[params.Net] = params
+	bnStrings[params.Net] = params.Name
+	pubKeyHashAddrIDs[params.PubKeyHashAddrID] = struct{}{}
+	scriptHashAddrIDs[params.ScriptHashAddrID] = struct{}{}
+	return nil
+}
+
+// mustRegister performs the same function as Register except it panics if there
+// is an error.  This should only be called from package init functions.
+func mustRegister(params *Params) {
+	if err := Register(params); err != nil {
+		panic("failed to register network: " + err.Error())
+	}
+}
+
+// ParamsForNet returns the registered network parameters for the passed
+// Reddcoin network.  ErrUnknownNet is returned when no parameters are
+// registered for the network.
+func ParamsForNet(net ReddcoinNet) (*Params, error) {
+	registerMtx.RLock()
+	defer registerMtx.RUnlock()
+
+	params, ok := registeredNets[net]
+	if !ok {
+		return nil, ErrUnknownNet
+	}
+	return params, nil
+}
+
+// IsPubKeyHashAddrID returns whether the id is an identifier known to prefix a
+// pay-to-pubkey-hash address on any default or registered network.  This is
+// used when decoding an address string into a specific address type.  It is up
+// to the caller to check both this and IsScriptHashAddrID and decide whether an
+// address is a pubkey hash address, script hash address, neither, or
+// undeterminable (if both return true).
+func IsPubKeyHashAddrID(id byte) bool {
+	registerMtx.RLock()
+	defer registerMtx.RUnlock()
+
+	_, ok := pubKeyHashAddrIDs[id]
+	return ok
+}
+
+// IsScriptHashAddrID returns whether the id is an identifier known to prefix a
+// pay-to-script-hash address on any default or registered network.  This is
+// used when decoding an address string into a specific address type.  It is up
+// to the caller to check both this and IsPubKeyHashAddrID and decide whether an
+// address is a pubkey hash address, script hash address, neither, or
+// undeterminable (if both return true).
+func IsScriptHashAddrID(id byte) bool {
+	registerMtx.RLock()
+	defer registerMtx.RUnlock()
+
+	_, ok := scriptHashAddrIDs[id]
+	return ok
+}
+
+func init() {
+	// Register all default networks when the package is initialized.
+	mustRegister(&MainNetParams)
+	mustRegister(&TestNetParams)
+	mustRegister(&SimNetParams)
+}
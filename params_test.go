@@ -0,0 +1,248 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/reddcoin-project/rddwire"
+)
+
+// TestParams tests the consistency of the default network parameters.
+func TestParams(t *testing.T) {
+	tests := []*rddwire.Params{
+		&rddwire.MainNetParams,
+		&rddwire.TestNetParams,
+		&rddwire.SimNetParams,
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, params := range tests {
+		// The parameters must be registered for their network.
+		got, err := rddwire.ParamsForNet(params.Net)
+		if err != nil {
+			t.Errorf("ParamsForNet %s: %v", params.Name, err)
+			continue
+		}
+		if got != params {
+			t.Errorf("ParamsForNet %s: wrong parameters returned",
+				params.Name)
+			continue
+		}
+		if s := params.Net.String(); s != params.Name {
+			t.Errorf("String %s: got %s", params.Name, s)
+			continue
+		}
+
+		// The compact proof of work limit must match the limit.
+		bits := rddwire.BigToCompact(params.PowLimit)
+		if bits != params.PowLimitBits {
+			t.Errorf("PowLimitBits %s: got 0x%08x, want 0x%08x",
+				params.Name, params.PowLimitBits, bits)
+			continue
+		}
+
+		// The address magics must be recognized.
+		if !rddwire.IsPubKeyHashAddrID(params.PubKeyHashAddrID) {
+			t.Errorf("IsPubKeyHashAddrID %s: 0x%02x is not recognized",
+				params.Name, params.PubKeyHashAddrID)
+			continue
+		}
+		if !rddwire.IsScriptHashAddrID(params.ScriptHashAddrID) {
+			t.Errorf("IsScriptHashAddrID %s: 0x%02x is not recognized",
+				params.Name, params.ScriptHashAddrID)
+			continue
+		}
+
+		// Checkpoints must be ordered by height and the checkpoint at the
+		// genesis block, if any, must match the genesis hash.
+		for i, checkpoint := range params.Checkpoints {
+			if i > 0 && checkpoint.Height <=
+				params.Checkpoints[i-1].Height {
+
+				t.Errorf("Checkpoints %s: checkpoint #%d is out "+
+					"of order", params.Name, i)
+				break
+			}
+			if checkpoint.Height == 0 &&
+				!checkpoint.Hash.IsEqual(params.GenesisHash) {
+
+				t.Errorf("Checkpoints %s: genesis checkpoint %v "+
+					"does not match genesis hash %v", params.Name,
+					checkpoint.Hash, params.GenesisHash)
+				break
+			}
+		}
+	}
+
+	// The address magics of the default networks must not be shared, so an
+	// address identifies its network.
+	magics := make(map[byte]string)
+	for _, params := range tests {
+		for _, id := range []byte{params.PubKeyHashAddrID,
+			params.ScriptHashAddrID} {
+
+			if name, ok := magics[id]; ok {
+				t.Errorf("address magic 0x%02x of %s is also used "+
+					"by %s", id, params.Name, name)
+			}
+			magics[id] = params.Name
+		}
+	}
+
+	// Parameters are not registered for the test network (version 3).
+	if _, err := rddwire.ParamsForNet(rddwire.TestNet3); err != rddwire.ErrUnknownNet {
+		t.Errorf("ParamsForNet: got %v, want %v", err,
+			rddwire.ErrUnknownNet)
+	}
+}
+
+// TestPoSVRules tests the PoSV rules which apply to blocks by height.
+func TestPoSVRules(t *testing.T) {
+	tests := []struct {
+		params *rddwire.Params   // Network parameters
+		height int32             // Block height
+		posv   bool              // Whether a PoSV block is expected
+		rules  rddwire.PoSVRules // Expected PoSV rules
+		err    error             // Expected error
+	}{
+		{&rddwire.MainNetParams, 0, false, 0, rddwire.ErrUnknownPoSVRules},
+		{&rddwire.MainNetParams, 260799, false, 0,
+			rddwire.ErrUnknownPoSVRules},
+		// The PoSV v2 activation height of the main network is not
+		// known, so neither version of the rules may be assumed.
+		{&rddwire.MainNetParams, 260800, true, 0,
+			rddwire.ErrUnknownPoSVRules},
+		{&rddwire.MainNetParams, 5000000, true, 0,
+			rddwire.ErrUnknownPoSVRules},
+		{&rddwire.SimNetParams, 99, false, 0, rddwire.ErrUnknownPoSVRules},
+		{&rddwire.SimNetParams, 100, true, rddwire.PoSV, nil},
+		{&rddwire.SimNetParams, 199, true, rddwire.PoSV, nil},
+		{&rddwire.SimNetParams, 200, true, rddwire.PoSVv2, nil},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		posv := test.params.IsPoSV(test.height)
+		if posv != test.posv {
+			t.Errorf("IsPoSV #%d (%s height %d): got %v, want %v", i,
+				test.params.Name, test.height, posv, test.posv)
+			continue
+		}
+
+		rules, err := test.params.PoSVRules(test.height)
+		if rules != test.rules || err != test.err {
+			t.Errorf("PoSVRules #%d (%s height %d): got %v %v, want "+
+				"%v %v", i, test.params.Name, test.height, rules,
+				err, test.rules, test.err)
+			continue
+		}
+	}
+}
+
+// TestRegister tests registering the parameters of custom networks.
+func TestRegister(t *testing.T) {
+	regTestParams := rddwire.TestNetParams
+	regTestParams.Name = "RegTest"
+	regTestParams.Net = 0xdab5bffa
+	regTestParams.PubKeyHashAddrID = 0x7a
+	regTestParams.ScriptHashAddrID = 0x7c
+
+	// Custom networks are unknown until they are registered.
+	if s := regTestParams.Net.String(); s != "Unknown ReddcoinNet (3669344250)" {
+		t.Errorf("String: unexpected name %s of unregistered network", s)
+	}
+	if _, err := rddwire.ParamsForNet(regTestParams.Net); err != rddwire.ErrUnknownNet {
+		t.Errorf("ParamsForNet: got %v, want %v", err,
+			rddwire.ErrUnknownNet)
+	}
+	if rddwire.IsPubKeyHashAddrID(0x7a) || rddwire.IsScriptHashAddrID(0x7c) {
+		t.Errorf("address magics of unregistered network are recognized")
+	}
+
+	if err := rddwire.Register(&regTestParams); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// The registered network is picked up by the lookups.
+	if s := regTestParams.Net.String(); s != "RegTest" {
+		t.Errorf("String: got %s, want RegTest", s)
+	}
+	params, err := rddwire.ParamsForNet(regTestParams.Net)
+	if err != nil || params != &regTestParams {
+		t.Errorf("ParamsForNet: got %v %v, want registered parameters",
+			params, err)
+	}
+	if !rddwire.IsPubKeyHashAddrID(0x7a) {
+		t.Errorf("IsPubKeyHashAddrID: registered magic not recognized")
+	}
+	if !rddwire.IsScriptHashAddrID(0x7c) {
+		t.Errorf("IsScriptHashAddrID: registered magic not recognized")
+	}
+
+	// Networks can't be registered twice.
+	tests := []*rddwire.Params{
+		&regTestParams,
+		&rddwire.MainNetParams,
+		&rddwire.TestNetParams,
+		&rddwire.SimNetParams,
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, params := range tests {
+		err := rddwire.Register(params)
+		if err != rddwire.ErrDuplicateNet {
+			t.Errorf("Register %s: got %v, want %v", params.Name, err,
+				rddwire.ErrDuplicateNet)
+			continue
+		}
+	}
+}
+
+// TestRegisterConcurrent tests registering networks concurrently with lookups
+// of the registered networks.  It is mainly useful with the race detector.
+func TestRegisterConcurrent(t *testing.T) {
+	const numNets = 8
+
+	var wg sync.WaitGroup
+	params := make([]rddwire.Params, numNets)
+	for i := range params {
+		params[i] = rddwire.SimNetParams
+		params[i].Name = fmt.Sprintf("ConcurrentNet%d", i)
+		params[i].Net = rddwire.ReddcoinNet(0xcc000000 + i)
+
+		wg.Add(2)
+		go func(params *rddwire.Params) {
+			defer wg.Done()
+			if err := rddwire.Register(params); err != nil {
+				t.Errorf("Register %s: %v", params.Name, err)
+			}
+		}(&params[i])
+		go func(net rddwire.ReddcoinNet) {
+			defer wg.Done()
+			_ = net.String()
+			_, _ = rddwire.ParamsForNet(net)
+			_ = rddwire.IsPubKeyHashAddrID(0x7d)
+			_ = rddwire.IsScriptHashAddrID(0x7f)
+		}(params[i].Net)
+	}
+	wg.Wait()
+
+	t.Logf("Running %d tests", len(params))
+	for i := range params {
+		got, err := rddwire.ParamsForNet(params[i].Net)
+		if err != nil || got != &params[i] {
+			t.Errorf("ParamsForNet %s: got %v %v, want registered "+
+				"parameters", params[i].Name, got, err)
+			continue
+		}
+		if s := params[i].Net.String(); s != params[i].Name {
+			t.Errorf("String %s: got %s", params[i].Name, s)
+			continue
+		}
+	}
+}
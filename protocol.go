@@ -38,6 +38,28 @@ const (
 	// RejectVersion is the protocol version which added a new reject
 	// message.
 	RejectVersion uint32 = 70002
+
+	// SendHeadersVersion is the protocol version which added a new
+	// sendheaders message (pver >= SendHeadersVersion).
+	SendHeadersVersion uint32 = 70012
+
+	// FeeFilterVersion is the protocol version which added a new
+	// feefilter message (pver >= FeeFilterVersion).
+	FeeFilterVersion uint32 = 70013
+
+	// BIP0152Version is the protocol version which added the compact block
+	// relay messages sendcmpct, cmpctblock, getblocktxn, and blocktxn
+	// (pver >= BIP0152Version).
+	BIP0152Version uint32 = 70014
+
+	// AddrV2Version is the protocol version which added the addrv2 and
+	// sendaddrv2 messages (pver >= AddrV2Version).
+	AddrV2Version uint32 = 70016
+
+	// WtxidRelayVersion is the protocol version which added the wtxidrelay
+	// message and the relay of transactions by their witness transaction
+	// id (pver >= WtxidRelayVersion).
+	WtxidRelayVersion uint32 = 70016
 )
 
 // ServiceFlag identifies services supported by a Reddcoin peer.
@@ -110,7 +132,10 @@ var bnStrings = map[ReddcoinNet]string{
 
 // String returns the ReddcoinNet in human-readable form.
 func (n ReddcoinNet) String() string {
-	if s, ok := bnStrings[n]; ok {
+	registerMtx.RLock()
+	s, ok := bnStrings[n]
+	registerMtx.RUnlock()
+	if ok {
 		return s
 	}
 
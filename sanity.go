@@ -0,0 +1,172 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"fmt"
+)
+
+const (
+	// MaxSatoshi is the maximum transaction amount allowed in satoshi.
+	MaxSatoshi = 92233720368 * SatoshiPerReddcoin
+
+	// MinCoinbaseScriptLen is the minimum length a coinbase script can be.
+	MinCoinbaseScriptLen = 2
+
+	// MaxCoinbaseScriptLen is the maximum length a coinbase script can be.
+	MaxCoinbaseScriptLen = 100
+)
+
+// CheckTransactionSanity performs some preliminary checks on a transaction to
+// ensure it is sane.  These checks are context free.
+//
+// A RuleError is returned when the transaction has no inputs or outputs, is
+// larger than MaxBlockPayload when serialized, has an output with a negative
+// value or outputs whose values exceed MaxSatoshi, spends an outpoint more than
+// once, or is a coinbase whose signature script is not within
+// MinCoinbaseScriptLen and MaxCoinbaseScriptLen bytes.  Inputs of transactions
+// other than a coinbase must not spend the null outpoint.
+func CheckTransactionSanity(tx *MsgTx) error {
+	// A transaction must have at least one input.
+	if len(tx.TxIn) == 0 {
+		return ruleError(ErrNoTxInputs, "transaction has no inputs")
+	}
+
+	// A transaction must have at least one output.
+	if len(tx.TxOut) == 0 {
+		return ruleError(ErrNoTxOutputs, "transaction has no outputs")
+	}
+
+	// A transaction must not exceed the maximum allowed block payload when
+	// serialized.
+	serializedTxSize := tx.SerializeSizeStripped()
+	if serializedTxSize > MaxBlockPayload {
+		str := fmt.Sprintf("serialized transaction is too big - got "+
+			"%d, max %d", serializedTxSize, MaxBlockPayload)
+		return ruleError(ErrTxTooBig, str)
+	}
+
+	// Ensure the transaction amounts are in range.  Each transaction
+	// output must not be negative or more than the max allowed per
+	// transaction.  Also, the total of all outputs must abide by the same
+	// restrictions.  The empty first output of a coinstake has a value of
+	// zero, which is allowed.
+	var totalSatoshi int64
+	for _, txOut := range tx.TxOut {
+		satoshi := txOut.Value
+		if satoshi < 0 {
+			str := fmt.Sprintf("transaction output has negative "+
+				"value of %v", satoshi)
+			return ruleError(ErrNegativeTxOutValue, str)
+		}
+		if satoshi > MaxSatoshi {
+			str := fmt.Sprintf("transaction output value of %v is "+
+				"higher than max allowed value of %v", satoshi,
+				int64(MaxSatoshi))
+			return ruleError(ErrTxOutValueTooHigh, str)
+		}
+
+		// MaxSatoshi is close to the maximum int64, so compare
+		// before adding to avoid overflowing the total.
+		if totalSatoshi > MaxSatoshi-satoshi {
+			str := fmt.Sprintf("total value of all transaction "+
+				"outputs exceeds max allowed value of %v",
+				int64(MaxSatoshi))
+			return ruleError(ErrTxOutValueTooHigh, str)
+		}
+		totalSatoshi += satoshi
+	}
+
+	// Check for duplicate transaction inputs.
+	existingTxOut := make(map[OutPoint]struct{})
+	for _, txIn := range tx.TxIn {
+		if _, exists := existingTxOut[txIn.PreviousOutPoint]; exists {
+			str := fmt.Sprintf("transaction contains duplicate input "+
+				"%v:%d", txIn.PreviousOutPoint.Hash,
+				txIn.PreviousOutPoint.Index)
+			return ruleError(ErrDuplicateTxInputs, str)
+		}
+		existingTxOut[txIn.PreviousOutPoint] = struct{}{}
+	}
+
+	// Coinbase script length must be between min and max length.
+	if tx.IsCoinBase() {
+		slen := len(tx.TxIn[0].SignatureScript)
+		if slen < MinCoinbaseScriptLen || slen > MaxCoinbaseScriptLen {
+			str := fmt.Sprintf("coinbase transaction script length "+
+				"of %d is out of range (min: %d, max: %d)",
+				slen, MinCoinbaseScriptLen, MaxCoinbaseScriptLen)
+			return ruleError(ErrBadCoinbaseScriptLen, str)
+		}
+		return nil
+	}
+
+	// Previous transaction outputs referenced by the inputs to this
+	// transaction must not be null.
+	for _, txIn := range tx.TxIn {
+		if txIn.PreviousOutPoint.isNull() {
+			str := "transaction input refers to previous output " +
+				"that is null"
+			return ruleError(ErrBadTxInput, str)
+		}
+	}
+
+	return nil
+}
+
+// CheckBlockSanity performs some preliminary checks on a block to ensure it is
+// sane before continuing with block processing.  These checks are context
+// free and don't include the proof of work or stake of the block, which are
+// checked by CheckProofOfWork and StakeKernel.Check.
+//
+// A RuleError is returned when the block is larger than MaxBlockPayload when
+// serialized without witness data, violates the rules of CheckStructure, has a transaction which
+// doesn't pass CheckTransactionSanity, or has a merkle root which doesn't match
+// its transactions (see CheckMerkleRoot).  The Timestamp of each transaction of
+// a PoSV block must not be after the timestamp of the block.
+func CheckBlockSanity(block *MsgBlock) error {
+	// A block must not exceed the maximum allowed block payload when
+	// serialized.  The witness data of its transactions is not counted
+	// against the limit.
+	serializedSize := block.SerializeSizeStripped()
+	if serializedSize > MaxBlockPayload {
+		str := fmt.Sprintf("serialized block is too big - got %d, "+
+			"max %d", serializedSize, MaxBlockPayload)
+		return ruleError(ErrBlockTooBig, str)
+	}
+
+	if err := block.checkStructure(); err != nil {
+		return err
+	}
+
+	// Do some preliminary checks on each transaction to ensure they are
+	// sane before continuing.
+	for _, tx := range block.Transactions {
+		err := CheckTransactionSanity(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Transactions of PoSV blocks must not be newer than the block.
+	if block.Header.Version > PowBlockVersion {
+		for i, tx := range block.Transactions {
+			if tx.Timestamp.After(block.Header.Timestamp) {
+				str := fmt.Sprintf("transaction at index %d has "+
+					"timestamp %v after the block timestamp %v",
+					i, tx.Timestamp, block.Header.Timestamp)
+				return ruleError(ErrTxTimestampTooNew, str)
+			}
+		}
+	}
+
+	// Build the merkle tree and ensure the calculated merkle root matches
+	// the entry in the block header and the tree was not mutated.
+	err := block.CheckMerkleRoot()
+	if merr, ok := err.(*MessageError); ok {
+		return ruleError(ErrBadMerkleRoot, merr.Description)
+	}
+	return err
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+)
+
+// TestErrorCodeStringer tests the stringized output for the ErrorCode type.
+func TestErrorCodeStringer(t *testing.T) {
+	tests := []struct {
+		in   rddwire.ErrorCode
+		want string
+	}{
+		{rddwire.ErrNoTxInputs, "ErrNoTxInputs"},
+		{rddwire.ErrNoTxOutputs, "ErrNoTxOutputs"},
+		{rddwire.ErrTxTooBig, "ErrTxTooBig"},
+		{rddwire.ErrNegativeTxOutValue, "ErrNegativeTxOutValue"},
+		{rddwire.ErrTxOutValueTooHigh, "ErrTxOutValueTooHigh"},
+		{rddwire.ErrDuplicateTxInputs, "ErrDuplicateTxInputs"},
+		{rddwire.ErrBadCoinbaseScriptLen, "ErrBadCoinbaseScriptLen"},
+		{rddwire.ErrBadTxInput, "ErrBadTxInput"},
+		{rddwire.ErrNoTransactions, "ErrNoTransactions"},
+		{rddwire.ErrBlockTooBig, "ErrBlockTooBig"},
+		{rddwire.ErrFirstTxNotCoinbase, "ErrFirstTxNotCoinbase"},
+		{rddwire.ErrMultipleCoinbases, "ErrMultipleCoinbases"},
+		{rddwire.ErrCoinStakeMisplaced, "ErrCoinStakeMisplaced"},
+		{rddwire.ErrCoinbaseNotEmpty, "ErrCoinbaseNotEmpty"},
+		{rddwire.ErrTxTimestampTooNew, "ErrTxTimestampTooNew"},
+		{rddwire.ErrBadMerkleRoot, "ErrBadMerkleRoot"},
+		{0xffff, "Unknown ErrorCode (65535)"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := test.in.String()
+		if result != test.want {
+			t.Errorf("String #%d\n got: %s want: %s", i, result,
+				test.want)
+			continue
+		}
+	}
+}
+
+// sanityTestFixMerkleRoot updates the merkle root of the passed block to match
+// its transactions after they were modified.
+func sanityTestFixMerkleRoot(block *rddwire.MsgBlock) {
+	txHashes, _ := block.TxShas()
+	block.Header.MerkleRoot, _ = rddwire.BuildMerkleTreeStore(txHashes)
+}
+
+// TestCheckTransactionSanity tests the context free transaction checks.
+func TestCheckTransactionSanity(t *testing.T) {
+	p2pkhScript := []byte{
+		0x76, 0xa9, 0x14, 0x34, 0x42, 0x19, 0x3e, 0x1b, 0xb7, 0x09,
+		0x16, 0xe9, 0x14, 0x55, 0x21, 0x72, 0xcd, 0x4e, 0x2d, 0xbc,
+		0x9d, 0xf8, 0x11, 0x88, 0xac,
+	}
+
+	tests := []struct {
+		name   string                  // Test description
+		modify func(tx *rddwire.MsgTx) // Modification of a valid coinstake
+		isCB   bool                    // Whether to modify the coinbase
+		code   rddwire.ErrorCode       // Expected error code
+		valid  bool                    // Whether the tx is expected valid
+	}{
+		{"coinstake", func(tx *rddwire.MsgTx) {}, false, 0, true},
+		{"coinbase", func(tx *rddwire.MsgTx) {}, true, 0, true},
+		{"no inputs", func(tx *rddwire.MsgTx) {
+			tx.TxIn = nil
+		}, false, rddwire.ErrNoTxInputs, false},
+		{"no outputs", func(tx *rddwire.MsgTx) {
+			tx.TxOut = nil
+		}, false, rddwire.ErrNoTxOutputs, false},
+		{"too big", func(tx *rddwire.MsgTx) {
+			tx.TxIn[0].SignatureScript = make([]byte,
+				rddwire.MaxBlockPayload)
+		}, false, rddwire.ErrTxTooBig, false},
+		{"negative value", func(tx *rddwire.MsgTx) {
+			tx.TxOut[1].Value = -1
+		}, false, rddwire.ErrNegativeTxOutValue, false},
+		{"max value", func(tx *rddwire.MsgTx) {
+			tx.TxOut[1].Value = rddwire.MaxSatoshi
+		}, false, 0, true},
+		{"value too high", func(tx *rddwire.MsgTx) {
+			tx.TxOut[1].Value = rddwire.MaxSatoshi + 1
+		}, false, rddwire.ErrTxOutValueTooHigh, false},
+		{"total value too high", func(tx *rddwire.MsgTx) {
+			tx.TxOut[1].Value = rddwire.MaxSatoshi
+			tx.AddTxOut(rddwire.NewTxOut(rddwire.MaxSatoshi,
+				p2pkhScript))
+		}, false, rddwire.ErrTxOutValueTooHigh, false},
+		{"duplicate inputs", func(tx *rddwire.MsgTx) {
+			tx.AddTxIn(rddwire.NewTxIn(&tx.TxIn[0].PreviousOutPoint,
+				nil))
+		}, false, rddwire.ErrDuplicateTxInputs, false},
+		{"null input", func(tx *rddwire.MsgTx) {
+			prevOut := rddwire.NewOutPoint(&rddwire.ShaHash{},
+				0xffffffff)
+			tx.AddTxIn(rddwire.NewTxIn(prevOut, nil))
+		}, false, rddwire.ErrBadTxInput, false},
+		{"short coinbase script", func(tx *rddwire.MsgTx) {
+			tx.TxIn[0].SignatureScript = []byte{0x01}
+		}, true, rddwire.ErrBadCoinbaseScriptLen, false},
+		{"long coinbase script", func(tx *rddwire.MsgTx) {
+			tx.TxIn[0].SignatureScript = make([]byte,
+				rddwire.MaxCoinbaseScriptLen+1)
+		}, true, rddwire.ErrBadCoinbaseScriptLen, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		block := posvTestBlock(p2pkhScript, nil)
+		tx := block.Transactions[1]
+		if test.isCB {
+			tx = block.Transactions[0]
+		}
+		test.modify(tx)
+
+		err := rddwire.CheckTransactionSanity(tx)
+		if test.valid {
+			if err != nil {
+				t.Errorf("CheckTransactionSanity %s: unexpected "+
+					"error %v", test.name, err)
+			}
+			continue
+		}
+		rerr, ok := err.(*rddwire.RuleError)
+		if !ok {
+			t.Errorf("CheckTransactionSanity %s: wrong error got: "+
+				"%v, want: %T", test.name, err, &rddwire.RuleError{})
+			continue
+		}
+		if rerr.ErrorCode != test.code {
+			t.Errorf("CheckTransactionSanity %s: wrong error code "+
+				"got: %v, want: %v", test.name, rerr.ErrorCode,
+				test.code)
+			continue
+		}
+	}
+}
+
+// TestCheckBlockSanity tests the context free block checks.
+func TestCheckBlockSanity(t *testing.T) {
+	p2pkhScript := []byte{
+		0x76, 0xa9, 0x14, 0x34, 0x42, 0x19, 0x3e, 0x1b, 0xb7, 0x09,
+		0x16, 0xe9, 0x14, 0x55, 0x21, 0x72, 0xcd, 0x4e, 0x2d, 0xbc,
+		0x9d, 0xf8, 0x11, 0x88, 0xac,
+	}
+
+	// Transaction which spends a regular output.
+	spendTx := &rddwire.MsgTx{
+		Version: rddwire.TxVersion,
+		TxIn: []*rddwire.TxIn{
+			rddwire.NewTxIn(rddwire.NewOutPoint(&rddwire.ShaHash{0x02},
+				0), nil),
+		},
+		TxOut: []*rddwire.TxOut{
+			rddwire.NewTxOut(100000000, p2pkhScript),
+		},
+		Timestamp: time.Unix(1400000000, 0),
+	}
+
+	tests := []struct {
+		name      string                    // Test description
+		modify    func(b *rddwire.MsgBlock) // Modification of a PoSV block
+		fixMerkle bool                      // Whether to fix the merkle root
+		code      rddwire.ErrorCode         // Expected error code
+		valid     bool                      // Whether the block is valid
+	}{
+		{"posv block", func(b *rddwire.MsgBlock) {}, false, 0, true},
+		{"pow block", func(b *rddwire.MsgBlock) {
+			*b = blockOne
+		}, false, 0, true},
+		{"posv block with spend", func(b *rddwire.MsgBlock) {
+			b.AddTransaction(spendTx)
+		}, true, 0, true},
+		{"too big", func(b *rddwire.MsgBlock) {
+			b.Signature = make([]byte, rddwire.MaxBlockPayload)
+		}, false, rddwire.ErrBlockTooBig, false},
+		{"big witness", func(b *rddwire.MsgBlock) {
+			tx := spendTx.Copy()
+			tx.TxIn[0].Witness = rddwire.TxWitness{
+				make([]byte, rddwire.MaxBlockPayload),
+			}
+			b.AddTransaction(tx)
+		}, true, 0, true},
+		{"no transactions", func(b *rddwire.MsgBlock) {
+			b.ClearTransactions()
+		}, true, rddwire.ErrNoTransactions, false},
+		{"first tx not coinbase", func(b *rddwire.MsgBlock) {
+			b.Transactions = b.Transactions[1:]
+		}, true, rddwire.ErrFirstTxNotCoinbase, false},
+		{"multiple coinbases", func(b *rddwire.MsgBlock) {
+			b.AddTransaction(b.Transactions[0])
+		}, true, rddwire.ErrMultipleCoinbases, false},
+		{"misplaced coinstake", func(b *rddwire.MsgBlock) {
+			b.Transactions = []*rddwire.MsgTx{b.Transactions[0],
+				spendTx, b.Transactions[1]}
+		}, true, rddwire.ErrCoinStakeMisplaced, false},
+		{"coinbase not empty", func(b *rddwire.MsgBlock) {
+			b.Transactions[0].TxOut[0].Value = 1
+		}, true, rddwire.ErrCoinbaseNotEmpty, false},
+		{"bad transaction", func(b *rddwire.MsgBlock) {
+			b.Transactions[1].TxOut[1].Value = -1
+		}, true, rddwire.ErrNegativeTxOutValue, false},
+		{"tx timestamp too new", func(b *rddwire.MsgBlock) {
+			b.Transactions[1].Timestamp = time.Unix(1400000001, 0)
+		}, true, rddwire.ErrTxTimestampTooNew, false},
+		{"pow block tx timestamp", func(b *rddwire.MsgBlock) {
+			b.Header.Version = rddwire.PowBlockVersion
+			b.Transactions = b.Transactions[:1]
+			b.Transactions[0].TxOut[0].Value = 1
+			b.Transactions[0].Timestamp = time.Unix(1400000001, 0)
+		}, true, 0, true},
+		{"bad merkle root", func(b *rddwire.MsgBlock) {
+			b.Transactions[1].TxOut[1].Value++
+		}, false, rddwire.ErrBadMerkleRoot, false},
+		{"mutated merkle tree", func(b *rddwire.MsgBlock) {
+			b.AddTransaction(spendTx)
+			b.AddTransaction(spendTx)
+		}, true, rddwire.ErrBadMerkleRoot, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		block := posvTestBlock(p2pkhScript, nil)
+		test.modify(block)
+		if test.fixMerkle {
+			sanityTestFixMerkleRoot(block)
+		}
+
+		err := rddwire.CheckBlockSanity(block)
+		if test.valid {
+			if err != nil {
+				t.Errorf("CheckBlockSanity %s: unexpected error %v",
+					test.name, err)
+			}
+			continue
+		}
+		rerr, ok := err.(*rddwire.RuleError)
+		if !ok {
+			t.Errorf("CheckBlockSanity %s: wrong error got: %v, "+
+				"want: %T", test.name, err, &rddwire.RuleError{})
+			continue
+		}
+		if rerr.ErrorCode != test.code {
+			t.Errorf("CheckBlockSanity %s: wrong error code got: %v, "+
+				"want: %v", test.name, rerr.ErrorCode, test.code)
+			continue
+		}
+	}
+}
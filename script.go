@@ -0,0 +1,160 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// These constants define the script opcodes which are needed to examine the
+// scripts of transactions.
+const (
+	opData75        = 0x4b // Pushes the next 75 bytes
+	opPushData1     = 0x4c // Pushes a number of bytes given by a uint8
+	opPushData2     = 0x4d // Pushes a number of bytes given by a uint16
+	opPushData4     = 0x4e // Pushes a number of bytes given by a uint32
+	op1             = 0x51 // Pushes the number 1
+	op16            = 0x60 // Pushes the number 16
+	opReturn        = 0x6a // Marks an output as provably unspendable
+	opDup           = 0x76
+	opEqualVerify   = 0x88
+	opHash160       = 0xa9
+	opCheckSig      = 0xac
+	opCheckMultiSig = 0xae
+)
+
+// scriptOp describes a single opcode of a script along with the data it
+// pushes, if any.
+type scriptOp struct {
+	opcode byte
+	data   []byte
+}
+
+// parseScript splits the passed script into its opcodes.  An error is
+// returned if a push opcode claims more data than remains in the script along
+// with the opcodes which were parsed before it.
+func parseScript(script []byte) ([]scriptOp, error) {
+	var ops []scriptOp
+	for i := 0; i < len(script); {
+		opcode := script[i]
+		i++
+
+		// Determine the number of bytes pushed by the opcode, if any.
+		var size int
+		switch {
+		case opcode <= opData75:
+			size = int(opcode)
+
+		case opcode == opPushData1:
+			if len(script)-i < 1 {
+				return ops, scriptError(opcode)
+			}
+			size = int(script[i])
+			i++
+
+		case opcode == opPushData2:
+			if len(script)-i < 2 {
+				return ops, scriptError(opcode)
+			}
+			size = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+
+		case opcode == opPushData4:
+			if len(script)-i < 4 {
+				return ops, scriptError(opcode)
+			}
+			size64 := uint64(binary.LittleEndian.Uint32(script[i:]))
+			if size64 > uint64(len(script)) {
+				return ops, scriptError(opcode)
+			}
+			size = int(size64)
+			i += 4
+
+		default:
+			ops = append(ops, scriptOp{opcode: opcode})
+			continue
+		}
+
+		if len(script)-i < size {
+			return ops, scriptError(opcode)
+		}
+		ops = append(ops, scriptOp{opcode: opcode, data: script[i : i+size]})
+		i += size
+	}
+
+	return ops, nil
+}
+
+// scriptError returns an error which describes a push opcode which claims
+// more data than remains in a script.
+func scriptError(opcode byte) error {
+	str := fmt.Sprintf("opcode 0x%02x pushes more data than remains in "+
+		"the script", opcode)
+	return messageError("parseScript", str)
+}
+
+// isPubKeySize returns whether the passed data has the size of a compressed
+// or uncompressed public key.
+func isPubKeySize(data []byte) bool {
+	return len(data) == 33 || len(data) == 65
+}
+
+// isPubKeyScript returns whether the passed script is a standard
+// pay-to-pubkey script of the form <pubkey> OP_CHECKSIG.
+func isPubKeyScript(script []byte) bool {
+	ops, err := parseScript(script)
+	if err != nil {
+		return false
+	}
+
+	return len(ops) == 2 && isPubKeySize(ops[0].data) &&
+		ops[1].opcode == opCheckSig
+}
+
+// isPubKeyHashScript returns whether the passed script is a standard
+// pay-to-pubkey-hash script of the form OP_DUP OP_HASH160 <hash160>
+// OP_EQUALVERIFY OP_CHECKSIG.
+func isPubKeyHashScript(script []byte) bool {
+	ops, err := parseScript(script)
+	if err != nil {
+		return false
+	}
+
+	return len(ops) == 5 && ops[0].opcode == opDup &&
+		ops[1].opcode == opHash160 && len(ops[2].data) == 20 &&
+		ops[3].opcode == opEqualVerify && ops[4].opcode == opCheckSig
+}
+
+// isMultiSigScript returns whether the passed script is a standard multisig
+// script of the form OP_m <pubkey>... OP_n OP_CHECKMULTISIG where there are n
+// public keys and m is not more than n.
+func isMultiSigScript(script []byte) bool {
+	ops, err := parseScript(script)
+	if err != nil || len(ops) < 4 {
+		return false
+	}
+
+	// The script must start with a small integer and end with a small
+	// integer followed by OP_CHECKMULTISIG.
+	first, last := ops[0].opcode, ops[len(ops)-2].opcode
+	if first < op1 || first > op16 || last < op1 || last > op16 ||
+		ops[len(ops)-1].opcode != opCheckMultiSig {
+
+		return false
+	}
+
+	// Every other opcode must push a public key, and the number of them
+	// must match the number of keys claimed by the script.
+	pubKeys := ops[1 : len(ops)-2]
+	for _, op := range pubKeys {
+		if !isPubKeySize(op.data) {
+			return false
+		}
+	}
+	numSigs := int(first - (op1 - 1))
+	numPubKeys := int(last - (op1 - 1))
+	return numPubKeys == len(pubKeys) && numSigs <= numPubKeys
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+const (
+	// StakeMinAge is the minimum age of coins before they may be staked.
+	StakeMinAge = 8 * time.Hour
+
+	// StakeMaxAge is the maximum age coins are weighted by when staked.
+	// Coins which are older do not gain any more weight.
+	StakeMaxAge = 45 * 24 * time.Hour
+
+	// SatoshiPerReddcoin is the number of satoshi in one reddcoin (1 RDD).
+	SatoshiPerReddcoin = 1e8
+
+	// secondsPerDay is the number of seconds in one day.
+	secondsPerDay = 24 * 60 * 60
+
+	// stakeKernelLen is the length of the serialized stake kernel which is
+	// hashed.  It is the stake modifier 8 bytes + time of the block from
+	// 4 bytes + offset of the transaction 4 bytes + time of the transaction
+	// 4 bytes + output index 4 bytes + time of the coinstake 4 bytes.
+	stakeKernelLen = 28
+)
+
+// CoinAgeWeight returns the weight in seconds of coins which were received at
+// the passed start time and staked at the passed end time under the PoSV
+// velocity rules.
+//
+// Coins start gaining weight once they reach StakeMinAge.  Rather than the
+// linear weight of proof-of-stake, their weight then grows quickly over the
+// first 7 days and ever more slowly afterwards, which rewards coins which are
+// staked regularly.  The weight never exceeds StakeMaxAge.
+func CoinAgeWeight(start, end time.Time) int64 {
+	seconds := end.Unix() - start.Unix() - int64(StakeMinAge/time.Second)
+	if seconds < 0 {
+		seconds = 0
+	}
+
+	// The weight is a cubic curve over the first 7 days and a logarithmic
+	// curve afterwards, both in days.
+	var weight float64
+	days := float64(seconds) / secondsPerDay
+	if days <= 7 {
+		weight = -0.00408163*math.Pow(days, 3) +
+			0.05714286*math.Pow(days, 2) + days
+	} else {
+		weight = 8.4*math.Log(days) - 7.94564525
+	}
+
+	maxAge := int64(StakeMaxAge / time.Second)
+	if w := int64(weight * secondsPerDay); w < maxAge {
+		return w
+	}
+	return maxAge
+}
+
+// StakeKernel houses the data a PoSV coinstake commits to in order to create
+// a block.  The kernel is the first input of the coinstake, which spends an
+// output of the transaction TxPrev.
+type StakeKernel struct {
+	// StakeModifier is the stake modifier in effect for the block which
+	// contains TxPrev.  It prevents stakers from computing future proofs
+	// at the time their coins are confirmed.
+	StakeModifier uint64
+
+	// BlockFromTime is the time of the block which contains TxPrev.
+	BlockFromTime time.Time
+
+	// TxPrevOffset is the offset of TxPrev within its serialized block,
+	// such as the TxStart of its TxLoc.
+	TxPrevOffset uint32
+
+	// TxPrev is the transaction which holds the staked output.
+	TxPrev *MsgTx
+
+	// PrevOut is the staked output of TxPrev.
+	PrevOut OutPoint
+
+	// Time is the time of the coinstake.
+	Time time.Time
+}
+
+// Hash returns the stake kernel hash, which is the double sha256 of the stake
+// modifier followed by the time of the block from, the offset and time of the
+// previous transaction, the index of the staked output, and the time of the
+// coinstake.
+func (k *StakeKernel) Hash() ShaHash {
+	// Writing to a bytes.Buffer can't fail.
+	var buf bytes.Buffer
+	buf.Grow(stakeKernelLen)
+	_ = writeElements(&buf, k.StakeModifier,
+		uint32(k.BlockFromTime.Unix()), k.TxPrevOffset,
+		uint32(k.TxPrev.Timestamp.Unix()), k.PrevOut.Index,
+		uint32(k.Time.Unix()))
+
+	// SetBytes can't fail here due to the fact DoubleSha256 always returns
+	// a []byte of the right size regardless of input.
+	var hash ShaHash
+	_ = hash.SetBytes(DoubleSha256(buf.Bytes()))
+	return hash
+}
+
+// Target returns the target the stake kernel hash must not exceed for the
+// passed difficulty bits.  The bits describe the target per coin-day, so the
+// target is weighted by the value of the staked output in reddcoin multiplied
+// by its coin-age weight in days (see CoinAgeWeight).
+func (k *StakeKernel) Target(bits uint32) *big.Int {
+	value := k.TxPrev.TxOut[k.PrevOut.Index].Value
+	weight := CoinAgeWeight(k.TxPrev.Timestamp, k.Time)
+
+	coinDayWeight := new(big.Int).Mul(big.NewInt(value), big.NewInt(weight))
+	coinDayWeight.Quo(coinDayWeight, big.NewInt(SatoshiPerReddcoin))
+	coinDayWeight.Quo(coinDayWeight, big.NewInt(secondsPerDay))
+	return coinDayWeight.Mul(coinDayWeight, CompactToBig(bits))
+}
+
+// Check ensures the stake kernel is valid and that its hash meets the target
+// for the passed difficulty bits as Reddcoin requires of PoSV blocks.  The
+// stake kernel hash is returned so it can be used as the proof-of-stake hash
+// of the block.
+//
+// An error is returned when PrevOut is not an output of TxPrev, the coinstake
+// is older than TxPrev, the block from is younger than StakeMinAge at the time
+// of the coinstake, or the hash does not meet the target (see Target).
+func (k *StakeKernel) Check(bits uint32) (ShaHash, error) {
+	txPrevHash, err := k.TxPrev.TxSha()
+	if err != nil {
+		return ShaHash{}, err
+	}
+	if !k.PrevOut.Hash.IsEqual(&txPrevHash) ||
+		k.PrevOut.Index >= uint32(len(k.TxPrev.TxOut)) {
+
+		str := fmt.Sprintf("stake kernel outpoint %v:%d is not an "+
+			"output of transaction %v", k.PrevOut.Hash,
+			k.PrevOut.Index, txPrevHash)
+		return ShaHash{}, messageError("StakeKernel.Check", str)
+	}
+
+	if k.Time.Before(k.TxPrev.Timestamp) {
+		str := fmt.Sprintf("coinstake time %v is before the time of "+
+			"the staked transaction %v", k.Time, k.TxPrev.Timestamp)
+		return ShaHash{}, messageError("StakeKernel.Check", str)
+	}
+	if k.BlockFromTime.Add(StakeMinAge).After(k.Time) {
+		str := fmt.Sprintf("staked coins from block at %v do not meet "+
+			"the minimum age of %v at %v", k.BlockFromTime,
+			StakeMinAge, k.Time)
+		return ShaHash{}, messageError("StakeKernel.Check", str)
+	}
+
+	hash := k.Hash()
+	target := k.Target(bits)
+	if hashToBig(&hash).Cmp(target) > 0 {
+		str := fmt.Sprintf("stake kernel hash %v is higher than the "+
+			"coin-age weighted target %064x", hash, target)
+		return ShaHash{}, messageError("StakeKernel.Check", str)
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+)
+
+// TestCoinAgeWeight tests the coin-age weight of coins under the PoSV velocity
+// rules.
+func TestCoinAgeWeight(t *testing.T) {
+	start := time.Unix(1400000000, 0)
+	minAge := int64(rddwire.StakeMinAge / time.Second)
+	day := int64(24 * 60 * 60)
+
+	tests := []struct {
+		age  int64 // Age of the coins in seconds
+		want int64 // Expected weight in seconds
+	}{
+		// Coins gain no weight until they reach the minimum age.
+		{-1000, 0},
+		{0, 0},
+		{minAge, 0},
+
+		// Cubic curve over the first 7 days after the minimum age.
+		{minAge + 1, 1},
+		{minAge + 3600, 3608},
+		{minAge + day, 90984},
+		{minAge + 7*day, 725760},
+
+		// Logarithmic curve afterwards.
+		{minAge + 7*day + 1, 725761},
+		{minAge + 30*day, 1781949},
+		{minAge + 200*day, 3158803},
+
+		// Weight is limited to the maximum age.
+		{minAge + 10000*day, int64(rddwire.StakeMaxAge / time.Second)},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		end := start.Add(time.Duration(test.age) * time.Second)
+		got := rddwire.CoinAgeWeight(start, end)
+		if got != test.want {
+			t.Errorf("CoinAgeWeight #%d: got %d, want %d", i, got,
+				test.want)
+			continue
+		}
+	}
+}
+
+// TestStakeKernel tests the computation and checking of PoSV stake kernel
+// hashes.
+func TestStakeKernel(t *testing.T) {
+	blockFromTime := time.Unix(1400000000, 0)
+
+	// Transaction which holds the staked output of 10000 RDD at index 1.
+	txPrev := rddwire.NewMsgTx()
+	txPrev.AddTxIn(rddwire.NewTxIn(&rddwire.OutPoint{
+		Hash:  rddwire.ShaHash{0x01},
+		Index: 0,
+	}, []byte{0x51}))
+	txPrev.AddTxOut(rddwire.NewTxOut(5000, []byte{0x51}))
+	txPrev.AddTxOut(rddwire.NewTxOut(10000*rddwire.SatoshiPerReddcoin,
+		[]byte{0x51}))
+	txPrev.Timestamp = blockFromTime.Add(-time.Minute)
+	txPrevHash, _ := txPrev.TxSha()
+
+	// newKernel returns a stake kernel for the staked output at the passed
+	// number of seconds after 10 days from the block from.
+	newKernel := func(seconds int64) *rddwire.StakeKernel {
+		return &rddwire.StakeKernel{
+			StakeModifier: 0x0123456789abcdef,
+			BlockFromTime: blockFromTime,
+			TxPrevOffset:  81,
+			TxPrev:        txPrev,
+			PrevOut:       *rddwire.NewOutPoint(&txPrevHash, 1),
+			Time: blockFromTime.Add(10*24*time.Hour +
+				time.Duration(seconds)*time.Second),
+		}
+	}
+
+	tests := []struct {
+		kernel *rddwire.StakeKernel // Stake kernel to check
+		bits   uint32               // Difficulty bits
+		hash   string               // Expected stake kernel hash
+		target string               // Expected weighted target prefix
+		err    error                // Expected error
+	}{
+		{newKernel(0), 0x1e0fffff, "8f9c231f8fea80e1d0a1de2e72e0c87c6a92" +
+			"dee483c38d4933c36a629b6cc81e", "1b20de4df2",
+			&rddwire.MessageError{}},
+		{newKernel(13), 0x1e0fffff, "1216d924568df542fdce8b9fe959ddf055" +
+			"6a3cb5c78ce35ed1c2072e0f69a0d2", "1b20fe4df0", nil},
+		{newKernel(0), 0x1d00ffff, "8f9c231f8fea80e1d0a1de2e72e0c87c6a92" +
+			"dee483c38d4933c36a629b6cc81e", "0001b20c4df2",
+			&rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		hash := test.kernel.Hash()
+		if hash.String() != test.hash {
+			t.Errorf("Hash #%d: got %v, want %v", i, hash, test.hash)
+			continue
+		}
+
+		// The expected targets are given without their trailing
+		// zeros.
+		target := fmt.Sprintf("%064x", test.kernel.Target(test.bits))
+		want := test.target + strings.Repeat("0", 64-len(test.target))
+		if target != want {
+			t.Errorf("Target #%d: got %v, want %v", i, target, want)
+			continue
+		}
+
+		gotHash, err := test.kernel.Check(test.bits)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("Check #%d wrong error got: %v, want: %v", i,
+				err, reflect.TypeOf(test.err))
+			continue
+		}
+		if err == nil && !gotHash.IsEqual(&hash) {
+			t.Errorf("Check #%d: got hash %v, want %v", i, gotHash,
+				hash)
+			continue
+		}
+	}
+}
+
+// TestStakeKernelErrors performs negative tests against checking stake
+// kernels to ensure invalid kernels are rejected regardless of their hash.
+func TestStakeKernelErrors(t *testing.T) {
+	blockFromTime := time.Unix(1400000000, 0)
+	txPrev := rddwire.NewMsgTx()
+	txPrev.AddTxIn(rddwire.NewTxIn(&rddwire.OutPoint{}, []byte{0x51}))
+	txPrev.AddTxOut(rddwire.NewTxOut(10000*rddwire.SatoshiPerReddcoin,
+		[]byte{0x51}))
+	txPrev.Timestamp = blockFromTime
+	txPrevHash, _ := txPrev.TxSha()
+
+	// copyKernel returns a stake kernel with a very easy target which may
+	// be modified.
+	copyKernel := func() *rddwire.StakeKernel {
+		return &rddwire.StakeKernel{
+			BlockFromTime: blockFromTime,
+			TxPrev:        txPrev,
+			PrevOut:       *rddwire.NewOutPoint(&txPrevHash, 0),
+			Time:          blockFromTime.Add(30 * 24 * time.Hour),
+		}
+	}
+	easyBits := uint32(0x207fffff)
+
+	wrongHash := copyKernel()
+	wrongHash.PrevOut.Hash = rddwire.ShaHash{}
+
+	wrongIndex := copyKernel()
+	wrongIndex.PrevOut.Index = 1
+
+	beforeTxPrev := copyKernel()
+	beforeTxPrev.Time = txPrev.Timestamp.Add(-time.Second)
+
+	tooYoung := copyKernel()
+	tooYoung.Time = blockFromTime.Add(rddwire.StakeMinAge - time.Second)
+
+	minAge := copyKernel()
+	minAge.Time = blockFromTime.Add(rddwire.StakeMinAge)
+
+	tests := []struct {
+		kernel *rddwire.StakeKernel // Stake kernel to check
+		err    error                // Expected error
+	}{
+		{copyKernel(), nil},
+		{wrongHash, &rddwire.MessageError{}},
+		{wrongIndex, &rddwire.MessageError{}},
+		{beforeTxPrev, &rddwire.MessageError{}},
+		{tooYoung, &rddwire.MessageError{}},
+
+		// Coins which have just reached the minimum age have no
+		// weight, so they can't meet any target.
+		{minAge, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		_, err := test.kernel.Check(easyBits)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("Check #%d wrong error got: %v, want: %v", i,
+				err, reflect.TypeOf(test.err))
+			continue
+		}
+	}
+}
@@ -0,0 +1,367 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+const (
+	// StakeModifierInterval is the default time to elapse before a new
+	// stake modifier is computed.
+	StakeModifierInterval = 10 * time.Minute
+
+	// stakeModifierRatio is the ratio of the length of the first and last
+	// sections of the stake modifier selection interval.
+	stakeModifierRatio = 3
+
+	// stakeModifierRounds is the number of blocks selected to compute a
+	// stake modifier, one for each of its bits.
+	stakeModifierRounds = 64
+)
+
+// These constants define the flags of a block which are committed to by the
+// stake modifier checksum.
+const (
+	stakeFlagProofOfStake = 1 << 0
+	stakeFlagEntropy      = 1 << 1
+	stakeFlagModifier     = 1 << 2
+)
+
+// StakeModifier describes the stake modifier of a block.
+type StakeModifier struct {
+	// Modifier is the stake modifier in effect for the block.
+	Modifier uint64
+
+	// Generated is whether the modifier was computed by the block rather
+	// than carried over from its parent.
+	Generated bool
+
+	// Checksum is the stake modifier checksum of the block, which commits
+	// to the modifiers and proofs of all blocks up to it.
+	Checksum uint32
+}
+
+// stakeModifierNode houses the information about a block needed to compute
+// the stake modifiers of the blocks which follow it.
+type stakeModifierNode struct {
+	hash         ShaHash
+	timestamp    int64
+	proofOfStake bool
+	entropyBit   bool
+	proofHash    ShaHash
+	modifier     StakeModifier
+}
+
+// StakeModifierEngine computes the stake modifier of each block of a chain as
+// Reddcoin does.  Blocks are added in order starting with the genesis block.
+//
+// Every modifier interval, a new stake modifier is computed from the entropy
+// bits of 64 blocks selected from the blocks of the preceding selection
+// interval using the previous modifier, so the modifier of a block can't be
+// known ahead of time.  The stake modifier used by a stake kernel is the one
+// in effect a selection interval after the block which contains the staked
+// output (see KernelStakeModifier).
+//
+// A StakeModifierEngine is not safe for concurrent access.
+type StakeModifierEngine struct {
+	interval    int64
+	checkpoints map[int32]uint32
+	nodes       []*stakeModifierNode
+	heights     map[ShaHash]int32
+}
+
+// selectionIntervalSection returns the length in seconds of the passed section
+// of the selection interval.  Sections get longer over the selection interval,
+// with the last section stakeModifierRatio times as long as the first.
+func (e *StakeModifierEngine) selectionIntervalSection(section int) int64 {
+	return e.interval * 63 / (63 + int64(63-section)*(stakeModifierRatio-1))
+}
+
+// selectionInterval returns the length in seconds of the selection interval,
+// which is the total length of its sections.
+func (e *StakeModifierEngine) selectionInterval() int64 {
+	var interval int64
+	for section := 0; section < stakeModifierRounds; section++ {
+		interval += e.selectionIntervalSection(section)
+	}
+	return interval
+}
+
+// selectionHash returns the hash used to select the passed candidate block
+// given the previous stake modifier.  The hash of PoSV blocks is divided by
+// 2^32 so they are always favored over proof-of-work blocks.
+func selectionHash(node *stakeModifierNode, prevModifier uint64) *big.Int {
+	proofHash := &node.hash
+	if node.proofOfStake {
+		proofHash = &node.proofHash
+	}
+
+	// Writing to a bytes.Buffer can't fail.
+	var buf bytes.Buffer
+	buf.Grow(HashSize + 8)
+	_ = writeElements(&buf, proofHash, prevModifier)
+
+	// SetBytes can't fail here due to the fact DoubleSha256 always returns
+	// a []byte of the right size regardless of input.
+	var hash ShaHash
+	_ = hash.SetBytes(DoubleSha256(buf.Bytes()))
+	selection := hashToBig(&hash)
+	if node.proofOfStake {
+		selection.Rsh(selection, 32)
+	}
+	return selection
+}
+
+// nextModifier computes the stake modifier of a block whose parent is the last
+// block added to the engine.  It returns whether the block generated a new
+// modifier rather than carrying over the modifier of its parent.
+func (e *StakeModifierEngine) nextModifier() (uint64, bool) {
+	// The genesis block generates a modifier of zero.
+	if len(e.nodes) == 0 {
+		return 0, true
+	}
+
+	// Find the current stake modifier and the time of the block which
+	// generated it.  The modifier is reused until the interval it was
+	// generated in has passed.
+	prev := e.nodes[len(e.nodes)-1]
+	last := len(e.nodes) - 1
+	for last > 0 && !e.nodes[last].modifier.Generated {
+		last--
+	}
+	modifier := e.nodes[last].modifier.Modifier
+	if e.nodes[last].timestamp/e.interval >= prev.timestamp/e.interval {
+		return modifier, false
+	}
+
+	// Collect the candidate blocks from the selection interval ending at
+	// the start of the current modifier interval and sort them by time.
+	selectionStart := (prev.timestamp/e.interval)*e.interval -
+		e.selectionInterval()
+	var candidates []*stakeModifierNode
+	for i := len(e.nodes) - 1; i >= 0; i-- {
+		if e.nodes[i].timestamp < selectionStart {
+			break
+		}
+		candidates = append(candidates, e.nodes[i])
+	}
+	sort.Sort(stakeModifierCandidates(candidates))
+	hashes := make(map[*stakeModifierNode]*big.Int, len(candidates))
+	for _, node := range candidates {
+		hashes[node] = selectionHash(node, modifier)
+	}
+
+	// Select a block for each bit of the new modifier from the candidates
+	// of a growing section of the selection interval.  The block with the
+	// lowest selection hash that has not already been selected wins, and
+	// its entropy bit becomes the bit of the modifier.
+	var newModifier uint64
+	selected := make(map[*stakeModifierNode]struct{})
+	selectionStop := selectionStart
+	rounds := len(candidates)
+	if rounds > stakeModifierRounds {
+		rounds = stakeModifierRounds
+	}
+	for round := 0; round < rounds; round++ {
+		selectionStop += e.selectionIntervalSection(round)
+
+		var best *stakeModifierNode
+		var bestHash *big.Int
+		for _, node := range candidates {
+			if best != nil && node.timestamp > selectionStop {
+				break
+			}
+			if _, ok := selected[node]; ok {
+				continue
+			}
+
+			hash := hashes[node]
+			if best == nil || hash.Cmp(bestHash) < 0 {
+				best, bestHash = node, hash
+			}
+		}
+
+		// There are always at least as many candidates as rounds, so a
+		// block is always selected.
+		if best.entropyBit {
+			newModifier |= 1 << uint(round)
+		}
+		selected[best] = struct{}{}
+	}
+
+	return newModifier, true
+}
+
+// checksum returns the stake modifier checksum of the passed node given the
+// checksum of its parent.
+func (node *stakeModifierNode) checksum(prevChecksum uint32, genesis bool) uint32 {
+	var flags uint32
+	if node.proofOfStake {
+		flags |= stakeFlagProofOfStake
+	}
+	if node.entropyBit {
+		flags |= stakeFlagEntropy
+	}
+	if node.modifier.Generated {
+		flags |= stakeFlagModifier
+	}
+
+	// Writing to a bytes.Buffer can't fail.
+	var buf bytes.Buffer
+	if !genesis {
+		_ = writeElement(&buf, prevChecksum)
+	}
+	_ = writeElements(&buf, flags, &node.proofHash, node.modifier.Modifier)
+
+	// The checksum is the most significant 32 bits of the hash.
+	hash := DoubleSha256(buf.Bytes())
+	return binary.LittleEndian.Uint32(hash[HashSize-4:])
+}
+
+// AddBlock adds the block with the passed header to the end of the chain and
+// returns its stake modifier.  The first block added must be the genesis
+// block, and each block after it must connect to the previous one.
+//
+// Whether the block is a PoSV block and its stake entropy bit must be provided
+// along with its proof-of-stake hash, which is the hash of its stake kernel
+// (see StakeKernel), for PoSV blocks.  The proof-of-stake hash of
+// proof-of-work blocks is ignored and may be nil.
+//
+// An error is returned when the block does not connect to the previous block
+// or its stake modifier checksum does not match the checkpoint at its height.
+func (e *StakeModifierEngine) AddBlock(header *BlockHeader, proofOfStake,
+	entropyBit bool, proofHash *ShaHash) (*StakeModifier, error) {
+
+	height := int32(len(e.nodes))
+	if height > 0 {
+		prevHash := &e.nodes[height-1].hash
+		if !header.PrevBlock.IsEqual(prevHash) {
+			str := fmt.Sprintf("block at height %d does not connect "+
+				"to the previous block %v", height, prevHash)
+			return nil, messageError("StakeModifierEngine.AddBlock",
+				str)
+		}
+	}
+
+	hash, err := header.BlockSha()
+	if err != nil {
+		return nil, err
+	}
+	node := stakeModifierNode{
+		hash:         hash,
+		timestamp:    header.Timestamp.Unix(),
+		proofOfStake: proofOfStake,
+		entropyBit:   entropyBit,
+	}
+	if proofOfStake && proofHash != nil {
+		node.proofHash = *proofHash
+	}
+
+	node.modifier.Modifier, node.modifier.Generated = e.nextModifier()
+	var prevChecksum uint32
+	if height > 0 {
+		prevChecksum = e.nodes[height-1].modifier.Checksum
+	}
+	node.modifier.Checksum = node.checksum(prevChecksum, height == 0)
+
+	if checksum, ok := e.checkpoints[height]; ok &&
+		checksum != node.modifier.Checksum {
+
+		str := fmt.Sprintf("stake modifier checksum 0x%08x of block "+
+			"at height %d does not match checkpoint 0x%08x",
+			node.modifier.Checksum, height, checksum)
+		return nil, messageError("StakeModifierEngine.AddBlock", str)
+	}
+
+	e.nodes = append(e.nodes, &node)
+	e.heights[hash] = height
+	modifier := node.modifier
+	return &modifier, nil
+}
+
+// StakeModifier returns the stake modifier of the block at the passed height.
+func (e *StakeModifierEngine) StakeModifier(height int32) (*StakeModifier, error) {
+	if height < 0 || height >= int32(len(e.nodes)) {
+		str := fmt.Sprintf("no block at height %d", height)
+		return nil, messageError("StakeModifierEngine.StakeModifier",
+			str)
+	}
+
+	modifier := e.nodes[height].modifier
+	return &modifier, nil
+}
+
+// KernelStakeModifier returns the stake modifier to use for the stake kernel
+// of coins confirmed in the block with the passed hash.  It is the modifier in
+// effect at the first block which is at least a selection interval younger
+// than the block and which generated a modifier, so an error is returned when
+// the engine has not yet been given enough blocks to determine it.
+func (e *StakeModifierEngine) KernelStakeModifier(blockFromHash *ShaHash) (uint64, error) {
+	height, ok := e.heights[*blockFromHash]
+	if !ok {
+		str := fmt.Sprintf("block %v is unknown", blockFromHash)
+		return 0, messageError("StakeModifierEngine.KernelStakeModifier",
+			str)
+	}
+
+	from := e.nodes[height]
+	modifierTime := from.timestamp
+	for modifierTime < from.timestamp+e.selectionInterval() {
+		height++
+		if height >= int32(len(e.nodes)) {
+			str := fmt.Sprintf("stake modifier of block %v is not "+
+				"known yet", blockFromHash)
+			return 0, messageError("StakeModifierEngine."+
+				"KernelStakeModifier", str)
+		}
+		if e.nodes[height].modifier.Generated {
+			modifierTime = e.nodes[height].timestamp
+		}
+	}
+
+	return e.nodes[height].modifier.Modifier, nil
+}
+
+// NewStakeModifierEngine returns a new stake modifier engine which computes a
+// new stake modifier every passed interval, such as StakeModifierInterval.
+// The stake modifier checksum of each block added is verified against the
+// passed checkpoints, which map block heights to the expected checksums, when
+// there is one for its height.  The checkpoints may be nil.
+func NewStakeModifierEngine(interval time.Duration,
+	checkpoints map[int32]uint32) *StakeModifierEngine {
+
+	return &StakeModifierEngine{
+		interval:    int64(interval / time.Second),
+		checkpoints: checkpoints,
+		heights:     make(map[ShaHash]int32),
+	}
+}
+
+// stakeModifierCandidates implements sort.Interface to allow the candidate
+// blocks of a stake modifier to be sorted by their time and then their hash.
+type stakeModifierCandidates []*stakeModifierNode
+
+// Len returns the number of candidates in the slice.  It is part of the
+// sort.Interface implementation.
+func (s stakeModifierCandidates) Len() int { return len(s) }
+
+// Swap swaps the candidates at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s stakeModifierCandidates) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// Less returns whether the candidate with index i should sort before the
+// candidate with index j.  It is part of the sort.Interface implementation.
+func (s stakeModifierCandidates) Less(i, j int) bool {
+	if s[i].timestamp != s[j].timestamp {
+		return s[i].timestamp < s[j].timestamp
+	}
+	return hashToBig(&s[i].hash).Cmp(hashToBig(&s[j].hash)) < 0
+}
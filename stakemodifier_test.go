@@ -0,0 +1,210 @@
+// Copyright (c) 2014 Conformal Systems LLC.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rddwire_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddcoin-project/rddwire"
+)
+
+// stakeModifierTestBlock describes a block of the chain used to test the stake
+// modifier engine.
+type stakeModifierTestBlock struct {
+	header       rddwire.BlockHeader
+	proofOfStake bool
+	entropyBit   bool
+	proofHash    *rddwire.ShaHash
+}
+
+// stakeModifierTestChain returns a chain of the passed number of blocks which
+// are a minute apart.  The first 100 blocks are proof-of-work blocks and two
+// out of every three blocks after them are PoSV blocks.
+func stakeModifierTestChain(numBlocks int) []stakeModifierTestBlock {
+	blocks := make([]stakeModifierTestBlock, 0, numBlocks)
+	var prevHash rddwire.ShaHash
+	for i := 0; i < numBlocks; i++ {
+		block := stakeModifierTestBlock{
+			header: rddwire.BlockHeader{
+				Version:    rddwire.PowBlockVersion,
+				PrevBlock:  prevHash,
+				MerkleRoot: rddwire.ShaHash{byte(i), byte(i >> 8)},
+				Timestamp: time.Unix(int64(1400000000+i*60+
+					i%7*5), 0),
+				Bits:  0x1e0fffff,
+				Nonce: uint32(i),
+			},
+			entropyBit: i%5 < 2,
+		}
+		if i >= 100 {
+			block.header.Version = rddwire.BlockVersion
+			if i%3 != 0 {
+				block.proofOfStake = true
+				block.proofHash = &rddwire.ShaHash{0xff, byte(i),
+					byte(i >> 8)}
+			}
+		}
+
+		prevHash, _ = block.header.BlockSha()
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// TestStakeModifierEngine tests the stake modifiers and checksums computed by
+// the stake modifier engine.
+func TestStakeModifierEngine(t *testing.T) {
+	blocks := stakeModifierTestChain(700)
+	engine := rddwire.NewStakeModifierEngine(rddwire.StakeModifierInterval,
+		nil)
+	for i := range blocks {
+		block := &blocks[i]
+		_, err := engine.AddBlock(&block.header, block.proofOfStake,
+			block.entropyBit, block.proofHash)
+		if err != nil {
+			t.Fatalf("AddBlock #%d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		height int32                 // Height of the block
+		want   rddwire.StakeModifier // Expected stake modifier
+	}{
+		{0, rddwire.StakeModifier{0, true, 0x0e00670b}},
+		{1, rddwire.StakeModifier{0, false, 0xbc4b99b6}},
+		{8, rddwire.StakeModifier{0x63, true, 0xaac26f56}},
+		{18, rddwire.StakeModifier{0x18c63, true, 0x90f715ce}},
+		{28, rddwire.StakeModifier{0x6318c63, true, 0x2adc93c1}},
+		{99, rddwire.StakeModifier{0x318c6318c6318c63, false, 0x6797538d}},
+		{100, rddwire.StakeModifier{0x318c6318c6318c63, false, 0x1cab6990}},
+		{300, rddwire.StakeModifier{0xac80d12e20918c63, false, 0xe7d56472}},
+		{450, rddwire.StakeModifier{0x8ea628de97609442, false, 0x5519fefa}},
+		{678, rddwire.StakeModifier{0x4321a8921038851d, true, 0xb4be4f9b}},
+		{688, rddwire.StakeModifier{0x5627921438e9191a, true, 0x2da239e3}},
+		{698, rddwire.StakeModifier{0x046462c13d11c893, true, 0xc47785fd}},
+		{699, rddwire.StakeModifier{0x046462c13d11c893, false, 0x06264c8e}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		got, err := engine.StakeModifier(test.height)
+		if err != nil {
+			t.Errorf("StakeModifier height %d: %v", test.height, err)
+			continue
+		}
+		if *got != test.want {
+			t.Errorf("StakeModifier height %d: got %+v, want %+v",
+				test.height, *got, test.want)
+			continue
+		}
+	}
+
+	// Ensure heights without a block are rejected.
+	for _, height := range []int32{-1, 700} {
+		_, err := engine.StakeModifier(height)
+		if _, ok := err.(*rddwire.MessageError); !ok {
+			t.Errorf("StakeModifier: wrong error for height %d got: "+
+				"%v, want: %T", height, err,
+				&rddwire.MessageError{})
+		}
+	}
+}
+
+// TestKernelStakeModifier tests the stake modifiers used by the stake kernels
+// of coins confirmed in blocks.
+func TestKernelStakeModifier(t *testing.T) {
+	blocks := stakeModifierTestChain(700)
+	engine := rddwire.NewStakeModifierEngine(rddwire.StakeModifierInterval,
+		nil)
+	for i := range blocks {
+		block := &blocks[i]
+		engine.AddBlock(&block.header, block.proofOfStake,
+			block.entropyBit, block.proofHash)
+	}
+	blockHash := func(height int) *rddwire.ShaHash {
+		hash, _ := blocks[height].header.BlockSha()
+		return &hash
+	}
+
+	tests := []struct {
+		hash *rddwire.ShaHash // Hash of the block from
+		want uint64           // Expected stake modifier
+		err  error            // Expected error
+	}{
+		{blockHash(100), 0x2f1d0af011097c8b, nil},
+		{blockHash(300), 0xe06e8bc4d8b50052, nil},
+
+		// Blocks which are too recent for their stake modifier to be
+		// known.
+		{blockHash(500), 0, &rddwire.MessageError{}},
+		{blockHash(699), 0, &rddwire.MessageError{}},
+
+		// Unknown block.
+		{&rddwire.ShaHash{}, 0, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		got, err := engine.KernelStakeModifier(test.hash)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("KernelStakeModifier #%d wrong error got: %v, "+
+				"want: %v", i, err, reflect.TypeOf(test.err))
+			continue
+		}
+		if got != test.want {
+			t.Errorf("KernelStakeModifier #%d: got 0x%016x, want "+
+				"0x%016x", i, got, test.want)
+			continue
+		}
+	}
+}
+
+// TestStakeModifierEngineErrors performs negative tests against adding blocks
+// to the stake modifier engine.
+func TestStakeModifierEngineErrors(t *testing.T) {
+	blocks := stakeModifierTestChain(301)
+	checkpoints := map[int32]uint32{
+		0:   0x0e00670b,
+		300: 0xe7d56472,
+	}
+
+	tests := []struct {
+		checkpoints map[int32]uint32 // Stake modifier checkpoints
+		skip        int              // Height of a block to skip
+		err         error            // Expected error
+	}{
+		{checkpoints, -1, nil},
+		{map[int32]uint32{300: 0xe7d56473}, -1, &rddwire.MessageError{}},
+		{map[int32]uint32{0: 0}, -1, &rddwire.MessageError{}},
+		{checkpoints, 150, &rddwire.MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		engine := rddwire.NewStakeModifierEngine(
+			rddwire.StakeModifierInterval, test.checkpoints)
+
+		var err error
+		for j := range blocks {
+			if j == test.skip {
+				continue
+			}
+			block := &blocks[j]
+			_, err = engine.AddBlock(&block.header,
+				block.proofOfStake, block.entropyBit,
+				block.proofHash)
+			if err != nil {
+				break
+			}
+		}
+		if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("AddBlock #%d wrong error got: %v, want: %v", i,
+				err, reflect.TypeOf(test.err))
+			continue
+		}
+	}
+}